@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"syscall"
+)
+
+// abortCloseDialer returns a copy of base whose Control sets SO_LINGER with
+// a zero timeout on TCP sockets, so closing a probe connection sends a RST
+// instead of a FIN. Neither side then keeps the socket in TIME_WAIT, which
+// spares the target's resources and frees the local port at once. A
+// Control already set on base still runs first.
+//
+// Parameters:
+// - base: The dialer to copy, or nil for a default one.
+//
+// Returns:
+// - The dialer aborting its TCP connections on close.
+func abortCloseDialer(base *net.Dialer) *net.Dialer {
+	dialer := &net.Dialer{}
+	if base != nil {
+		copied := *base
+		dialer = &copied
+	}
+	control := dialer.Control
+	dialer.Control = func(network, address string, c syscall.RawConn) error {
+		if control != nil {
+			if err := control(network, address, c); err != nil {
+				return err
+			}
+		}
+		if !strings.HasPrefix(network, "tcp") {
+			return nil
+		}
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = setLingerZero(fd)
+		}); err != nil {
+			return err
+		}
+		return sockErr
+	}
+	return dialer
+}
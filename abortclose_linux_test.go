@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestAbortCloseDialer_SetsLinger(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+
+	controlled := false
+	base := &net.Dialer{Control: func(network, address string, c syscall.RawConn) error {
+		controlled = true
+		return nil
+	}}
+	conn, err := abortCloseDialer(base).Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	defer conn.Close()
+	if !controlled {
+		t.Errorf("Expected the base dialer's Control to still run")
+	}
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatalf("SyscallConn failed: %s", err)
+	}
+	var onoff int
+	var sockErr error
+	raw.Control(func(fd uintptr) {
+		// Linux copies as much of struct linger as fits, i.e. l_onoff
+		onoff, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_LINGER)
+	})
+	if sockErr != nil {
+		t.Fatalf("Reading SO_LINGER failed: %s", sockErr)
+	}
+	if onoff != 1 {
+		t.Errorf("Expected SO_LINGER enabled, got l_onoff %d", onoff)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// closeAndRead dials listener with scanner's dial function, closes the
+// connection and returns the error the accepting side reads.
+func closeAndRead(t *testing.T, scanner *PortScanner, listener net.Listener) error {
+	conn, err := scanner.dial()("tcp", listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("Dial failed: %s", err)
+	}
+	accepted, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Accept failed: %s", err)
+	}
+	defer accepted.Close()
+	conn.Close()
+	accepted.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = accepted.Read(make([]byte, 1))
+	return err
+}
+
+func TestAbortClose_SendsReset(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+
+	if err := closeAndRead(t, &PortScanner{}, listener); err != io.EOF {
+		t.Errorf("Expected a graceful close to read EOF, got %v", err)
+	}
+	err = closeAndRead(t, &PortScanner{AbortClose: true}, listener)
+	if err == nil || err == io.EOF {
+		t.Errorf("Expected AbortClose to reset the connection, got %v", err)
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		t.Errorf("Expected a reset, got a read timeout")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// setLingerZero enables SO_LINGER with a zero timeout on the socket fd.
+func setLingerZero(fd uintptr) error {
+	return syscall.SetsockoptLinger(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_LINGER, &syscall.Linger{Onoff: 1, Linger: 0})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net"
+	"runtime"
+)
+
+// AutoWorkers sizes the worker pool from the machine and the scan instead of
+// a fixed count. The heuristic:
+//
+// - A probe to a remote host spends nearly all its time waiting for the
+// network, so the scan is latency-bound and many more goroutines than CPUs
+// pay off: autoWorkersPerCPU workers per CPU, clamped to
+// [autoMinWorkers, autoMaxWorkers].
+// - A scan of loopback addresses only waits for the local kernel, so it is
+// CPU-bound and gains nothing from more workers than CPUs.
+// - No scan gets more workers than it has probes.
+const (
+	// autoWorkersPerCPU is the number of workers per CPU for network-bound scans.
+	autoWorkersPerCPU = 128
+	// autoMinWorkers is the lower bound for network-bound scans.
+	autoMinWorkers = 16
+	// autoMaxWorkers is the upper bound, keeping well below common file
+	// descriptor limits.
+	autoMaxWorkers = 2048
+)
+
+// AutoWorkerCount computes a worker count for a scan.
+//
+// Parameters:
+// - probes: The number of endpoints to probe.
+// - procs: The number of CPUs available, e.g. runtime.GOMAXPROCS(0).
+// - networkBound: Whether probes wait on the network rather than the CPU.
+//
+// Returns:
+// - The number of workers, at least 1.
+//
+// Example:
+//
+//	workers := AutoWorkerCount(65535, runtime.GOMAXPROCS(0), true)
+func AutoWorkerCount(probes, procs int, networkBound bool) int {
+	if procs < 1 {
+		procs = 1
+	}
+	workers := procs
+	if networkBound {
+		workers = procs * autoWorkersPerCPU
+		if workers < autoMinWorkers {
+			workers = autoMinWorkers
+		}
+		if workers > autoMaxWorkers {
+			workers = autoMaxWorkers
+		}
+	}
+	if workers > probes {
+		workers = probes
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// networkBound reports whether probing ips waits on the network, that is
+// whether any of them is not a loopback address.
+func networkBound(ips []string) bool {
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed == nil || !parsed.IsLoopback() {
+			return true
+		}
+	}
+	return false
+}
+
+// autoWorkers returns the number of workers a scan sizes its pools with:
+// AutoWorkerCount when AutoWorkers is enabled, otherwise NumWorkers.
+// NumWorkers is left as configured; the scan's meta records the count used.
+//
+// Parameters:
+// - ips: The IP addresses to scan.
+// - probes: The number of endpoints to probe.
+//
+// Returns:
+// - The number of workers.
+func (t *PortScanner) autoWorkers(ips []string, probes int) int {
+	if t.AutoWorkers {
+		return AutoWorkerCount(probes, runtime.GOMAXPROCS(0), networkBound(ips))
+	}
+	return t.NumWorkers
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestAutoWorkerCount(t *testing.T) {
+	tests := []struct {
+		name         string
+		probes       int
+		procs        int
+		networkBound bool
+		min, max     int
+	}{
+		{"single port", 1, 8, true, 1, 1},
+		{"few ports", 10, 8, true, 10, 10},
+		{"top ports on one CPU", 100, 1, true, autoMinWorkers, 100},
+		{"all ports", 65535, 4, true, 4 * autoWorkersPerCPU, 4 * autoWorkersPerCPU},
+		{"CIDR sweep on many CPUs", 65535 * 256, 64, true, autoMaxWorkers, autoMaxWorkers},
+		{"loopback", 65535, 4, false, 4, 4},
+		{"no CPUs reported", 1000, 0, false, 1, 1},
+		{"no probes", 0, 8, true, 1, 1},
+	}
+	for _, test := range tests {
+		workers := AutoWorkerCount(test.probes, test.procs, test.networkBound)
+		if workers < test.min || workers > test.max {
+			t.Errorf("%s: Expected between %d and %d workers, got %d", test.name, test.min, test.max, workers)
+		}
+	}
+}
+
+func TestScan_AutoWorkers(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner, err := NewTarget("127.0.0.1", 100)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{40001, 40002, 40003}
+	scanner.Dial = dialer.Dial
+	scanner.AutoWorkers = true
+	scanner.Quiet = true
+
+	scanner.Scan()
+
+	if workers := scanner.Report().Meta.Workers; workers < 1 || workers > len(scanner.Ports) {
+		t.Errorf("Expected at most one worker per probe, got %d", workers)
+	}
+	if scanner.NumWorkers != 100 {
+		t.Errorf("Expected NumWorkers to stay as configured, got %d", scanner.NumWorkers)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// maxBannerSize is the number of bytes read from a service's greeting.
+const maxBannerSize = 256
+
+// readBanner waits up to wait for the greeting a service sends on connect
+// without being asked, e.g. an SSH or SMTP banner.
+//
+// Parameters:
+// - conn: The freshly opened connection, closed by the caller.
+// - wait: How long to wait for the greeting.
+//
+// Returns:
+// - The greeting with surrounding whitespace trimmed, or "" if the service
+// stayed silent.
+func readBanner(conn net.Conn, wait time.Duration) string {
+	conn.SetReadDeadline(time.Now().Add(wait))
+	buf := make([]byte, maxBannerSize)
+	n, _ := conn.Read(buf)
+	return strings.TrimSpace(string(buf[:n]))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+// listenGreeting starts a TCP server that sends greeting to every client on
+// connect, or nothing when greeting is empty.
+func listenGreeting(t *testing.T, greeting string) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if greeting != "" {
+					conn.Write([]byte(greeting))
+				}
+				// Hold the connection until the client closes it
+				conn.Read(make([]byte, 1))
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestBannerWait_ReadsGreeting(t *testing.T) {
+	greeting := listenGreeting(t, "SSH-2.0-OpenSSH_9.6\r\n")
+	silent := listenGreeting(t, "")
+	tests := []struct {
+		name     string
+		port     int
+		wait     time.Duration
+		expected string
+	}{
+		{"greeting", greeting, time.Second, "SSH-2.0-OpenSSH_9.6"},
+		{"no wait", greeting, 0, ""},
+		{"silent", silent, 100 * time.Millisecond, ""},
+	}
+	for _, tt := range tests {
+		scanner := &PortScanner{Quiet: true, BannerWait: tt.wait}
+		runTCPWorker(scanner, "127.0.0.1", []int{tt.port})
+
+		svc, ok := <-scanner.OpenPorts
+		if !ok || svc.State != "Open" {
+			t.Fatalf("%s: Expected port %d to be open, got %+v", tt.name, tt.port, svc)
+		}
+		if svc.Banner != tt.expected {
+			t.Errorf("%s: Expected banner %q, got %q", tt.name, tt.expected, svc.Banner)
+		}
+	}
+}
+
+// FuzzDetectServiceBanner checks that no greeting makes the banner path of a
+// TCP probe panic: readBanner, then the known-vulnerability lookup. The
+// banner stays within maxBannerSize and trimmed, and a note only ever comes
+// from the table. Run it with:
+//
+//	go test -run '^$' -fuzz FuzzDetectServiceBanner -fuzztime 30s
+func FuzzDetectServiceBanner(f *testing.F) {
+	for _, seed := range []string{
+		"SSH-2.0-OpenSSH_9.6\r\n",
+		"SSH-2.0-OpenSSH_7.2p2 Ubuntu-4ubuntu2.8\r\n",
+		"220 (vsFTPd 2.3.4)\r\n",
+		"Apache/2.4.49  (Unix)",
+		"  220 ProFTPD   1.3.5\r\nServer\t",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+	notes := map[string]bool{}
+	for _, note := range service.KnownVulns {
+		notes[note] = true
+	}
+	f.Fuzz(func(t *testing.T, greeting []byte) {
+		client, server := net.Pipe()
+		defer client.Close()
+		go func() {
+			server.Write(greeting)
+			server.Close()
+		}()
+
+		banner := readBanner(client, time.Second)
+		if len(banner) > maxBannerSize {
+			t.Fatalf("Expected at most %d bytes, got %d", maxBannerSize, len(banner))
+		}
+		if banner != strings.TrimSpace(banner) {
+			t.Fatalf("Expected a trimmed banner, got %q", banner)
+		}
+		if note := service.VulnFor(banner, service.KnownVulns); note != "" && !notes[note] {
+			t.Fatalf("Unexpected note %q for %q", note, banner)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package main
+
+// loadBaseline reads the report a scan is compared against with -baseline:
+// the last report in the file, which may hold a single JSON report or a
+// store written by AppendRun.
+//
+// Parameters:
+// - path: The baseline file.
+//
+// Returns:
+// - The baseline report.
+// - false if the file does not exist yet or is empty, so there is nothing to compare to.
+// - An error if the file cannot be read or holds an invalid report.
+func loadBaseline(path string) (ScanReport, bool, error) {
+	runs, err := LoadRuns(path)
+	if err != nil {
+		return ScanReport{}, false, err
+	}
+	if len(runs) == 0 {
+		return ScanReport{}, false, nil
+	}
+	return runs[len(runs)-1], true, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// runCapturingStdout runs the command line and returns its exit code and
+// what it printed.
+func runCapturingStdout(t *testing.T, args []string) (int, string) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %s", err)
+	}
+	printed := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		printed <- string(data)
+	}()
+	stdout := os.Stdout
+	os.Stdout = w
+	code := run(args, strings.NewReader(""))
+	os.Stdout = stdout
+	w.Close()
+	return code, <-printed
+}
+
+func TestRun_Baseline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	open := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	// A port that was just released is closed until it is listened on again
+	later, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	laterAddr := later.Addr().String()
+	laterPort := strconv.Itoa(later.Addr().(*net.TCPAddr).Port)
+	later.Close()
+
+	dir := t.TempDir()
+	baseline := filepath.Join(dir, "baseline.json")
+	args := []string{"-quiet", "-o", filepath.Join(dir, "output.txt"), "-ports", open + "," + laterPort, "-baseline", baseline, "127.0.0.1"}
+
+	if code := run(args, strings.NewReader("")); code != ExitOpen {
+		t.Fatalf("Expected the first run to save the baseline and exit with %d, got %d", ExitOpen, code)
+	}
+	if _, err := os.Stat(baseline); err != nil {
+		t.Fatalf("Expected the baseline to be saved: %s", err)
+	}
+	if code := run(args, strings.NewReader("")); code != ExitOpen {
+		t.Errorf("Expected no drift against an unchanged host, got exit code %d", code)
+	}
+
+	reopened, err := net.Listen("tcp", laterAddr)
+	if err != nil {
+		t.Skipf("Port %s was taken in the meantime: %s", laterPort, err)
+	}
+	defer reopened.Close()
+	code, printed := runCapturingStdout(t, append([]string{"-summary"}, args[1:]...))
+	if code != ExitDrift {
+		t.Errorf("Expected exit code %d for a newly opened port, got %d", ExitDrift, code)
+	}
+	if !strings.Contains(printed, "Opened: 127.0.0.1 port "+laterPort+" (TCP)") {
+		t.Errorf("Expected the new open port in the printed delta, got %q", printed)
+	}
+}
+
+func TestRun_BaselineInvalid(t *testing.T) {
+	dir := t.TempDir()
+	baseline := filepath.Join(dir, "baseline.json")
+	if err := os.WriteFile(baseline, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+	args := []string{"-quiet", "-o", filepath.Join(dir, "output.txt"), "-ports", "1", "-baseline", baseline, "127.0.0.1"}
+	if code := run(args, strings.NewReader("")); code != ExitError {
+		t.Errorf("Expected exit code %d for a corrupt baseline, got %d", ExitError, code)
+	}
+}
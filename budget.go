@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"det/service"
+)
+
+// StateUnscanned is reported for ports left unprobed because their IP used
+// up PerHostBudget.
+const StateUnscanned State = "Unscanned (budget)"
+
+// overBudget reports whether an IP has used up PerHostBudget, counting the
+// endpoint as unscanned if so.
+//
+// Parameters:
+// - ip: The IP address about to be probed.
+//
+// Returns:
+// - true if the endpoint must be skipped.
+func (t *PortScanner) overBudget(ip string) bool {
+	if t.PerHostBudget <= 0 {
+		return false
+	}
+	t.reportMu.Lock()
+	defer t.reportMu.Unlock()
+	if t.hostSpent[ip] < t.PerHostBudget {
+		return false
+	}
+	if t.collected.Unscanned == nil {
+		t.collected.Unscanned = map[string]int{}
+	}
+	t.collected.Unscanned[ip]++
+	return true
+}
+
+// chargeBudget adds the time spent probing an endpoint to its IP's share of
+// PerHostBudget. It is meant to be deferred with the probe's start time.
+//
+// Parameters:
+// - ip: The probed IP address.
+// - start: When the probe started.
+func (t *PortScanner) chargeBudget(ip string, start time.Time) {
+	if t.PerHostBudget <= 0 {
+		return
+	}
+	t.reportMu.Lock()
+	defer t.reportMu.Unlock()
+	if t.hostSpent == nil {
+		t.hostSpent = map[string]time.Duration{}
+	}
+	t.hostSpent[ip] += time.Since(start)
+}
+
+// skipOverBudget reports an endpoint left unprobed by PerHostBudget, if
+// StateUnscanned is among the reported states.
+//
+// Parameters:
+// - endpoint: The skipped endpoint.
+// - protocol: The protocol it was to be probed with, "TCP" or "UDP".
+// - openPorts: A channel to send the result to.
+func (t *PortScanner) skipOverBudget(endpoint Endpoint, protocol string, openPorts chan service.ServiceVersion) {
+	t.countState(string(StateUnscanned))
+	if !t.reportsState(string(StateUnscanned)) {
+		return
+	}
+	svc := t.detectService(endpoint.Port, protocol)
+	svc.IP = endpoint.IP
+	svc.Protocol = protocol
+	svc.State = string(StateUnscanned)
+	svc.Reason = "per-host budget exceeded"
+	svc.Timestamp = time.Now()
+	t.sendResult(openPorts, svc)
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
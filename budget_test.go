@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestPerHostBudget_CutsOffSlowHost(t *testing.T) {
+	slow := &mockDialer{delay: 10 * time.Millisecond}
+	fast := &mockDialer{}
+	ports := make([]int, 50)
+	for i := range ports {
+		ports[i] = 40001 + i
+	}
+	scanner := &PortScanner{
+		IPs:        []string{"127.0.0.1", "127.0.0.2"},
+		Ports:      ports,
+		NumWorkers: 2,
+		Dial: func(network, address string, timeout time.Duration) (net.Conn, error) {
+			if strings.HasPrefix(address, "127.0.0.2:") {
+				return slow.Dial(network, address, timeout)
+			}
+			return fast.Dial(network, address, timeout)
+		},
+		TCPPortChannel: make(chan Endpoint, 100),
+		UDPPortChannel: make(chan Endpoint, 100),
+		OpenPorts:      make(chan service.ServiceVersion, 200),
+		OpenPortsUDP:   make(chan service.ServiceVersion, 200),
+		ICMPResults:    make(chan ICMPResult, 2),
+		Done:           make(chan bool, 6),
+		PerHostBudget:  50 * time.Millisecond,
+		ReportStates:   []string{"Open", string(StateUnscanned)},
+		Quiet:          true,
+		pinger:         func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} },
+	}
+
+	start := time.Now()
+	scanner.Scan()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the slow host to be cut off, the scan took %s", elapsed)
+	}
+
+	report := scanner.Report()
+	states := map[string]map[string]int{}
+	for _, svc := range append(report.TCP, report.UDP...) {
+		if states[svc.IP] == nil {
+			states[svc.IP] = map[string]int{}
+		}
+		states[svc.IP][svc.State]++
+	}
+	if states["127.0.0.1"][string(StateUnscanned)] != 0 || states["127.0.0.1"]["Open"] != 50 {
+		t.Errorf("Expected every TCP port of the fast host scanned, got %v", states["127.0.0.1"])
+	}
+	if states["127.0.0.2"][string(StateUnscanned)] == 0 {
+		t.Errorf("Expected the slow host to be cut off, got %v", states["127.0.0.2"])
+	}
+	if report.Unscanned["127.0.0.2"] != states["127.0.0.2"][string(StateUnscanned)] {
+		t.Errorf("Expected %d unscanned probes counted, got %d", states["127.0.0.2"][string(StateUnscanned)], report.Unscanned["127.0.0.2"])
+	}
+
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, fileName); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, _ := os.ReadFile(fileName)
+	if !strings.Contains(string(data), "IP 127.0.0.2: ") || !strings.Contains(string(data), "per-host budget of 50ms used up") {
+		t.Errorf("Expected the budget note in the report, got:\n%s", data)
+	}
+}
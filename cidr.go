@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+)
+
+// DefaultMaxHosts caps how many IPs a CIDR target expands to, so a /8
+// cannot turn into 16 million hosts by accident.
+const DefaultMaxHosts = 65536
+
+// maxEndpointBuffer caps the endpoint channels, which otherwise hold every
+// probe of the scan: 65535 ports of a /16 would be four billion endpoints.
+const maxEndpointBuffer = 1 << 20
+
+// ExpandCIDR lists every IP address of a CIDR range, network and broadcast
+// addresses included.
+//
+// Parameters:
+// - cidr: The range, e.g. "192.168.1.0/24".
+// - maxHosts: The most addresses to expand to (0 for DefaultMaxHosts).
+//
+// Returns:
+// - The addresses, in order.
+// - An error if cidr is invalid or holds more than maxHosts addresses.
+//
+// Example:
+//
+//	ips, err := ExpandCIDR("192.168.1.0/30", 0)
+//	// ips: ["192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"]
+func ExpandCIDR(cidr string, maxHosts int) ([]string, error) {
+	if maxHosts <= 0 {
+		maxHosts = DefaultMaxHosts
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	ones, bits := network.Mask.Size()
+	hosts := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	if hosts.Cmp(big.NewInt(int64(maxHosts))) > 0 {
+		return nil, fmt.Errorf("%s expands to %s hosts, more than the limit of %d; raise MaxHosts to scan it", cidr, hosts, maxHosts)
+	}
+
+	ips := make([]string, 0, int(hosts.Int64()))
+	ip := append(net.IP(nil), network.IP...)
+	for i := int64(0); i < hosts.Int64(); i++ {
+		ips = append(ips, ip.String())
+		incrementIP(ip)
+	}
+	return ips, nil
+}
+
+// incrementIP advances ip in place to the next address, carrying into the
+// higher bytes.
+func incrementIP(ip net.IP) {
+	for j := len(ip) - 1; j >= 0; j-- {
+		ip[j]++
+		if ip[j] != 0 {
+			break
+		}
+	}
+}
+
+// isCIDR reports whether a target is a CIDR range rather than a host.
+func isCIDR(target string) bool {
+	if !strings.Contains(target, "/") {
+		return false
+	}
+	_, _, err := net.ParseCIDR(target)
+	return err == nil
+}
+
+// NewCIDRTarget creates a new PortScanner instance scanning every IP of a
+// CIDR range. NewTarget does the same with MaxHosts left at
+// DefaultMaxHosts.
+//
+// Parameters:
+// - cidr: The range to scan, e.g. "192.168.1.0/24".
+// - numWorkers: The number of worker goroutines to use for scanning.
+// - maxHosts: The most IPs the range may expand to (0 for DefaultMaxHosts).
+//
+// Returns:
+// - A pointer to a newly created PortScanner instance.
+// - An error if numWorkers is below 1, or cidr is invalid or larger than maxHosts.
+//
+// Example:
+//
+//	scanner, err := NewCIDRTarget("10.0.0.0/8", 100, 1<<24)
+func NewCIDRTarget(cidr string, numWorkers, maxHosts int) (*PortScanner, error) {
+	if err := validateWorkers(numWorkers); err != nil {
+		return nil, err
+	}
+	if maxHosts <= 0 {
+		maxHosts = DefaultMaxHosts
+	}
+	ips, err := ExpandCIDR(cidr, maxHosts)
+	if err != nil {
+		return nil, err
+	}
+	t := newTargetFromResolution(cidr, numWorkers, Resolution{Host: cidr, IPs: ips}, false)
+	t.MaxHosts = maxHosts
+	return t, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewTarget_CIDR(t *testing.T) {
+	if _, err := NewTarget("10.0.0.0/8", 1); err == nil || !strings.Contains(err.Error(), "limit of 65536") {
+		t.Errorf("Expected a /8 to exceed DefaultMaxHosts, got %v", err)
+	}
+
+	scanner, err := NewTarget("192.168.7.0/24", 1)
+	if err != nil {
+		t.Fatalf("Expected a /24 to be scanned, got %s", err)
+	}
+	if len(scanner.IPs) != 256 || scanner.IPs[0] != "192.168.7.0" || scanner.IPs[255] != "192.168.7.255" {
+		t.Errorf("Expected the 256 IPs of the /24, got %d from %v", len(scanner.IPs), scanner.IPs[:1])
+	}
+	if scanner.MaxHosts != DefaultMaxHosts {
+		t.Errorf("Expected MaxHosts %d, got %d", DefaultMaxHosts, scanner.MaxHosts)
+	}
+	if cap(scanner.TCPPortChannel) > maxEndpointBuffer {
+		t.Errorf("Expected the endpoint channel capped to %d, got %d", maxEndpointBuffer, cap(scanner.TCPPortChannel))
+	}
+}
+
+func TestNewCIDRTarget_MaxHosts(t *testing.T) {
+	if _, err := NewCIDRTarget("10.1.0.0/20", 1, 1024); err == nil {
+		t.Errorf("Expected a /20 to exceed a limit of 1024 hosts")
+	}
+	scanner, err := NewCIDRTarget("10.1.0.0/20", 1, 4096)
+	if err != nil {
+		t.Fatalf("Expected a raised limit to allow the /20, got %s", err)
+	}
+	if len(scanner.IPs) != 4096 || scanner.MaxHosts != 4096 {
+		t.Errorf("Expected 4096 IPs under a limit of 4096, got %d and %d", len(scanner.IPs), scanner.MaxHosts)
+	}
+}
+
+func TestExpandCIDR(t *testing.T) {
+	tests := []struct {
+		cidr     string
+		expected []string
+	}{
+		{"192.168.1.0/30", []string{"192.168.1.0", "192.168.1.1", "192.168.1.2", "192.168.1.3"}},
+		{"10.0.0.255/31", []string{"10.0.0.254", "10.0.0.255"}},
+		{"10.0.0.255/32", []string{"10.0.0.255"}},
+		{"10.0.0.254/31", []string{"10.0.0.254", "10.0.0.255"}},
+		{"2001:db8::/127", []string{"2001:db8::", "2001:db8::1"}},
+	}
+	for _, tt := range tests {
+		ips, err := ExpandCIDR(tt.cidr, 0)
+		if err != nil {
+			t.Errorf("%s: Expected no error, got %s", tt.cidr, err)
+			continue
+		}
+		if !reflect.DeepEqual(ips, tt.expected) {
+			t.Errorf("%s: Expected %v, got %v", tt.cidr, tt.expected, ips)
+		}
+	}
+	if _, err := ExpandCIDR("2001:db8::/32", 0); err == nil {
+		t.Errorf("Expected an IPv6 /32 to exceed the limit")
+	}
+	if _, err := ExpandCIDR("10.0.0.0/33", 0); err == nil {
+		t.Errorf("Expected an error for an invalid CIDR")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+// resultBuffer is the capacity of the result channels: a few results per
+// worker rather than one per probe, which for 65535 ports preallocated tens
+// of megabytes per target before anything was found.
+func resultBuffer(numWorkers int) int {
+	return streamBuffer(numWorkers)
+}
+
+// collect starts a goroutine that moves the results arriving on the result
+// channels into the report as they come, until every channel is closed. A
+// worker sending while the collector is busy blocks, so the channels apply
+// backpressure instead of growing. The results still end up in Report;
+// in streaming mode nothing is sent and the report stays empty. With
+// IdleTimeout, every result and answered probe resets an idle timer, and
+// the scan is aborted when it fires.
+//
+// Returns:
+// - A function that waits until the collector has seen every channel closed.
+func (t *PortScanner) collect() func() {
+	done := make(chan struct{})
+	tcp, udp, icmp := t.OpenPorts, t.OpenPortsUDP, t.ICMPResults
+	idle := newIdleTimer(t.IdleTimeout)
+	go func() {
+		defer close(done)
+		defer idle.stop()
+		for tcp != nil || udp != nil || icmp != nil {
+			idle.reset()
+			select {
+			case <-t.answered:
+				continue
+			case <-idle.C:
+				t.abortIdle()
+				idle.stop()
+				continue
+			case svc, ok := <-tcp:
+				if !ok {
+					tcp = nil
+					continue
+				}
+				t.reportMu.Lock()
+				t.collected.TCP = append(t.collected.TCP, svc)
+				t.reportMu.Unlock()
+			case svc, ok := <-udp:
+				if !ok {
+					udp = nil
+					continue
+				}
+				t.reportMu.Lock()
+				t.collected.UDP = append(t.collected.UDP, svc)
+				t.reportMu.Unlock()
+			case result, ok := <-icmp:
+				if !ok {
+					icmp = nil
+					continue
+				}
+				t.reportMu.Lock()
+				t.collected.ICMP = append(t.collected.ICMP, result)
+				t.reportMu.Unlock()
+			}
+		}
+	}()
+	return func() { <-done }
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// largeBanner is the banner every port of bannerTarget sends.
+var largeBanner = strings.Repeat("x", 4096)
+
+// bannerProber finds every port open with largeBanner.
+type bannerProber struct{}
+
+func (bannerProber) Probe(ctx context.Context, ip string, port int) (State, error) {
+	return StateOpen, nil
+}
+
+func (bannerProber) ProbeBanner(ctx context.Context, ip string, port int) (State, string, error) {
+	return StateOpen, largeBanner, nil
+}
+
+// bannerTarget creates a scanner of ports 1 to ports on 127.0.0.1 where every
+// TCP port is open with a large banner.
+func bannerTarget(ports int, streaming bool) *PortScanner {
+	scanner := newTargetFromResolution("127.0.0.1", 8, Resolution{Host: "127.0.0.1", IPs: []string{"127.0.0.1"}}, streaming)
+	scanner.Ports = allPorts()[:ports]
+	scanner.TCPProber = bannerProber{}
+	scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+	scanner.EnableICMP = false
+	scanner.HTTPProbe = false
+	scanner.Quiet = true
+	scanner.OnResult = func(ScanEvent) {}
+	return scanner
+}
+
+func TestScan_CollectsThroughBoundedChannels(t *testing.T) {
+	scanner := bannerTarget(5000, false)
+	if cap(scanner.OpenPorts) > 8 || cap(scanner.OpenPortsUDP) > 8 {
+		t.Errorf("Expected result channels sized to the workers, got %d and %d", cap(scanner.OpenPorts), cap(scanner.OpenPortsUDP))
+	}
+
+	scanner.Scan()
+
+	report := scanner.Report()
+	if len(report.TCP) != 5000 {
+		t.Fatalf("Expected all 5000 open ports in the report, got %d", len(report.TCP))
+	}
+	for _, svc := range report.TCP {
+		if svc.Banner != largeBanner {
+			t.Fatalf("Expected port %d to keep its banner, got %d bytes", svc.Port, len(svc.Banner))
+		}
+	}
+}
+
+// peakHeap samples the heap in use until stop is closed and returns the
+// largest sample.
+func peakHeap(stop chan struct{}) chan uint64 {
+	peak := make(chan uint64, 1)
+	go func() {
+		var stats runtime.MemStats
+		var max uint64
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > max {
+				max = stats.HeapInuse
+			}
+			select {
+			case <-stop:
+				peak <- max
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return peak
+}
+
+// BenchmarkScan_LargeBanners scans 65535 open ports with 4 KiB banners and
+// reports the peak heap. The result channels only hold a few results, so
+// in streaming mode the peak stays flat however many ports are open, while
+// the collected report grows with the results alone.
+func BenchmarkScan_LargeBanners(b *testing.B) {
+	for _, mode := range []struct {
+		name      string
+		streaming bool
+	}{{"Collected", false}, {"Streaming", true}} {
+		b.Run(mode.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				stop := make(chan struct{})
+				sampled := peakHeap(stop)
+				bannerTarget(65535, mode.streaming).Scan()
+				close(stop)
+				if p := <-sampled; p > peak {
+					peak = p
+				}
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-MiB")
+		})
+	}
+}
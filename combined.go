@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// PortResult is the combined view of one port on one IP, with its TCP and
+// UDP states side by side.
+//
+// Fields:
+// - IP: The IP address the port was scanned on.
+// - Port: The port number.
+// - TCP: The TCP state, empty if no TCP result was reported.
+// - UDP: The UDP state, empty if no UDP result was reported.
+// - Service: The service name of the port.
+//
+// Example:
+//
+//	result := PortResult{IP: "192.0.2.1", Port: 53, TCP: StateOpen, UDP: StateOpen, Service: "domain"}
+type PortResult struct {
+	IP      string `json:"ip,omitempty"`
+	Port    int    `json:"port"`
+	TCP     State  `json:"tcp,omitempty"`
+	UDP     State  `json:"udp,omitempty"`
+	Service string `json:"service"`
+}
+
+// CombinePorts merges the TCP and UDP results of a report into one
+// PortResult per IP and port. Only reported states appear, so include
+// "Closed" in ReportStates to see closed ports next to open ones.
+//
+// Parameters:
+// - report: The scan report.
+//
+// Returns:
+// - The combined results, sorted by IP, then port.
+//
+// Example:
+//
+//	for _, result := range CombinePorts(scanner.Report()) {
+//	    fmt.Printf("%d: TCP %s, UDP %s\n", result.Port, result.TCP, result.UDP)
+//	}
+func CombinePorts(report ScanReport) []PortResult {
+	type portKey struct {
+		ip   string
+		port int
+	}
+	index := map[portKey]int{}
+	var results []PortResult
+	entry := func(ip string, port int, service string) *PortResult {
+		key := portKey{ip, port}
+		i, ok := index[key]
+		if !ok {
+			i = len(results)
+			index[key] = i
+			results = append(results, PortResult{IP: ip, Port: port, Service: service})
+		}
+		return &results[i]
+	}
+	for _, svc := range report.TCP {
+		entry(svc.IP, svc.Port, svc.Service).TCP = State(svc.State)
+	}
+	for _, svc := range report.UDP {
+		result := entry(svc.IP, svc.Port, svc.Service)
+		result.UDP = State(svc.State)
+		if result.Service == "" || result.Service == "Unknown" {
+			result.Service = svc.Service
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].IP != results[j].IP {
+			return results[i].IP < results[j].IP
+		}
+		return results[i].Port < results[j].Port
+	})
+	return results
+}
+
+// WriteCombined writes combined results as an aligned table with the TCP
+// and UDP states of each port side by side. A protocol without a reported
+// state is shown as "-".
+//
+// Parameters:
+// - w: Where to write.
+// - results: The combined results, e.g. from CombinePorts.
+//
+// Returns:
+// - An error if writing fails.
+//
+// Example:
+//
+//	err := WriteCombined(os.Stdout, CombinePorts(scanner.Report()))
+func WriteCombined(w io.Writer, results []PortResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "IP\tPORT\tTCP\tUDP\tSERVICE")
+	for _, result := range results {
+		fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\n", result.IP, result.Port, stateCell(result.TCP), stateCell(result.UDP), result.Service)
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("error writing to file: %s", err)
+	}
+	return nil
+}
+
+// stateCell formats a state for the combined table.
+func stateCell(state State) string {
+	if state == "" {
+		return "-"
+	}
+	return string(state)
+}
+
+// writeCombinedFile writes the scan metadata and the combined table of the
+// scan results to a file.
+//
+// Parameters:
+// - t: The scanner whose results are written.
+// - fileName: The file to write.
+//
+// Returns:
+// - An error if the file cannot be written.
+func writeCombinedFile(t *PortScanner, fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("error creating file: %s", err)
+	}
+	defer file.Close()
+	report := t.Report()
+	if _, err := fmt.Fprintln(file, report.Meta); err != nil {
+		return fmt.Errorf("error writing to file: %s", err)
+	}
+	return WriteCombined(file, CombinePorts(report))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"det/service"
+)
+
+func TestCombinePorts_WriteCombined(t *testing.T) {
+	report := ScanReport{
+		TCP: []service.ServiceVersion{
+			{IP: "192.0.2.1", Port: 80, Service: "http", State: "Open"},
+			{IP: "192.0.2.1", Port: 53, Service: "domain", State: "Open"},
+		},
+		UDP: []service.ServiceVersion{
+			{IP: "192.0.2.1", Port: 53, Service: "domain", State: "Closed"},
+			{IP: "192.0.2.1", Port: 161, Service: "snmp", State: "Open"},
+		},
+	}
+
+	results := CombinePorts(report)
+	expected := []PortResult{
+		{IP: "192.0.2.1", Port: 53, TCP: StateOpen, UDP: StateClosed, Service: "domain"},
+		{IP: "192.0.2.1", Port: 80, TCP: StateOpen, Service: "http"},
+		{IP: "192.0.2.1", Port: 161, UDP: StateOpen, Service: "snmp"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, results)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCombined(&buf, results); err != nil {
+		t.Fatalf("WriteCombined failed: %s", err)
+	}
+	table := "IP         PORT  TCP   UDP     SERVICE\n" +
+		"192.0.2.1  53    Open  Closed  domain\n" +
+		"192.0.2.1  80    Open  -       http\n" +
+		"192.0.2.1  161   -     Open    snmp\n"
+	if buf.String() != table {
+		t.Errorf("Expected:\n%s\ngot:\n%s", table, buf.String())
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "context"
+
+// confirmOpen probes a port found open once more when ConfirmOpen is set,
+// so a flaky link answering a single probe is not reported as a confident
+// open. A port is only kept open if both probes agree; otherwise it is
+// reported Filtered with ReasonUnconfirmed.
+//
+// Parameters:
+// - ctx: The context of the scan.
+// - prober: The prober that found the port open.
+// - endpoint: The endpoint probed.
+// - state: The state of the first probe.
+// - detail: The banner and reason of the first probe.
+//
+// Returns:
+// - The confirmed state.
+// - The detail to report: the first probe's if the port stays open.
+func (t *PortScanner) confirmOpen(ctx context.Context, prober Prober, endpoint Endpoint, state string, detail ProbeDetail) (string, ProbeDetail) {
+	if !t.ConfirmOpen || state != "Open" {
+		return state, detail
+	}
+	second, _, _ := probe(ctx, prober, endpoint)
+	if second == "Open" {
+		return state, detail
+	}
+	t.logPort("%s Port %d: Open, then %s on the confirming probe\n", endpoint.IP, endpoint.Port, second)
+	return "Filtered", ProbeDetail{Reason: ReasonUnconfirmed}
+}
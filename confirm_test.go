@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestConfirmOpen(t *testing.T) {
+	calls := map[int]int{}
+	scanner := &PortScanner{
+		ConfirmOpen:  true,
+		ReportStates: []string{"Open", "Filtered"},
+		Quiet:        true,
+		// Port 22 stays open, 23 is open once and then closed
+		TCPProber: ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+			calls[port]++
+			if port == 23 && calls[port] > 1 {
+				return StateClosed, nil
+			}
+			return StateOpen, nil
+		}),
+	}
+	runTCPWorker(scanner, "127.0.0.1", []int{22, 23})
+
+	results := map[int][2]string{}
+	for svc := range scanner.OpenPorts {
+		results[svc.Port] = [2]string{svc.State, svc.Reason}
+	}
+	if results[22][0] != "Open" {
+		t.Errorf("Expected port 22 to be confirmed Open, got %v", results[22])
+	}
+	if results[23] != [2]string{"Filtered", ReasonUnconfirmed} {
+		t.Errorf("Expected port 23 to be Filtered as unconfirmed, got %v", results[23])
+	}
+	if calls[22] != 2 || calls[23] != 2 {
+		t.Errorf("Expected every open port to be probed twice, got %v", calls)
+	}
+}
+
+func TestConfirmOpen_Disabled(t *testing.T) {
+	calls := 0
+	scanner := &PortScanner{
+		Quiet: true,
+		TCPProber: ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+			calls++
+			return StateOpen, nil
+		}),
+	}
+	runTCPWorker(scanner, "127.0.0.1", []int{22})
+	if calls != 1 {
+		t.Errorf("Expected a single probe without ConfirmOpen, got %d", calls)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net"
+	"sync"
+)
+
+// initialUDPWindow is the number of UDP probes allowed in flight when a scan
+// with CongestionControl starts.
+const initialUDPWindow = 4
+
+// CongestionWindow limits the number of probes in flight with additive
+// increase, multiplicative decrease (AIMD): every answered probe grows the
+// window by 1/window, so it grows by about one probe per round trip, and every
+// lost probe halves it.
+//
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	window := NewCongestionWindow(4, 100)
+//	window.Acquire()
+//	err := probe()
+//	window.Release(isTimeout(err))
+type CongestionWindow struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	window   float64
+	max      float64
+	inFlight int
+}
+
+// NewCongestionWindow creates a congestion window.
+//
+// Parameters:
+// - initial: The initial number of probes allowed in flight.
+// - max: The largest the window may grow to.
+//
+// Returns:
+// - A pointer to the new CongestionWindow.
+func NewCongestionWindow(initial, max int) *CongestionWindow {
+	if max < 1 {
+		max = 1
+	}
+	if initial < 1 {
+		initial = 1
+	}
+	if initial > max {
+		initial = max
+	}
+	w := &CongestionWindow{window: float64(initial), max: float64(max)}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// Acquire blocks until another probe fits in the window and counts it as in flight.
+func (w *CongestionWindow) Acquire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.inFlight >= int(w.window) {
+		w.cond.Wait()
+	}
+	w.inFlight++
+}
+
+// Release marks a probe acquired with Acquire as finished and adjusts the window.
+//
+// Parameters:
+// - lost: Whether the probe got no answer, which is taken as a sign of congestion.
+func (w *CongestionWindow) Release(lost bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight--
+	if lost {
+		w.window /= 2
+		if w.window < 1 {
+			w.window = 1
+		}
+	} else {
+		w.window += 1 / w.window
+		if w.window > w.max {
+			w.window = w.max
+		}
+	}
+	w.cond.Broadcast()
+}
+
+// Size returns the number of probes currently allowed in flight.
+func (w *CongestionWindow) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return int(w.window)
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// windowed runs a UDP probe within the scanner's congestion window, if
+// CongestionControl is enabled. A probe that times out counts as lost.
+//
+// Parameters:
+// - probe: The probe to run.
+//
+// Returns:
+// - The error returned by probe.
+func (t *PortScanner) windowed(probe func() error) (err error) {
+	if t.udpWindow == nil {
+		return probe()
+	}
+	t.udpWindow.Acquire()
+	defer func() { t.udpWindow.Release(isTimeout(err)) }()
+	return probe()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lossyDialer answers UDP probes unless more than threshold are in flight,
+// in which case the probe is dropped and its read times out.
+type lossyDialer struct {
+	mu          sync.Mutex
+	threshold   int
+	inFlight    int
+	maxInFlight int
+}
+
+// lossyConn is a UDP connection from lossyDialer.
+type lossyConn struct {
+	net.Conn
+	dialer *lossyDialer
+	lost   bool
+}
+
+func (d *lossyDialer) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	client, server := net.Pipe()
+	server.Close()
+	if network != "udp" {
+		return client, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight++
+	if d.inFlight > d.maxInFlight {
+		d.maxInFlight = d.inFlight
+	}
+	return &lossyConn{Conn: client, dialer: d, lost: d.inFlight > d.threshold}, nil
+}
+
+func (c *lossyConn) Write(b []byte) (int, error) { return len(b), nil }
+
+func (c *lossyConn) Read(b []byte) (int, error) {
+	time.Sleep(2 * time.Millisecond)
+	if c.lost {
+		return 0, timeoutError{}
+	}
+	return 1, nil
+}
+
+func (c *lossyConn) Close() error {
+	c.dialer.mu.Lock()
+	c.dialer.inFlight--
+	c.dialer.mu.Unlock()
+	return c.Conn.Close()
+}
+
+func TestCongestionWindow_AIMD(t *testing.T) {
+	window := NewCongestionWindow(4, 8)
+	for i := 0; i < 4; i++ {
+		window.Acquire()
+		window.Release(false)
+	}
+	if size := window.Size(); size != 4 {
+		t.Errorf("Expected the window to grow by about one per round trip, got %d", size)
+	}
+	for i := 0; i < 100; i++ {
+		window.Acquire()
+		window.Release(false)
+	}
+	if size := window.Size(); size != 8 {
+		t.Errorf("Expected the window to be capped at 8, got %d", size)
+	}
+	window.Acquire()
+	window.Release(true)
+	if size := window.Size(); size != 4 {
+		t.Errorf("Expected the window to halve on loss, got %d", size)
+	}
+	for i := 0; i < 10; i++ {
+		window.Acquire()
+		window.Release(true)
+	}
+	if size := window.Size(); size != 1 {
+		t.Errorf("Expected the window to bottom out at 1, got %d", size)
+	}
+}
+
+func TestScan_CongestionControlBacksOff(t *testing.T) {
+	const workers = 32
+	dialer := &lossyDialer{threshold: 4}
+	scanner, err := NewTarget("127.0.0.1", workers)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = make([]int, 500)
+	for i := range scanner.Ports {
+		scanner.Ports[i] = 40001 + i
+	}
+	scanner.Dial = dialer.Dial
+	scanner.CongestionControl = true
+	scanner.Quiet = true
+
+	scanner.Scan()
+
+	if size := scanner.udpWindow.Size(); size > 2*dialer.threshold {
+		t.Errorf("Expected the window to back off to about %d probes, got %d", dialer.threshold, size)
+	}
+	if dialer.maxInFlight >= workers {
+		t.Errorf("Expected fewer than %d UDP probes in flight, got %d", workers, dialer.maxInFlight)
+	}
+}
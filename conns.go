@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// trackedConn is a probe connection counted in the scanner's open connections
+// until it is closed.
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+// Close closes the connection and releases its slot. Closing twice releases
+// the slot once.
+func (c *trackedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+// trackDial wraps a dial function so every connection it opens is counted
+// until closed, and new dials block while MaxOpenConns connections are open.
+// This bounds the sockets held at once by a huge scan against a slow target.
+//
+// Parameters:
+// - ctx: Cancelling it ends the wait for a free slot.
+// - dial: The dial function to wrap.
+//
+// Returns:
+// - The wrapped dial function.
+func (t *PortScanner) trackDial(ctx context.Context, dial DialFunc) DialFunc {
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		slots := t.openConnSlots()
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		release := func() {
+			atomic.AddInt64(&t.openConns, -1)
+			if slots != nil {
+				<-slots
+			}
+		}
+		atomic.AddInt64(&t.openConns, 1)
+
+		// Release the slot on a failed or panicking dial too
+		opened := false
+		defer func() {
+			if !opened {
+				release()
+			}
+		}()
+		conn, err := dial(network, address, timeout)
+		if err != nil {
+			atomic.AddInt64(&t.dialErrors, 1)
+			if conn != nil {
+				conn.Close()
+			}
+			return nil, err
+		}
+		opened = true
+		return &trackedConn{Conn: conn, release: release}, nil
+	}
+}
+
+// openConnSlots returns the semaphore limiting open connections, creating it
+// on first use, or nil when MaxOpenConns is unset.
+func (t *PortScanner) openConnSlots() chan struct{} {
+	t.connSlotsOnce.Do(func() {
+		if t.MaxOpenConns > 0 {
+			t.connSlots = make(chan struct{}, t.MaxOpenConns)
+		}
+	})
+	return t.connSlots
+}
+
+// OpenConns returns the number of probe connections currently open.
+//
+// Returns:
+// - The number of dialed connections not yet closed.
+//
+// Example:
+//
+//	fmt.Printf("%d sockets open\n", scanner.OpenConns())
+func (t *PortScanner) OpenConns() int64 {
+	return atomic.LoadInt64(&t.openConns)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// leakyDialer hands out connections that fail every write and panics when
+// dialing ports ending in 7, exercising the error and panic paths. It counts
+// the connections it handed out that were never closed.
+type leakyDialer struct {
+	mu      sync.Mutex
+	open    int
+	maxOpen int
+}
+
+// leakyConn is a connection from leakyDialer.
+type leakyConn struct {
+	net.Conn
+	dialer *leakyDialer
+	once   sync.Once
+}
+
+func (d *leakyDialer) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	if strings.HasSuffix(address, "7") {
+		panic("dial exploded")
+	}
+	client, server := net.Pipe()
+	server.Close()
+	d.mu.Lock()
+	d.open++
+	if d.open > d.maxOpen {
+		d.maxOpen = d.open
+	}
+	d.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	return &leakyConn{Conn: client, dialer: d}, nil
+}
+
+func (c *leakyConn) Write(b []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func (c *leakyConn) Close() error {
+	c.once.Do(func() {
+		c.dialer.mu.Lock()
+		c.dialer.open--
+		c.dialer.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+func TestScan_ClosesEveryConnection(t *testing.T) {
+	dialer := &leakyDialer{}
+	scanner, err := NewTarget("127.0.0.1", 16)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = make([]int, 200)
+	for i := range scanner.Ports {
+		scanner.Ports[i] = 40001 + i
+	}
+	scanner.Dial = dialer.Dial
+	scanner.MaxOpenConns = 4
+	scanner.Quiet = true
+
+	scanner.Scan()
+
+	if open := scanner.OpenConns(); open != 0 {
+		t.Errorf("Expected no open connections after the scan, got %d", open)
+	}
+	if dialer.open != 0 {
+		t.Errorf("Expected every dialed connection to be closed, %d were leaked", dialer.open)
+	}
+	if dialer.maxOpen > scanner.MaxOpenConns {
+		t.Errorf("Expected at most %d connections open at once, got %d", scanner.MaxOpenConns, dialer.maxOpen)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"det/service"
+)
+
+// ReportDiff lists what changed between two scans of the same target.
+//
+// Fields:
+// - Opened: Ports open in the new scan but not in the old one.
+// - Closed: Ports open in the old scan but not in the new one.
+// - Changed: Ports open in both scans whose detected service differs.
+//
+// Example:
+//
+//	diff := DiffReports(yesterday, today)
+//	for _, svc := range diff.Opened {
+//	    fmt.Printf("%s port %d opened\n", svc.IP, svc.Port)
+//	}
+type ReportDiff struct {
+	Opened  []service.ServiceVersion `json:"opened"`
+	Closed  []service.ServiceVersion `json:"closed"`
+	Changed []ServiceChange          `json:"changed"`
+}
+
+// ServiceChange is a port whose detected service differs between two scans.
+//
+// Fields:
+// - Old: The port as reported by the old scan.
+// - New: The port as reported by the new scan.
+type ServiceChange struct {
+	Old service.ServiceVersion `json:"old"`
+	New service.ServiceVersion `json:"new"`
+}
+
+// portKey identifies a port across scans.
+type portKey struct {
+	ip    string
+	port  int
+	proto string
+}
+
+// DiffReports compares two scan reports of the same target. Ports are matched
+// on IP, port and protocol, and only ports in the "Open" state count as open.
+//
+// Parameters:
+// - old: The earlier report.
+// - new: The later report.
+//
+// Returns:
+// - The ports that opened, closed or changed service, each sorted by IP,
+// protocol and port.
+//
+// Example:
+//
+//	diff := DiffReports(previous, scanner.Report())
+func DiffReports(old, new ScanReport) ReportDiff {
+	before := openPortsByKey(old)
+	after := openPortsByKey(new)
+
+	var diff ReportDiff
+	for key, svc := range after {
+		prev, ok := before[key]
+		if !ok {
+			diff.Opened = append(diff.Opened, svc)
+		} else if !prev.Equal(svc) {
+			diff.Changed = append(diff.Changed, ServiceChange{Old: prev, New: svc})
+		}
+	}
+	for key, svc := range before {
+		if _, ok := after[key]; !ok {
+			diff.Closed = append(diff.Closed, svc)
+		}
+	}
+
+	sortServices(diff.Opened)
+	sortServices(diff.Closed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return serviceLess(diff.Changed[i].New, diff.Changed[j].New)
+	})
+	return diff
+}
+
+// openPortsByKey indexes the open TCP and UDP ports of a report. The protocol
+// is taken from the section the port is reported in.
+func openPortsByKey(report ScanReport) map[portKey]service.ServiceVersion {
+	open := make(map[portKey]service.ServiceVersion)
+	sections := map[string][]service.ServiceVersion{"TCP": report.TCP, "UDP": report.UDP}
+	for proto, services := range sections {
+		for _, svc := range services {
+			if svc.State != "Open" {
+				continue
+			}
+			// Normalize the protocol so Equal compares the service alone
+			svc.Protocol = proto
+			open[portKey{ip: svc.IP, port: svc.Port, proto: proto}] = svc
+		}
+	}
+	return open
+}
+
+// sortServices orders services by IP, protocol and port.
+func sortServices(services []service.ServiceVersion) {
+	sort.Slice(services, func(i, j int) bool {
+		return serviceLess(services[i], services[j])
+	})
+}
+
+// serviceLess reports whether a sorts before b by IP, protocol and port.
+func serviceLess(a, b service.ServiceVersion) bool {
+	if a.IP != b.IP {
+		return a.IP < b.IP
+	}
+	if a.Protocol != b.Protocol {
+		return a.Protocol < b.Protocol
+	}
+	return a.Port < b.Port
+}
+
+// Empty reports whether the diff found no change at all.
+func (d ReportDiff) Empty() bool {
+	return len(d.Opened) == 0 && len(d.Closed) == 0 && len(d.Changed) == 0
+}
+
+// String formats the diff as one line per change, e.g.
+// "Opened: 192.0.2.1 port 22 (TCP), ssh".
+func (d ReportDiff) String() string {
+	var lines []string
+	for _, svc := range d.Opened {
+		lines = append(lines, fmt.Sprintf("Opened: %s port %d (%s), %s", svc.IP, svc.Port, svc.Protocol, svc.Service))
+	}
+	for _, svc := range d.Closed {
+		lines = append(lines, fmt.Sprintf("Closed: %s port %d (%s), %s", svc.IP, svc.Port, svc.Protocol, svc.Service))
+	}
+	for _, change := range d.Changed {
+		lines = append(lines, fmt.Sprintf("Changed: %s port %d (%s), %s -> %s", change.New.IP, change.New.Port, change.New.Protocol, change.Old.Service, change.New.Service))
+	}
+	return strings.Join(lines, "\n")
+}
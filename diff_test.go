@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"det/service"
+)
+
+func TestDiffReports(t *testing.T) {
+	open := func(ip string, port int, svc string) service.ServiceVersion {
+		return service.ServiceVersion{IP: ip, Port: port, Protocol: "Unknown", Service: svc, State: "Open"}
+	}
+	old := ScanReport{
+		TCP: []service.ServiceVersion{
+			open("192.0.2.1", 22, "OpenSSH 7.4"),
+			open("192.0.2.1", 80, "http"),
+			open("192.0.2.2", 443, "https"),
+		},
+		UDP: []service.ServiceVersion{open("192.0.2.1", 53, "domain")},
+	}
+	new := ScanReport{
+		TCP: []service.ServiceVersion{
+			open("192.0.2.1", 22, "OpenSSH 8.9"),
+			open("192.0.2.1", 80, "http"),
+			open("192.0.2.1", 8080, "http-alt"),
+			{IP: "192.0.2.2", Port: 443, Service: "https", State: "Filtered"},
+		},
+		UDP: []service.ServiceVersion{open("192.0.2.1", 53, "domain")},
+	}
+
+	diff := DiffReports(old, new)
+	if len(diff.Opened) != 1 || diff.Opened[0].IP != "192.0.2.1" || diff.Opened[0].Port != 8080 {
+		t.Errorf("Expected 192.0.2.1 port 8080 opened, got %v", diff.Opened)
+	}
+	if len(diff.Closed) != 1 || diff.Closed[0].IP != "192.0.2.2" || diff.Closed[0].Port != 443 {
+		t.Errorf("Expected 192.0.2.2 port 443 closed, got %v", diff.Closed)
+	}
+	if len(diff.Changed) != 1 {
+		t.Fatalf("Expected 1 changed service, got %v", diff.Changed)
+	}
+	change := diff.Changed[0]
+	if change.New.Port != 22 || change.Old.Service != "OpenSSH 7.4" || change.New.Service != "OpenSSH 8.9" {
+		t.Errorf("Expected port 22 to change from OpenSSH 7.4 to OpenSSH 8.9, got %v", change)
+	}
+}
+
+func TestDiffReports_KeysOnProtocolAndIP(t *testing.T) {
+	svc := service.ServiceVersion{IP: "192.0.2.1", Port: 53, Service: "domain", State: "Open"}
+	other := svc
+	other.IP = "192.0.2.2"
+	old := ScanReport{TCP: []service.ServiceVersion{svc}}
+	new := ScanReport{UDP: []service.ServiceVersion{svc}, TCP: []service.ServiceVersion{other}}
+
+	diff := DiffReports(old, new)
+	if len(diff.Opened) != 2 {
+		t.Errorf("Expected UDP port 53 and 192.0.2.2 port 53 opened, got %v", diff.Opened)
+	}
+	if len(diff.Closed) != 1 || diff.Closed[0].Protocol != "TCP" {
+		t.Errorf("Expected TCP port 53 on 192.0.2.1 closed, got %v", diff.Closed)
+	}
+	if len(diff.Changed) != 0 {
+		t.Errorf("Expected no changed services, got %v", diff.Changed)
+	}
+}
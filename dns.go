@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// NewDNSResolver creates a resolver that sends every query to server
+// instead of the servers of /etc/resolv.conf, e.g. an internal DNS server
+// in a split-horizon network. /etc/hosts is still consulted first.
+//
+// Parameters:
+// - server: The DNS server as host:port, e.g. "10.0.0.53:53".
+//
+// Returns:
+// - The resolver.
+// - An error if server is not a valid host:port address.
+//
+// Example:
+//
+//	resolver, err := NewDNSResolver("10.0.0.53:53")
+//	if err == nil {
+//	    scanner, err = NewTargetWithResolver("intranet.example", 100, resolver)
+//	}
+func NewDNSResolver(server string) (*net.Resolver, error) {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DNS server %q: %s", server, err)
+	}
+	if host == "" {
+		return nil, fmt.Errorf("invalid DNS server %q: missing host", server)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return nil, fmt.Errorf("invalid DNS server %q: invalid port %q", server, port)
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}, nil
+}
+
+// NewTargetWithDNSServer creates a new PortScanner instance, resolving the
+// domain through the given DNS server. The scanner keeps the server in
+// DNSServer, so its reverse lookups use it as well.
+//
+// Parameters:
+// - domain: The domain to scan.
+// - numWorkers: The number of worker goroutines to use for scanning.
+// - server: The DNS server as host:port.
+//
+// Returns:
+// - A pointer to a newly created PortScanner instance.
+// - An error if the server address is invalid or the domain cannot be resolved through it.
+//
+// Example:
+//
+//	scanner, err := NewTargetWithDNSServer("intranet.example", 100, "10.0.0.53:53")
+func NewTargetWithDNSServer(domain string, numWorkers int, server string) (*PortScanner, error) {
+	resolver, err := NewDNSResolver(server)
+	if err != nil {
+		return nil, err
+	}
+	t, err := NewTargetWithResolver(domain, numWorkers, resolver)
+	if err != nil {
+		return nil, err
+	}
+	t.DNSServer = server
+	return t, nil
+}
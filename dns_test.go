@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+// stubDNSServer answers every A query on a loopback UDP port with answer and
+// every other query with no records. The queried names are sent to the
+// returned channel.
+func stubDNSServer(t *testing.T, answer net.IP) (string, chan string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	queries := make(chan string, 16)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 12 {
+				continue
+			}
+			// Walk the question name to find its type
+			var labels []string
+			i := 12
+			for i < n && buf[i] != 0 {
+				labels = append(labels, string(buf[i+1:i+1+int(buf[i])]))
+				i += 1 + int(buf[i])
+			}
+			question := buf[12 : i+5]
+			qtype := binary.BigEndian.Uint16(buf[i+1:])
+			select {
+			case queries <- strings.Join(labels, "."):
+			default:
+			}
+
+			resp := append([]byte{}, buf[:2]...)
+			resp = append(resp, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+			resp = append(resp, question...)
+			if qtype == 1 {
+				resp[7] = 1
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+				resp = append(resp, answer.To4()...)
+			}
+			conn.WriteTo(resp, from)
+		}
+	}()
+	return conn.LocalAddr().String(), queries
+}
+
+func TestNewTargetWithDNSServer(t *testing.T) {
+	server, queries := stubDNSServer(t, net.ParseIP("10.9.8.7"))
+	scanner, err := NewTargetWithDNSServer("intranet.example", 1, server)
+	if err != nil {
+		t.Fatalf("NewTargetWithDNSServer failed: %s", err)
+	}
+	if len(scanner.IPs) != 1 || scanner.IPs[0] != "10.9.8.7" {
+		t.Errorf("Expected the override's answer 10.9.8.7, got %v", scanner.IPs)
+	}
+	if scanner.DNSServer != server {
+		t.Errorf("Expected DNSServer %s, got %s", server, scanner.DNSServer)
+	}
+	select {
+	case name := <-queries:
+		if name != "intranet.example" {
+			t.Errorf("Expected a query for intranet.example, got %s", name)
+		}
+	default:
+		t.Errorf("Expected the query to reach the override server")
+	}
+}
+
+func TestNewDNSResolver_Validates(t *testing.T) {
+	for _, server := range []string{"10.0.0.53", ":53", "10.0.0.53:0", "10.0.0.53:dns", "10.0.0.53:70000"} {
+		if _, err := NewDNSResolver(server); err == nil {
+			t.Errorf("Expected an error for DNS server %q", server)
+		}
+	}
+	for _, server := range []string{"10.0.0.53:53", "[::1]:5353", "dns.internal:53"} {
+		if _, err := NewDNSResolver(server); err != nil {
+			t.Errorf("Expected DNS server %q to be valid, got %s", server, err)
+		}
+	}
+}
+
+func TestRun_InvalidDNSServer(t *testing.T) {
+	if code := run([]string{"-quiet", "-dns", "nonsense", "127.0.0.1"}, nil); code != ExitError {
+		t.Errorf("Expected exit code %d for an invalid DNS server, got %d", ExitError, code)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"det/service"
+)
+
+// Scan event types.
+const (
+	EventPort = "port"
+	EventICMP = "icmp"
+)
+
+// ScanEvent is one result reported while a scan runs.
+//
+// Fields:
+// - Type: EventPort for a TCP or UDP port, EventICMP for a reachability result.
+// - Time: When the result was reported.
+// - Port: The reported port, for EventPort.
+// - ICMP: The reachability result, for EventICMP.
+//
+// Example:
+//
+//	scanner.OnResult = func(event ScanEvent) {
+//	    if event.Type == EventPort {
+//	        fmt.Println(event.Port.Port, event.Port.State)
+//	    }
+//	}
+type ScanEvent struct {
+	Type string                  `json:"type"`
+	Time time.Time               `json:"time"`
+	Port *service.ServiceVersion `json:"port,omitempty"`
+	ICMP *ICMPResult             `json:"icmp,omitempty"`
+}
+
+// sendResult sends a reported port to its result channel and emits it as an
+// event, appending it to the incremental output first if one is open. In
+// streaming mode the port is not sent to the channel.
+//
+// Parameters:
+// - openPorts: The result channel.
+// - svc: The reported port.
+func (t *PortScanner) sendResult(openPorts chan service.ServiceVersion, svc service.ServiceVersion) {
+	if t.incremental != nil {
+		t.incremental.write(svc)
+	}
+	if !t.Streaming {
+		openPorts <- svc
+	}
+	t.emit(ScanEvent{Type: EventPort, Port: &svc})
+}
+
+// emit calls OnResult, if set, with the event stamped with the current time.
+//
+// Parameters:
+// - event: The event to emit.
+func (t *PortScanner) emit(event ScanEvent) {
+	if t.OnResult == nil {
+		return
+	}
+	event.Time = time.Now()
+	t.OnResult(event)
+}
+
+// NDJSONWriter writes scan events as newline-delimited JSON, one object per
+// line, flushing after each so a consumer such as jq sees results as they
+// arrive. It is safe for concurrent use.
+//
+// Example:
+//
+//	scanner.OnResult = NewNDJSONWriter(os.Stdout).Write
+type NDJSONWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+	err error
+}
+
+// NewNDJSONWriter creates an NDJSONWriter.
+//
+// Parameters:
+// - w: Where to write. If it is buffered, e.g. a bufio.Writer, its Flush method is called after every line.
+//
+// Returns:
+// - A pointer to the new NDJSONWriter.
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: w, enc: json.NewEncoder(w)}
+}
+
+// Write writes one event as a line. After the first error, events are dropped.
+//
+// Parameters:
+// - event: The event to write.
+func (n *NDJSONWriter) Write(event ScanEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.err != nil {
+		return
+	}
+	if n.err = n.enc.Encode(event); n.err != nil {
+		return
+	}
+	if f, ok := n.w.(interface{ Flush() error }); ok {
+		n.err = f.Flush()
+	}
+}
+
+// Err returns the first error writing an event, if any.
+//
+// Returns:
+// - The write error, or nil.
+func (n *NDJSONWriter) Err() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.err
+}
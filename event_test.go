@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNDJSONWriter_Stream(t *testing.T) {
+	var out bytes.Buffer
+	buffered := bufio.NewWriter(&out)
+	dialer := &mockDialer{accept: func(address string) bool { return address == "127.0.0.1:40001" }}
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{40001, 40002}
+	scanner.Dial = dialer.Dial
+	scanner.Quiet = true
+	scanner.ReportStates = []string{"Open", "Closed"}
+	writer := NewNDJSONWriter(buffered)
+	var mu sync.Mutex
+	lines := 0
+	scanner.OnResult = func(event ScanEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		writer.Write(event)
+		// Every event is flushed as soon as it is written
+		if buffered.Buffered() != 0 {
+			t.Errorf("Expected the event to be flushed, %d bytes buffered", buffered.Buffered())
+		}
+		lines++
+	}
+
+	scanner.Scan()
+
+	if err := writer.Err(); err != nil {
+		t.Fatalf("Writing events failed: %s", err)
+	}
+	seen := map[string]bool{}
+	scanLines := bufio.NewScanner(strings.NewReader(out.String()))
+	count := 0
+	for scanLines.Scan() {
+		count++
+		var event map[string]interface{}
+		if err := json.Unmarshal(scanLines.Bytes(), &event); err != nil {
+			t.Fatalf("Line %d is not valid JSON: %s\n%s", count, err, scanLines.Text())
+		}
+		if _, ok := event["time"]; !ok {
+			t.Errorf("Line %d: Expected a time field, got %s", count, scanLines.Text())
+		}
+		switch event["type"] {
+		case EventPort:
+			port := event["port"].(map[string]interface{})
+			seen[port["protocol"].(string)+"/"+port["state"].(string)] = true
+		case EventICMP:
+			if _, ok := event["icmp"].(map[string]interface{})["state"]; !ok {
+				t.Errorf("Line %d: Expected an ICMP state, got %s", count, scanLines.Text())
+			}
+			seen["ICMP"] = true
+		default:
+			t.Errorf("Line %d: Unexpected event type %v", count, event["type"])
+		}
+	}
+	if count != lines || count != 5 {
+		t.Errorf("Expected 5 lines, one per event, got %d lines for %d events", count, lines)
+	}
+	for _, expected := range []string{"TCP/Open", "TCP/Closed", "UDP/Closed", "ICMP"} {
+		if !seen[expected] {
+			t.Errorf("Expected a %s event, got %v", expected, seen)
+		}
+	}
+}
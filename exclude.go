@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net"
+	"strings"
+)
+
+// filterExcluded removes ExcludeIPs and ExcludePorts, and IPs outside the
+// selected AddressFamily, from the scan targets. Excluded items are recorded
+// so the report can list them as skipped.
+//
+// Returns:
+// - The IP addresses left to scan.
+// - The ports left to scan.
+//
+// Example:
+//
+//	scanner.ExcludePorts = []int{502}
+//	scanner.ExcludeIPs = []string{"10.0.0.0/30"}
+//	ips, ports := scanner.filterExcluded()
+func (t *PortScanner) filterExcluded() ([]string, []int) {
+	t.skippedIPs = nil
+	t.skippedPorts = nil
+
+	excludedPorts := make(map[int]bool, len(t.ExcludePorts))
+	for _, port := range t.ExcludePorts {
+		excludedPorts[port] = true
+	}
+
+	ips := make([]string, 0, len(t.IPs))
+	for _, ip := range FilterFamily(t.IPs, t.AddressFamily) {
+		if ipExcluded(ip, t.ExcludeIPs) {
+			t.skippedIPs = append(t.skippedIPs, ip)
+			continue
+		}
+		ips = append(ips, ip)
+	}
+
+	ports := make([]int, 0, len(t.Ports))
+	for _, port := range t.Ports {
+		if excludedPorts[port] {
+			t.skippedPorts = append(t.skippedPorts, port)
+			continue
+		}
+		ports = append(ports, port)
+	}
+
+	return ips, ports
+}
+
+// ipExcluded reports whether ip matches one of the exclusions. An exclusion
+// is either a CIDR range, an IP address, or a literal host name.
+//
+// Parameters:
+// - ip: The IP address to check.
+// - exclusions: The IP addresses or CIDR ranges to match against.
+//
+// Returns:
+// - true if ip is excluded, otherwise false.
+func ipExcluded(ip string, exclusions []string) bool {
+	addr := net.ParseIP(ip)
+	for _, exclusion := range exclusions {
+		if strings.Contains(exclusion, "/") {
+			_, network, err := net.ParseCIDR(exclusion)
+			if err == nil && addr != nil && network.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(exclusion); other != nil && addr != nil {
+			if other.Equal(addr) {
+				return true
+			}
+			continue
+		}
+		if exclusion == ip {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIPExcluded(t *testing.T) {
+	exclusions := []string{"10.0.0.0/30", "192.168.1.5"}
+	tests := []struct {
+		ip       string
+		excluded bool
+	}{
+		{"10.0.0.1", true},
+		{"10.0.0.3", true},
+		{"10.0.0.4", false},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+	}
+	for _, test := range tests {
+		if got := ipExcluded(test.ip, exclusions); got != test.excluded {
+			t.Errorf("IP %s: Expected excluded=%v, got %v", test.ip, test.excluded, got)
+		}
+	}
+}
+
+func TestScan_Exclusions(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.IPs = []string{"127.0.0.1", "10.0.0.1"}
+	scanner.Ports = []int{40001, 502, 40002}
+	scanner.ExcludePorts = []int{502}
+	scanner.ExcludeIPs = []string{"10.0.0.0/8"}
+	scanner.Dial = dialer.Dial
+
+	scanner.Scan()
+
+	for _, addr := range dialer.addrs {
+		if strings.HasSuffix(addr, ":502") || strings.Contains(addr, "10.0.0.1") {
+			t.Errorf("Excluded endpoint was probed: %s", addr)
+		}
+	}
+
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, fileName); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	for _, expected := range []string{"IP 10.0.0.1 is skipped", "Port 502 is skipped"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in report, got:\n%s", expected, data)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "net"
+
+// AddressFamily selects which resolved addresses are scanned.
+type AddressFamily int
+
+const (
+	// FamilyAny scans both IPv4 and IPv6 addresses.
+	FamilyAny AddressFamily = iota
+	// FamilyIPv4 scans only IPv4 addresses.
+	FamilyIPv4
+	// FamilyIPv6 scans only IPv6 addresses.
+	FamilyIPv6
+)
+
+// String returns the name of the address family.
+func (f AddressFamily) String() string {
+	switch f {
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	}
+	return "Any"
+}
+
+// FilterFamily returns the addresses of ips that belong to the family.
+// Entries that are not IP literals are kept, since their family is unknown.
+//
+// Parameters:
+// - ips: The addresses to filter.
+// - family: The address family to keep.
+//
+// Returns:
+// - The matching addresses, in their original order.
+//
+// Example:
+//
+//	v4 := FilterFamily([]string{"192.0.2.1", "2001:db8::1"}, FamilyIPv4)
+//	// []string{"192.0.2.1"}
+func FilterFamily(ips []string, family AddressFamily) []string {
+	if family == FamilyAny {
+		return ips
+	}
+	filtered := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addr := net.ParseIP(ip)
+		isV4 := addr != nil && addr.To4() != nil
+		switch {
+		case addr == nil,
+			family == FamilyIPv4 && isV4,
+			family == FamilyIPv6 && !isV4:
+			filtered = append(filtered, ip)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterFamily(t *testing.T) {
+	resolver := &stubResolver{hosts: map[string][]string{
+		"dual.example": {"192.0.2.1", "2001:db8::1", "192.0.2.2", "2001:db8::2"},
+	}}
+	scanner, err := NewTargetWithResolver("dual.example", 1, resolver)
+	if err != nil {
+		t.Fatalf("NewTargetWithResolver failed: %s", err)
+	}
+
+	tests := []struct {
+		family   AddressFamily
+		expected []string
+	}{
+		{FamilyAny, []string{"192.0.2.1", "2001:db8::1", "192.0.2.2", "2001:db8::2"}},
+		{FamilyIPv4, []string{"192.0.2.1", "192.0.2.2"}},
+		{FamilyIPv6, []string{"2001:db8::1", "2001:db8::2"}},
+	}
+	for _, test := range tests {
+		scanner.AddressFamily = test.family
+		ips, _ := scanner.filterExcluded()
+		if !reflect.DeepEqual(ips, test.expected) {
+			t.Errorf("Family %s: Expected %v, got %v", test.family, test.expected, ips)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "time"
+
+// FastConnect mode trades the default "a hundred workers, ten second timeout"
+// connect scan for thousands of concurrent connects with a short timeout.
+// Each in-flight connect is a goroutine parked in Go's runtime netpoller
+// (epoll/kqueue), so the cost is one file descriptor per connect rather than
+// one thread.
+//
+// Every concurrent connect holds a file descriptor, so the batch size is
+// bounded by the process limit (`ulimit -n`). On start the soft limit is raised
+// towards the hard limit where the OS allows it, and the batch is clamped to
+// the resulting limit minus fdHeadroom descriptors kept free for the rest of
+// the process. Raise the hard limit (e.g. `ulimit -Hn 65535`) for larger batches.
+const (
+	// DefaultFastConnectBatch is the number of concurrent connects in FastConnect mode.
+	DefaultFastConnectBatch = 2048
+	// DefaultFastConnectTimeout is the TCP connect timeout in FastConnect mode.
+	DefaultFastConnectTimeout = time.Second
+
+	// fdHeadroom is the number of file descriptors kept free for the process.
+	fdHeadroom = 64
+)
+
+// tcpWorkerCount returns the number of TCP workers Scan should start.
+//
+// Parameters:
+// - numWorkers: The number of workers of the scan (see autoWorkers).
+//
+// Returns:
+// - numWorkers normally, or the FastConnect batch size clamped to the
+// file descriptor limit when FastConnect is enabled.
+func (t *PortScanner) tcpWorkerCount(numWorkers int) int {
+	if !t.FastConnect {
+		return numWorkers
+	}
+	batch := t.FastConnectBatch
+	if batch <= 0 {
+		batch = DefaultFastConnectBatch
+	}
+	if limit := fileLimit(); limit > 0 {
+		available := int(limit) - fdHeadroom
+		if available < batch {
+			batch = available
+		}
+	}
+	if batch < 1 {
+		batch = 1
+	}
+	return batch
+}
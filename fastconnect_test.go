@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestTCPWorkerCount(t *testing.T) {
+	scanner := &PortScanner{NumWorkers: 10}
+	if got := scanner.tcpWorkerCount(scanner.NumWorkers); got != 10 {
+		t.Errorf("Expected 10 workers without FastConnect, got %d", got)
+	}
+
+	scanner.FastConnect = true
+	scanner.FastConnectBatch = 500
+	got := scanner.tcpWorkerCount(scanner.NumWorkers)
+	if got < 1 || got > 500 {
+		t.Errorf("Expected FastConnect batch within [1, 500], got %d", got)
+	}
+	if limit := fileLimit(); limit > 0 && uint64(got) > limit {
+		t.Errorf("Expected batch %d to respect the file limit %d", got, limit)
+	}
+}
+
+func TestTCPTimeout_FastConnect(t *testing.T) {
+	scanner := &PortScanner{Timeout: 10 * time.Second, FastConnect: true}
+	if got := scanner.tcpTimeout(); got != DefaultFastConnectTimeout {
+		t.Errorf("Expected FastConnect timeout %v, got %v", DefaultFastConnectTimeout, got)
+	}
+}
+
+// benchmarkWorkers scans 1000 endpoints with a dialer that takes 1ms per connect.
+func benchmarkWorkers(b *testing.B, scanner *PortScanner) {
+	dialer := &mockDialer{delay: time.Millisecond}
+	scanner.Dial = dialer.Dial
+	endpoints := Interleave([]string{"127.0.0.1"}, make([]int, 1000))
+
+	for i := 0; i < b.N; i++ {
+		workers := scanner.tcpWorkerCount(scanner.NumWorkers)
+		queue := make(chan Endpoint, len(endpoints))
+		for _, endpoint := range endpoints {
+			queue <- endpoint
+		}
+		close(queue)
+		openPorts := make(chan service.ServiceVersion, len(endpoints))
+		done := make(chan bool, workers)
+		for w := 0; w < workers; w++ {
+			go scanner.WorkerTCP(context.Background(), queue, openPorts, done)
+		}
+		for w := 0; w < workers; w++ {
+			<-done
+		}
+	}
+}
+
+func BenchmarkScanTCP_Workers(b *testing.B) {
+	benchmarkWorkers(b, &PortScanner{NumWorkers: 10, Quiet: true})
+}
+
+func BenchmarkScanTCP_FastConnect(b *testing.B) {
+	benchmarkWorkers(b, &PortScanner{NumWorkers: 10, FastConnect: true, FastConnectBatch: 500, Quiet: true})
+}
@@ -0,0 +1,96 @@
+package main
+
+import "sync"
+
+// DefaultFileLimit is the open-file budget assumed where the limit cannot be
+// queried, e.g. on Windows, which has no RLIMIT_NOFILE.
+const DefaultFileLimit = 8192
+
+// fileLimit returns the soft open-file limit after raising it as far as the
+// OS allows, 0 if unknown. It is a variable so tests can simulate a low
+// limit.
+var fileLimit = cachedFileLimit
+
+// raisedFileLimit is the limit in effect once raiseFileLimit ran.
+var (
+	raisedFileLimit     uint64
+	raisedFileLimitOnce sync.Once
+)
+
+// cachedFileLimit raises the open-file limit on the first call only, rather
+// than on every scan, and returns the limit then in effect.
+func cachedFileLimit() uint64 {
+	raisedFileLimitOnce.Do(func() { raisedFileLimit = raiseFileLimit() })
+	return raisedFileLimit
+}
+
+// clampToFileLimit keeps the workers from holding more file descriptors than
+// the process may open: every TCP and UDP worker holds one while probing, and
+// fdHeadroom more are kept free for the rest of the process. Running out
+// would make dials fail with EMFILE, misreported as closed ports. When the
+// workers do not fit, both pools are shrunk in proportion and a warning is
+// logged.
+//
+// Parameters:
+// - tcpWorkers: The number of TCP workers to start, e.g. from tcpWorkerCount, 0 if TCP is not scanned.
+// - udpWorkers: The number of UDP workers to start, 0 if UDP is not scanned.
+//
+// Returns:
+// - The number of TCP workers to start.
+// - The number of UDP workers to start.
+func (t *PortScanner) clampToFileLimit(tcpWorkers, udpWorkers int) (int, int) {
+	limit := int(fileLimit())
+	if limit <= 0 {
+		limit = DefaultFileLimit
+	}
+	available := limit - fdHeadroom
+	if available < 2 {
+		available = 2
+	}
+	if tcpWorkers+udpWorkers <= available {
+		return tcpWorkers, udpWorkers
+	}
+
+	udp := available * udpWorkers / (tcpWorkers + udpWorkers)
+	if udp < 1 && udpWorkers > 0 {
+		udp = 1
+	}
+	tcp := available - udp
+	if tcp < 1 && tcpWorkers > 0 {
+		tcp = 1
+	}
+	t.logf("Warning: %d TCP and %d UDP workers exceed the open file limit of %d, using %d and %d; raise it with ulimit -n for more\n",
+		tcpWorkers, udpWorkers, limit, tcp, udp)
+	return tcp, udp
+}
+
+// clampedWorkers returns the number of workers a scan runs with: numWorkers,
+// lowered to a pool clampToFileLimit shrank below it.
+//
+// Parameters:
+// - numWorkers: The configured NumWorkers.
+// - tcpWorkers, udpWorkers: The pools before clampToFileLimit.
+// - clampedTCP, clampedUDP: The pools it returned.
+//
+// Returns:
+// - The number of workers, recorded in the scan's meta.
+func clampedWorkers(numWorkers, tcpWorkers, udpWorkers, clampedTCP, clampedUDP int) int {
+	workers := numWorkers
+	if clampedUDP < udpWorkers && clampedUDP < workers {
+		workers = clampedUDP
+	}
+	if clampedTCP < tcpWorkers && clampedTCP < workers {
+		workers = clampedTCP
+	}
+	return workers
+}
+
+// scanWorkers returns the number of workers of the current or last scan,
+// or NumWorkers before the first one. The caller must hold reportMu if the
+// scan may be running.
+func (t *PortScanner) scanWorkers() int {
+	if t.workers > 0 {
+		return t.workers
+	}
+	return t.NumWorkers
+}
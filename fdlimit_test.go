@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+// lowFileLimit makes the open-file limit look like limit until the test ends.
+func lowFileLimit(t *testing.T, limit uint64) {
+	fileLimit = func() uint64 { return limit }
+	t.Cleanup(func() { fileLimit = cachedFileLimit })
+}
+
+func TestClampToFileLimit(t *testing.T) {
+	lowFileLimit(t, fdHeadroom+40)
+	tests := []struct {
+		tcp, udp  int
+		expectTCP int
+		expectUDP int
+	}{
+		{10, 10, 10, 10},
+		{100, 100, 20, 20},
+		{300, 100, 30, 10},
+		{100, 0, 40, 0},
+		{0, 100, 0, 40},
+	}
+	for _, tt := range tests {
+		scanner := &PortScanner{Quiet: true}
+		tcp, udp := scanner.clampToFileLimit(tt.tcp, tt.udp)
+		if tcp != tt.expectTCP || udp != tt.expectUDP {
+			t.Errorf("%d TCP and %d UDP workers: Expected %d and %d, got %d and %d", tt.tcp, tt.udp, tt.expectTCP, tt.expectUDP, tcp, udp)
+		}
+	}
+
+	// Without a known limit the default budget applies
+	lowFileLimit(t, 0)
+	scanner := &PortScanner{}
+	if tcp, udp := scanner.clampToFileLimit(DefaultFileLimit, DefaultFileLimit); tcp+udp > DefaultFileLimit-fdHeadroom {
+		t.Errorf("Expected the workers to fit DefaultFileLimit, got %d and %d", tcp, udp)
+	}
+}
+
+func TestScan_ConcurrencyClampedToFileLimit(t *testing.T) {
+	lowFileLimit(t, fdHeadroom+8)
+	scanner, err := NewTarget("127.0.0.1", 100)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = allPorts()[:200]
+	var inFlight, peak int64
+	counting := ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		now := atomic.AddInt64(&inFlight, 1)
+		for {
+			old := atomic.LoadInt64(&peak)
+			if now <= old || atomic.CompareAndSwapInt64(&peak, old, now) {
+				break
+			}
+		}
+		atomic.AddInt64(&inFlight, -1)
+		return StateClosed, nil
+	})
+	scanner.TCPProber = counting
+	scanner.UDPProber = counting
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	scanner.Quiet = true
+	scanner.Scan()
+
+	if scanner.NumWorkers != 100 {
+		t.Errorf("Expected NumWorkers to stay as configured, got %d", scanner.NumWorkers)
+	}
+	if workers := scanner.Report().Meta.Workers; workers > 8 {
+		t.Errorf("Expected the meta to record the workers clamped to the limit, got %d", workers)
+	}
+	if peak > 8 {
+		t.Errorf("Expected at most 8 probes in flight, got %d", peak)
+	}
+	if completed := scanner.Completed(); completed != 400 {
+		t.Errorf("Expected the clamped scan to complete 400 probes, got %d", completed)
+	}
+}
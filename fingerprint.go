@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strconv"
+	"time"
+)
+
+// DefaultFingerprintTimeout bounds each fingerprinting connection, from the
+// connect to the banner or TLS certificate.
+const DefaultFingerprintTimeout = 2 * time.Second
+
+// errNoFingerprint is returned when a connection yields neither a banner
+// nor a certificate.
+var errNoFingerprint = errors.New("no banner or certificate")
+
+// FingerprintConn identifies the backend behind a connection: the SHA-256
+// of the leaf certificate when useTLS is set, otherwise the greeting the
+// service sends on connect, e.g. an SSH or SMTP banner.
+//
+// Parameters:
+// - conn: The connection to the service, closed by the caller.
+// - useTLS: Whether to perform a TLS handshake.
+// - timeout: How long to wait for the handshake or the greeting.
+//
+// Returns:
+// - The fingerprint, "sha256:<hex>" for certificates or "banner:<text>" for greetings.
+// - An error if the service offered neither.
+//
+// Example:
+//
+//	fingerprint, err := FingerprintConn(conn, true, 2*time.Second)
+func FingerprintConn(conn net.Conn, useTLS bool, timeout time.Duration) (string, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if useTLS {
+		client := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+		if err := client.Handshake(); err != nil {
+			return "", err
+		}
+		certs := client.ConnectionState().PeerCertificates
+		if len(certs) == 0 {
+			return "", errNoFingerprint
+		}
+		sum := sha256.Sum256(certs[0].Raw)
+		return "sha256:" + hex.EncodeToString(sum[:]), nil
+	}
+	banner := readBanner(conn, timeout)
+	if banner == "" {
+		return "", errNoFingerprint
+	}
+	return "banner:" + banner, nil
+}
+
+// fingerprintBackends connects to an open port Fingerprint times and
+// collects the distinct fingerprints of the backends that answered. A load
+// balancer rotating several backends shows up as more than one.
+//
+// Parameters:
+// - ip: The IP address of the open port.
+// - port: The open port.
+//
+// Returns:
+// - The distinct fingerprints, in the order they were first seen.
+func (t *PortScanner) fingerprintBackends(ip string, port int) []string {
+	if t.Fingerprint <= 0 {
+		return nil
+	}
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	useTLS := t.HTTPPorts[port]
+	dial := t.dial()
+	seen := map[string]bool{}
+	var fingerprints []string
+	for i := 0; i < t.Fingerprint; i++ {
+		conn, err := dial("tcp", address, DefaultFingerprintTimeout)
+		if err != nil {
+			continue
+		}
+		fingerprint, err := FingerprintConn(conn, useTLS, DefaultFingerprintTimeout)
+		conn.Close()
+		if err != nil || seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	return fingerprints
+}
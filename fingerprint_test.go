@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rotatingDialer connects to backends that greet with each banner in turn,
+// like a load balancer rotating its backends.
+type rotatingDialer struct {
+	mu      sync.Mutex
+	banners []string
+	next    int
+}
+
+func (d *rotatingDialer) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	d.mu.Lock()
+	banner := d.banners[d.next%len(d.banners)]
+	d.next++
+	d.mu.Unlock()
+	client, server := net.Pipe()
+	go func() {
+		server.Write([]byte(banner + "\r\n"))
+		server.Close()
+	}()
+	return client, nil
+}
+
+func TestFingerprint_MultipleBackends(t *testing.T) {
+	tests := []struct {
+		name     string
+		banners  []string
+		backends []string
+	}{
+		{"rotating backends", []string{"SSH-2.0-OpenSSH_8.9", "SSH-2.0-OpenSSH_9.6"}, []string{"banner:SSH-2.0-OpenSSH_9.6", "banner:SSH-2.0-OpenSSH_8.9"}},
+		{"single backend", []string{"SSH-2.0-OpenSSH_9.6"}, []string{"banner:SSH-2.0-OpenSSH_9.6"}},
+	}
+	for _, test := range tests {
+		scanner := &PortScanner{
+			Dial:        (&rotatingDialer{banners: test.banners}).Dial,
+			Fingerprint: 4,
+		}
+		runTCPWorker(scanner, "127.0.0.1", []int{22})
+		result := <-scanner.OpenPorts
+		if !reflect.DeepEqual(result.Backends, test.backends) {
+			t.Errorf("%s: Expected backends %v, got %v", test.name, test.backends, result.Backends)
+		}
+		if expected := len(test.backends) > 1; result.MultipleBackends != expected {
+			t.Errorf("%s: Expected MultipleBackends %v, got %v", test.name, expected, result.MultipleBackends)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GeoInfo annotates an IP address with its network owner and location.
+//
+// Fields:
+// - ASN: The autonomous system number announcing the address.
+// - Org: The organization owning the autonomous system.
+// - Country: The ISO 3166-1 alpha-2 country code of the address.
+//
+// Example:
+//
+//	info := GeoInfo{ASN: 13335, Org: "Cloudflare, Inc.", Country: "US"}
+type GeoInfo struct {
+	ASN     int    `json:"asn,omitempty"`
+	Org     string `json:"org,omitempty"`
+	Country string `json:"country,omitempty"`
+}
+
+// String formats the annotation as a report line.
+func (g GeoInfo) String() string {
+	return fmt.Sprintf("AS%d %s, %s", g.ASN, g.Org, g.Country)
+}
+
+// GeoLookupFunc looks up the GeoInfo of an IP address, e.g. in a local
+// MaxMind database supplied by the user.
+type GeoLookupFunc func(ip string) (GeoInfo, error)
+
+// annotateIPs looks up every IP with GeoLookup and keeps the annotations for
+// the report. IPs whose lookup fails are left unannotated.
+//
+// Parameters:
+// - ips: The IP addresses to annotate.
+func (t *PortScanner) annotateIPs(ips []string) {
+	if t.GeoLookup == nil {
+		return
+	}
+	geo := make(map[string]GeoInfo, len(ips))
+	for _, ip := range ips {
+		info, err := t.GeoLookup(ip)
+		if err != nil {
+			t.logf("Error looking up %s: %s\n", ip, err)
+			t.addError("geo", ip, err)
+			continue
+		}
+		geo[ip] = info
+	}
+	t.reportMu.Lock()
+	t.collected.Geo = geo
+	t.reportMu.Unlock()
+}
+
+// sortedGeoIPs returns the annotated IPs of a report in order.
+func sortedGeoIPs(geo map[string]GeoInfo) []string {
+	ips := make([]string, 0, len(geo))
+	for ip := range geo {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+	return ips
+}
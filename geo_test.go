@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGeoLookup_Report(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = nil
+	scanner.Quiet = true
+	scanner.GeoLookup = func(ip string) (GeoInfo, error) {
+		if ip != "127.0.0.1" {
+			return GeoInfo{}, errors.New("not found")
+		}
+		return GeoInfo{ASN: 64500, Org: "Example Networks", Country: "NL"}, nil
+	}
+
+	scanner.Scan()
+
+	report := scanner.Report()
+	expected := GeoInfo{ASN: 64500, Org: "Example Networks", Country: "NL"}
+	if report.Geo["127.0.0.1"] != expected {
+		t.Errorf("Expected %v for 127.0.0.1, got %v", expected, report.Geo)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, fileName); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	if !strings.Contains(string(data), "IP 127.0.0.1: AS64500 Example Networks, NL") {
+		t.Errorf("Expected the annotation in the report, got:\n%s", data)
+	}
+}
+
+func TestGeoLookup_Unset(t *testing.T) {
+	scanner := &PortScanner{}
+	scanner.annotateIPs([]string{"127.0.0.1"})
+	if report := scanner.Report(); report.Geo != nil {
+		t.Errorf("Expected no annotations without a GeoLookup, got %v", report.Geo)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// connectProxy is a local HTTP CONNECT proxy stub. It refuses tunnels to
+// forbidden ports with 403 and answers 502 when the target cannot be dialed.
+type connectProxy struct {
+	listener  net.Listener
+	forbidden map[int]bool
+
+	mu   sync.Mutex
+	auth string
+}
+
+func newConnectProxy(t *testing.T, forbidden map[int]bool) *connectProxy {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	proxy := &connectProxy{listener: listener, forbidden: forbidden}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go proxy.handle(conn)
+		}
+	}()
+	return proxy
+}
+
+func (p *connectProxy) handle(conn net.Conn) {
+	defer conn.Close()
+	req, err := http.ReadRequest(bufio.NewReader(conn))
+	if err != nil || req.Method != http.MethodConnect {
+		io.WriteString(conn, "HTTP/1.1 405 Method Not Allowed\r\n\r\n")
+		return
+	}
+	p.mu.Lock()
+	p.auth = req.Header.Get("Proxy-Authorization")
+	p.mu.Unlock()
+	_, portText, _ := net.SplitHostPort(req.Host)
+	port, _ := strconv.Atoi(portText)
+	if p.forbidden[port] {
+		io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\n\r\n")
+		return
+	}
+	target, err := net.DialTimeout("tcp", req.Host, time.Second)
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+		return
+	}
+	defer target.Close()
+	io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+func TestProxy_HTTPConnect(t *testing.T) {
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer open.Close()
+	openPort := open.Addr().(*net.TCPAddr).Port
+	closed, _ := net.Listen("tcp", "127.0.0.1:0")
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+	forbiddenPort := 25
+
+	proxy := newConnectProxy(t, map[int]bool{forbiddenPort: true})
+	scanner := &PortScanner{
+		Proxy:   "http://" + proxy.listener.Addr().String(),
+		Timeout: time.Second,
+	}
+
+	tests := []struct {
+		port   int
+		state  string
+		reason string
+	}{
+		{openPort, "Open", ReasonConnected},
+		{closedPort, "Closed", "502 Bad Gateway"},
+		{forbiddenPort, "Error", "proxy refused CONNECT to 127.0.0.1:25: 403 Forbidden"},
+	}
+	for _, test := range tests {
+		state, reason, _ := scanner.connectTCP(context.Background(), "127.0.0.1", test.port)
+		if state != test.state {
+			t.Errorf("Expected port %d to be %s, got %s", test.port, test.state, state)
+		}
+		if !strings.Contains(reason, test.reason) {
+			t.Errorf("Expected the reason for port %d to contain %q, got %q", test.port, test.reason, reason)
+		}
+	}
+}
+
+func TestDialHTTPConnect_Auth(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer target.Close()
+	proxy := newConnectProxy(t, nil)
+	dial, err := DialHTTPConnect(net.DialTimeout, "http://user:secret@"+proxy.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("DialHTTPConnect failed: %s", err)
+	}
+	conn, err := dial("tcp", target.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatalf("Dial through the proxy failed: %s", err)
+	}
+	conn.Close()
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if expected := "Basic dXNlcjpzZWNyZXQ="; proxy.auth != expected {
+		t.Errorf("Expected Proxy-Authorization %q, got %q", expected, proxy.auth)
+	}
+}
+
+func TestDialHTTPConnect_InvalidURL(t *testing.T) {
+	for _, proxy := range []string{"socks5://127.0.0.1:1080", "127.0.0.1:3128", "http://"} {
+		if _, err := DialHTTPConnect(net.DialTimeout, proxy); err == nil {
+			t.Errorf("Expected an error for proxy %q", proxy)
+		}
+	}
+}
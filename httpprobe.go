@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"det/service"
+)
+
+// DefaultHTTPPorts maps ports likely to serve HTTP to whether they use TLS.
+var DefaultHTTPPorts = map[int]bool{
+	80:   false,
+	443:  true,
+	8000: false,
+	8008: false,
+	8080: false,
+	8443: true,
+	8888: false,
+}
+
+// httpBodyLimit is the number of body bytes read when looking for a title.
+const httpBodyLimit = 64 * 1024
+
+// titlePattern matches the contents of an HTML <title> element.
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// ProbeHTTP sends a GET request to a web server and captures its status,
+// headers and page title. The connection always goes to ip:port, whatever
+// the request's host, and TLS certificates are not verified.
+//
+// Parameters:
+// - ip: The IP address of the server.
+// - port: The port of the server.
+// - useTLS: Whether to speak HTTPS.
+// - timeout: The timeout for the whole request.
+//
+// Returns:
+// - The captured HTTPInfo.
+// - An error if the server did not answer with a valid HTTP response.
+//
+// Example:
+//
+//	info, err := ProbeHTTP("192.168.1.1", 8080, false, 5*time.Second)
+func ProbeHTTP(ip string, port int, useTLS bool, timeout time.Duration) (*service.HTTPInfo, error) {
+	info, _, err := probeHTTPHost(net.DialTimeout, ip, port, useTLS, "", timeout)
+	return info, err
+}
+
+// probeHTTPHost is ProbeHTTP with the request's Host header, and over TLS
+// its server name, set to host, so a name-based virtual host answers. The
+// connection is opened with dial, so a scanner's probe goes through its
+// proxy, source address and connection limit.
+//
+// Parameters:
+// - dial: Opens the connection to the server.
+// - ip: The IP address of the server.
+// - port: The port of the server.
+// - useTLS: Whether to speak HTTPS.
+// - host: The Host header, "" for the server's address.
+// - timeout: The timeout for the whole request.
+//
+// Returns:
+// - The captured HTTPInfo.
+// - The start of the response body, up to httpBodyLimit bytes.
+// - An error if the server did not answer with a valid HTTP response.
+func probeHTTPHost(dial DialFunc, ip string, port int, useTLS bool, host string, timeout time.Duration) (*service.HTTPInfo, []byte, error) {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	if host != "" {
+		tlsConfig.ServerName = host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			tlsConfig.ServerName = name
+		}
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, network, _ string) (net.Conn, error) {
+				return dial(network, address, timeout)
+			},
+			TLSClientConfig:   tlsConfig,
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s://%s/", scheme, address), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if host != "" {
+		req.Host = host
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, httpBodyLimit))
+	info := &service.HTTPInfo{
+		TLS:        useTLS,
+		StatusCode: resp.StatusCode,
+		Server:     resp.Header.Get("Server"),
+		Headers:    resp.Header,
+	}
+	if match := titlePattern.FindSubmatch(body); match != nil {
+		info.Title = strings.TrimSpace(html.UnescapeString(string(match[1])))
+	}
+	return info, body, nil
+}
+
+// probeHTTP probes an open port over HTTP if it is one of the scanner's HTTP ports.
+//
+// Parameters:
+// - ip: The IP address of the open port.
+// - port: The open port.
+//
+// Returns:
+// - The captured HTTPInfo, or nil if the port is not probed or does not speak HTTP.
+func (t *PortScanner) probeHTTP(ip string, port int) *service.HTTPInfo {
+	if !t.HTTPProbe {
+		return nil
+	}
+	useTLS, ok := t.HTTPPorts[port]
+	if !ok {
+		return nil
+	}
+	info, body, err := probeHTTPHost(t.dial(), ip, port, useTLS, "", t.tcpTimeout())
+	if err != nil {
+		return nil
+	}
+	t.probeVHosts(ip, port, useTLS, info, body)
+	return info
+}
+
+// probeVHosts repeats the HTTP probe of a port with each of the scanner's
+// VHosts as the Host header and records in info.VHosts those that answer
+// differently than the default response: another status, redirect
+// location, title or body. A page that changes on every request, e.g. with
+// a timestamp, thus makes every vhost look distinct.
+//
+// Parameters:
+// - ip: The IP address of the web server.
+// - port: Its port.
+// - useTLS: Whether it speaks HTTPS.
+// - info: The default response, which gets the distinct vhosts.
+// - body: The body of the default response.
+func (t *PortScanner) probeVHosts(ip string, port int, useTLS bool, info *service.HTTPInfo, body []byte) {
+	dial := t.dial()
+	for _, host := range t.VHosts {
+		vhost, vhostBody, err := probeHTTPHost(dial, ip, port, useTLS, host, t.tcpTimeout())
+		if err != nil || sameHTTPResponse(info, body, vhost, vhostBody) {
+			continue
+		}
+		if info.VHosts == nil {
+			info.VHosts = make(map[string]*service.HTTPInfo)
+		}
+		info.VHosts[host] = vhost
+	}
+}
+
+// sameHTTPResponse reports whether two HTTP responses serve the same page.
+func sameHTTPResponse(a *service.HTTPInfo, aBody []byte, b *service.HTTPInfo, bBody []byte) bool {
+	return a.StatusCode == b.StatusCode &&
+		http.Header(a.Headers).Get("Location") == http.Header(b.Headers).Get("Location") &&
+		a.Title == b.Title &&
+		bytes.Equal(aBody, bBody)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func newWebServer(tls bool) *httptest.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test-server/1.0")
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("<html><head><TITLE> Tea &amp; Biscuits </TITLE></head></html>"))
+	})
+	if tls {
+		return httptest.NewTLSServer(handler)
+	}
+	return httptest.NewServer(handler)
+}
+
+func hostPort(t *testing.T, server *httptest.Server) (string, int) {
+	t.Helper()
+	addr := server.Listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port
+}
+
+func TestProbeHTTP(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		server := newWebServer(useTLS)
+		ip, port := hostPort(t, server)
+
+		info, err := ProbeHTTP(ip, port, useTLS, DefaultTCPTimeout)
+		server.Close()
+		if err != nil {
+			t.Fatalf("TLS %v: ProbeHTTP failed: %s", useTLS, err)
+		}
+		if info.StatusCode != http.StatusTeapot {
+			t.Errorf("TLS %v: Expected status %d, got %d", useTLS, http.StatusTeapot, info.StatusCode)
+		}
+		if info.Server != "test-server/1.0" {
+			t.Errorf("TLS %v: Expected Server header test-server/1.0, got %q", useTLS, info.Server)
+		}
+		if info.Title != "Tea & Biscuits" {
+			t.Errorf("TLS %v: Expected title %q, got %q", useTLS, "Tea & Biscuits", info.Title)
+		}
+		if info.TLS != useTLS {
+			t.Errorf("TLS %v: Expected TLS flag %v, got %v", useTLS, useTLS, info.TLS)
+		}
+	}
+}
+
+func TestWorkerTCP_AttachesHTTPInfo(t *testing.T) {
+	server := newWebServer(false)
+	defer server.Close()
+	ip, port := hostPort(t, server)
+
+	scanner := &PortScanner{HTTPProbe: true, HTTPPorts: map[int]bool{port: false}}
+	queue := make(chan Endpoint, 1)
+	queue <- Endpoint{IP: ip, Port: port}
+	close(queue)
+	openPorts := make(chan service.ServiceVersion, 1)
+	done := make(chan bool, 1)
+	scanner.WorkerTCP(context.Background(), queue, openPorts, done)
+
+	svc := <-openPorts
+	if svc.HTTP == nil || svc.HTTP.Title != "Tea & Biscuits" {
+		t.Errorf("Expected HTTP info for port %s, got %+v", strconv.Itoa(port), svc.HTTP)
+	}
+}
+
+func TestWorkerTCP_VHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host {
+		case "shop.example":
+			w.Write([]byte("<title>Shop</title>"))
+		case "admin.example":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("<title>Admin</title>"))
+		default:
+			w.Write([]byte("<title>Default site</title>"))
+		}
+	}))
+	defer server.Close()
+	ip, port := hostPort(t, server)
+
+	scanner := &PortScanner{
+		HTTPProbe: true,
+		HTTPPorts: map[int]bool{port: false},
+		VHosts:    []string{"shop.example", "admin.example", "unknown.example"},
+	}
+	queue := make(chan Endpoint, 1)
+	queue <- Endpoint{IP: ip, Port: port}
+	close(queue)
+	openPorts := make(chan service.ServiceVersion, 1)
+	scanner.WorkerTCP(context.Background(), queue, openPorts, make(chan bool, 1))
+
+	svc := <-openPorts
+	if svc.HTTP == nil {
+		t.Fatalf("Expected HTTP info for port %d", port)
+	}
+	if svc.HTTP.Title != "Default site" {
+		t.Errorf("Expected the default title, got %q", svc.HTTP.Title)
+	}
+	if len(svc.HTTP.VHosts) != 2 {
+		t.Errorf("Expected 2 distinct vhosts, got %v", svc.HTTP.VHosts)
+	}
+	if vhost := svc.HTTP.VHosts["shop.example"]; vhost == nil || vhost.Title != "Shop" {
+		t.Errorf("Expected the shop.example vhost with title Shop, got %+v", vhost)
+	}
+	if vhost := svc.HTTP.VHosts["admin.example"]; vhost == nil || vhost.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the admin.example vhost with status %d, got %+v", http.StatusUnauthorized, vhost)
+	}
+	if _, ok := svc.HTTP.VHosts["unknown.example"]; ok {
+		t.Errorf("Expected unknown.example, served the default site, not to be recorded")
+	}
+}
+
+func TestProbeHTTP_ThroughProxy(t *testing.T) {
+	server := newWebServer(false)
+	defer server.Close()
+	ip, port := hostPort(t, server)
+
+	// The proxy reaches the web server; a direct connection would too, so
+	// a proxy refusing the port shows which way the probe went
+	for _, refused := range []bool{false, true} {
+		proxy := newConnectProxy(t, map[int]bool{port: refused})
+		scanner := &PortScanner{
+			HTTPProbe: true,
+			HTTPPorts: map[int]bool{port: false},
+			Proxy:     "http://" + proxy.listener.Addr().String(),
+			Timeout:   time.Second,
+		}
+		info := scanner.probeHTTP(ip, port)
+		if refused && info != nil {
+			t.Errorf("Expected no HTTP info when the proxy refuses the port, got %+v", info)
+		}
+		if !refused && (info == nil || info.Title != "Tea & Biscuits") {
+			t.Errorf("Expected the HTTP info through the proxy, got %+v", info)
+		}
+	}
+}
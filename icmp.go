@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// ICMP reachability states reported by ScanICMP.
+const (
+	ICMPReachable   = "Reachable"
+	ICMPNoReply     = "Unreachable (no reply)"
+	ICMPUnreachable = "Unreachable"
+	ICMPFiltered    = "Filtered (admin prohibited)"
+)
+
+// ICMP message types used by the scanner.
+const (
+	icmpEchoReply       = 0
+	icmpDestUnreachable = 3
+	icmpEcho            = 8
+)
+
+// icmpPortUnreachable is the destination-unreachable code sent for a UDP
+// datagram to a closed port.
+const icmpPortUnreachable = 3
+
+// IP protocol numbers of the datagrams quoted in ICMP error messages.
+const (
+	protoICMP = 1
+	protoUDP  = 17
+)
+
+// Destination-unreachable codes meaning a firewall rejected the packet:
+// network and host administratively prohibited, and communication
+// administratively prohibited.
+var icmpAdminProhibited = map[int]bool{9: true, 10: true, 13: true}
+
+// ICMPMessage is a decoded ICMPv4 message.
+//
+// Fields:
+// - Type: The ICMP message type.
+// - Code: The ICMP message code.
+// - ID: The echo identifier (of the original echo for error messages).
+// - Seq: The echo sequence number (of the original echo for error messages).
+// - OriginalDst: For error messages, the destination of the packet that triggered the error.
+// - OriginalProto: For error messages, the IP protocol of the packet that triggered the error.
+// - OriginalSrcPort: For error messages about a UDP datagram, its source port.
+// - OriginalDstPort: For error messages about a UDP datagram, its destination port.
+// - Data: For echo messages, the echo payload.
+type ICMPMessage struct {
+	Type            int
+	Code            int
+	ID              int
+	Seq             int
+	OriginalDst     net.IP
+	OriginalProto   int
+	OriginalSrcPort int
+	OriginalDstPort int
+	Data            []byte
+}
+
+// ICMPResult is the outcome of pinging one IP address.
+//
+// Fields:
+// - IP: The pinged IP address.
+// - Reachable: Whether an echo reply was received.
+// - State: The reachability state, e.g. "Reachable" or "Filtered (admin prohibited)".
+// - RTT: The round-trip time of the echo reply.
+// - TTL: The TTL of the reply's IP header, 0 if unknown.
+// - OSGuess: The operating system family guessed from the TTL (see GuessOS).
+//
+// Example:
+//
+//	result := ICMPResult{IP: "192.0.2.1", Reachable: true, State: ICMPReachable, RTT: 12 * time.Millisecond, TTL: 64}
+type ICMPResult struct {
+	IP        string        `json:"ip"`
+	Reachable bool          `json:"reachable"`
+	State     string        `json:"state"`
+	RTT       time.Duration `json:"rtt"`
+	TTL       int           `json:"ttl,omitempty"`
+	OSGuess   string        `json:"os_guess,omitempty"`
+}
+
+// String formats the result as a report line.
+func (r ICMPResult) String() string {
+	s := fmt.Sprintf("IP: %s, Response: %s", r.IP, r.State)
+	if r.Reachable {
+		s += fmt.Sprintf(", RTT: %s", r.RTT)
+	}
+	if r.TTL > 0 {
+		s += fmt.Sprintf(", TTL: %d", r.TTL)
+	}
+	if r.OSGuess != "" {
+		s += fmt.Sprintf(", OS: %s", r.OSGuess)
+	}
+	return s
+}
+
+const (
+	// DefaultICMPPayloadSize is the echo payload size, matching ping's default.
+	DefaultICMPPayloadSize = 56
+	// icmpTimestampSize is the size of the send timestamp at the start of the payload.
+	icmpTimestampSize = 8
+)
+
+// EchoPayload builds an echo payload of the given size that starts with the
+// send time, so the RTT can be computed from the reply alone.
+//
+// Parameters:
+// - sent: The time the echo request is sent.
+// - size: The payload size in bytes, raised to fit the timestamp if smaller.
+//
+// Returns:
+// - The echo payload.
+func EchoPayload(sent time.Time, size int) []byte {
+	if size < icmpTimestampSize {
+		size = icmpTimestampSize
+	}
+	payload := make([]byte, size)
+	binary.BigEndian.PutUint64(payload, uint64(sent.UnixNano()))
+	for i := icmpTimestampSize; i < size; i++ {
+		payload[i] = byte(i)
+	}
+	return payload
+}
+
+// EchoRTT computes the round-trip time of an echo reply from the timestamp
+// carried in its payload.
+//
+// Parameters:
+// - data: The echo reply payload.
+// - received: The time the reply was received.
+//
+// Returns:
+// - The round-trip time.
+// - false if the payload carries no timestamp.
+func EchoRTT(data []byte, received time.Time) (time.Duration, bool) {
+	if len(data) < icmpTimestampSize {
+		return 0, false
+	}
+	sent := time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+	return received.Sub(sent), true
+}
+
+// errShortICMP is returned when a buffer is too short to hold an ICMP message.
+var errShortICMP = errors.New("icmp: message too short")
+
+// MarshalEcho builds an ICMP echo request with a valid checksum.
+//
+// Parameters:
+// - id: The echo identifier.
+// - seq: The echo sequence number.
+// - payload: The echo data.
+//
+// Returns:
+// - The encoded ICMP message.
+//
+// Example:
+//
+//	packet := MarshalEcho(os.Getpid()&0xffff, 1, []byte("ping"))
+func MarshalEcho(id, seq int, payload []byte) []byte {
+	b := make([]byte, 8+len(payload))
+	b[0] = icmpEcho
+	binary.BigEndian.PutUint16(b[4:], uint16(id))
+	binary.BigEndian.PutUint16(b[6:], uint16(seq))
+	copy(b[8:], payload)
+	binary.BigEndian.PutUint16(b[2:], icmpChecksum(b))
+	return b
+}
+
+// icmpChecksum computes the Internet checksum of b.
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// ParseICMP decodes an ICMPv4 message without its IP header. For
+// destination-unreachable messages the echo identifiers and destination are
+// taken from the embedded original datagram.
+//
+// Parameters:
+// - b: The raw ICMP message.
+//
+// Returns:
+// - The decoded ICMPMessage.
+// - An error if the message is truncated.
+//
+// Example:
+//
+//	msg, err := ParseICMP(buf[:n])
+func ParseICMP(b []byte) (ICMPMessage, error) {
+	if len(b) < 8 {
+		return ICMPMessage{}, errShortICMP
+	}
+	msg := ICMPMessage{Type: int(b[0]), Code: int(b[1])}
+	if msg.Type != icmpDestUnreachable {
+		msg.ID = int(binary.BigEndian.Uint16(b[4:]))
+		msg.Seq = int(binary.BigEndian.Uint16(b[6:]))
+		msg.Data = b[8:]
+		return msg, nil
+	}
+
+	// The original IPv4 header follows the 8 byte ICMP header
+	inner := b[8:]
+	if len(inner) < 20 {
+		return ICMPMessage{}, errShortICMP
+	}
+	headerLen := int(inner[0]&0x0f) * 4
+	if headerLen < 20 || len(inner) < headerLen {
+		return ICMPMessage{}, errShortICMP
+	}
+	msg.OriginalDst = net.IP(append([]byte(nil), inner[16:20]...))
+	msg.OriginalProto = int(inner[9])
+	if len(inner) < headerLen+8 {
+		return msg, nil
+	}
+	switch msg.OriginalProto {
+	case protoICMP:
+		msg.ID = int(binary.BigEndian.Uint16(inner[headerLen+4:]))
+		msg.Seq = int(binary.BigEndian.Uint16(inner[headerLen+6:]))
+	case protoUDP:
+		msg.OriginalSrcPort = int(binary.BigEndian.Uint16(inner[headerLen:]))
+		msg.OriginalDstPort = int(binary.BigEndian.Uint16(inner[headerLen+2:]))
+	}
+	return msg, nil
+}
+
+// stripIPv4Header removes the IPv4 header that ReadMsgIP, unlike ReadFrom,
+// leaves in front of raw ICMP messages.
+func stripIPv4Header(b []byte) []byte {
+	if len(b) < 20 || b[0]>>4 != 4 {
+		return b
+	}
+	headerLen := int(b[0]&0x0f) * 4
+	if headerLen < 20 || len(b) < headerLen {
+		return b
+	}
+	return b[headerLen:]
+}
+
+// ClassifyICMP maps an ICMP reply to a reachability state.
+//
+// Parameters:
+// - msg: The decoded reply.
+//
+// Returns:
+// - ICMPReachable for an echo reply, ICMPFiltered for an administratively
+// prohibited error, ICMPUnreachable for other destination-unreachable errors
+// and ICMPNoReply for anything else.
+func ClassifyICMP(msg ICMPMessage) string {
+	switch {
+	case msg.Type == icmpEchoReply:
+		return ICMPReachable
+	case msg.Type == icmpDestUnreachable && icmpAdminProhibited[msg.Code]:
+		return ICMPFiltered
+	case msg.Type == icmpDestUnreachable:
+		return ICMPUnreachable
+	}
+	return ICMPNoReply
+}
+
+// pingICMP sends one echo request to ip and waits for the matching reply or
+// error message until the timeout expires.
+//
+// Parameters:
+// - src: The local address to send from, "0.0.0.0" for any.
+// - ip: The IPv4 address to ping.
+// - size: The echo payload size in bytes.
+// - timeout: How long to wait for a reply.
+//
+// Returns:
+// - The ICMPResult, with RTT and TTL set for echo replies.
+// - An error if the ICMP socket cannot be used.
+func pingICMP(src, ip string, size int, timeout time.Duration) (ICMPResult, error) {
+	result := ICMPResult{IP: ip, State: ICMPNoReply}
+	dst := net.ParseIP(ip)
+	if dst == nil || dst.To4() == nil {
+		return result, nil
+	}
+	packetConn, err := listenRaw("ip4:icmp", src)
+	if err != nil {
+		return result, err
+	}
+	conn := packetConn.(*net.IPConn)
+	defer conn.Close()
+	enableTTL(conn)
+
+	id := os.Getpid() & 0xffff
+	if _, err := conn.WriteTo(MarshalEcho(id, 1, EchoPayload(time.Now(), size)), &net.IPAddr{IP: dst}); err != nil {
+		return result, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 65535)
+	oob := make([]byte, 128)
+	for {
+		n, oobn, _, from, err := conn.ReadMsgIP(buf, oob)
+		if err != nil {
+			// A read timeout simply means no reply arrived
+			return result, nil
+		}
+		received := time.Now()
+		msg, err := ParseICMP(stripIPv4Header(buf[:n]))
+		if err != nil || msg.ID != id {
+			continue
+		}
+		if msg.Type == icmpEchoReply && from.IP.Equal(dst) {
+			result.State = ClassifyICMP(msg)
+			result.Reachable = true
+			result.RTT, _ = EchoRTT(msg.Data, received)
+			result.TTL = receivedTTL(buf[:n], oob[:oobn])
+			return result, nil
+		}
+		if msg.Type == icmpDestUnreachable && msg.OriginalDst.Equal(dst) {
+			result.State = ClassifyICMP(msg)
+			return result, nil
+		}
+	}
+}
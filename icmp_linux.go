@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// enableTTL asks the kernel to attach the received TTL to every ICMP reply.
+func enableTTL(conn *net.IPConn) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+	raw.Control(func(fd uintptr) {
+		syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTTL, 1)
+	})
+}
+
+// parseTTL extracts the IP_TTL control message enabled by enableTTL.
+//
+// Returns:
+// - The TTL of the received packet, or 0 if it is not present.
+func parseTTL(oob []byte) int {
+	messages, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0
+	}
+	for _, msg := range messages {
+		if msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_TTL && len(msg.Data) >= 4 {
+			return int(*(*int32)(unsafe.Pointer(&msg.Data[0])))
+		}
+	}
+	return 0
+}
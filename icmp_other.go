@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+// enableTTL is a no-op on platforms where the received TTL is not reported.
+func enableTTL(conn *net.IPConn) {}
+
+// parseTTL always returns 0, meaning unknown, on this platform.
+func parseTTL(oob []byte) int {
+	return 0
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Recorded ICMP messages, without their outer IPv4 header.
+const (
+	// Echo reply from 192.0.2.1 for id 0x1234, seq 1.
+	recordedEchoReply = "0000 0efa 1234 0001 70696e67"
+	// Destination unreachable, communication administratively prohibited,
+	// quoting an echo request to 192.0.2.1 with id 0x1234, seq 1.
+	recordedAdminProhibited = "030d dbc3 00000000" +
+		"4500 0020 0000 4000 4001 b6da c0000201 c0000201" +
+		"0800 06fa 1234 0001"
+	// Destination unreachable, host unreachable, for the same echo request.
+	recordedHostUnreachable = "0301 dbcf 00000000" +
+		"4500 0020 0000 4000 4001 b6da c0000201 c0000201" +
+		"0800 06fa 1234 0001"
+	// Echo reply for id 0x1234, seq 1 whose 16 byte payload starts with the
+	// send timestamp 1700000000000000000ns.
+	recordedTimestampedReply = "0000 d6da 1234 0001 17979cfe362a0000 08090a0b0c0d0e0f"
+)
+
+func decodeHex(t testing.TB, s string) []byte {
+	t.Helper()
+	clean := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			clean = append(clean, s[i])
+		}
+	}
+	b, err := hex.DecodeString(string(clean))
+	if err != nil {
+		t.Fatalf("Invalid recorded packet: %s", err)
+	}
+	return b
+}
+
+func TestParseICMP_Recorded(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   string
+		expected string
+	}{
+		{"echo reply", recordedEchoReply, ICMPReachable},
+		{"admin prohibited", recordedAdminProhibited, ICMPFiltered},
+		{"host unreachable", recordedHostUnreachable, ICMPUnreachable},
+	}
+	for _, test := range tests {
+		msg, err := ParseICMP(decodeHex(t, test.packet))
+		if err != nil {
+			t.Fatalf("%s: ParseICMP failed: %s", test.name, err)
+		}
+		if msg.ID != 0x1234 || msg.Seq != 1 {
+			t.Errorf("%s: Expected id 0x1234 seq 1, got id %#x seq %d", test.name, msg.ID, msg.Seq)
+		}
+		if msg.Type == icmpDestUnreachable && !msg.OriginalDst.Equal(net.ParseIP("192.0.2.1")) {
+			t.Errorf("%s: Expected original destination 192.0.2.1, got %s", test.name, msg.OriginalDst)
+		}
+		if state := ClassifyICMP(msg); state != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, state)
+		}
+	}
+}
+
+func TestParseICMP_Short(t *testing.T) {
+	if _, err := ParseICMP([]byte{3, 13, 0}); err == nil {
+		t.Errorf("Expected an error for a truncated message")
+	}
+	if _, err := ParseICMP(decodeHex(t, "030d dbc3 00000000 4500")); err == nil {
+		t.Errorf("Expected an error for a truncated original datagram")
+	}
+}
+
+func TestMarshalEcho_Checksum(t *testing.T) {
+	packet := MarshalEcho(0x1234, 1, []byte("ping"))
+	if icmpChecksum(packet) != 0 {
+		t.Errorf("Expected a valid checksum, got packet %x", packet)
+	}
+	msg, err := ParseICMP(packet)
+	if err != nil {
+		t.Fatalf("ParseICMP failed: %s", err)
+	}
+	if msg.Type != icmpEcho || msg.ID != 0x1234 || msg.Seq != 1 {
+		t.Errorf("Unexpected echo request %+v", msg)
+	}
+}
+
+func TestRecordedChecksums(t *testing.T) {
+	for _, packet := range []string{recordedEchoReply, recordedAdminProhibited, recordedHostUnreachable} {
+		if sum := icmpChecksum(decodeHex(t, packet)); sum != 0 {
+			t.Errorf("Recorded packet %q has an invalid checksum", packet)
+		}
+	}
+}
+
+func TestEchoRTT_Recorded(t *testing.T) {
+	msg, err := ParseICMP(decodeHex(t, recordedTimestampedReply))
+	if err != nil {
+		t.Fatalf("ParseICMP failed: %s", err)
+	}
+	if len(msg.Data) != 16 {
+		t.Fatalf("Expected a 16 byte payload, got %d bytes", len(msg.Data))
+	}
+	received := time.Unix(0, 1700000000000000000).Add(12 * time.Millisecond)
+	rtt, ok := EchoRTT(msg.Data, received)
+	if !ok || rtt != 12*time.Millisecond {
+		t.Errorf("Expected RTT 12ms, got %v (ok=%v)", rtt, ok)
+	}
+	if _, ok := EchoRTT([]byte("ping"), received); ok {
+		t.Errorf("Expected no RTT for a payload without a timestamp")
+	}
+}
+
+func TestEchoPayload_Size(t *testing.T) {
+	sent := time.Unix(0, 1700000000000000000)
+	payload := EchoPayload(sent, 100)
+	if len(payload) != 100 {
+		t.Errorf("Expected a 100 byte payload, got %d bytes", len(payload))
+	}
+	if rtt, ok := EchoRTT(payload, sent.Add(time.Second)); !ok || rtt != time.Second {
+		t.Errorf("Expected RTT 1s, got %v (ok=%v)", rtt, ok)
+	}
+	if payload := EchoPayload(sent, 2); len(payload) != icmpTimestampSize {
+		t.Errorf("Expected a payload raised to %d bytes, got %d bytes", icmpTimestampSize, len(payload))
+	}
+}
+
+func TestICMPResult_String(t *testing.T) {
+	result := ICMPResult{IP: "192.0.2.1", Reachable: true, State: ICMPReachable, RTT: 12 * time.Millisecond, TTL: 64}
+	expected := "IP: 192.0.2.1, Response: Reachable, RTT: 12ms, TTL: 64"
+	if got := result.String(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	unreachable := ICMPResult{IP: "192.0.2.1", State: ICMPNoReply}
+	if got := unreachable.String(); strings.Contains(got, "RTT") {
+		t.Errorf("Expected no RTT for an unreachable host, got %q", got)
+	}
+}
+
+// FuzzParseICMP checks that no packet read from the raw socket makes
+// ParseICMP or stripIPv4Header panic. Run it with:
+//
+//	go test -run '^$' -fuzz FuzzParseICMP -fuzztime 30s
+func FuzzParseICMP(f *testing.F) {
+	for _, recorded := range []string{recordedEchoReply, recordedAdminProhibited, recordedHostUnreachable, recordedTimestampedReply} {
+		f.Add(decodeHex(f, recorded))
+	}
+	f.Add([]byte{0x45, 0x00})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if msg, err := ParseICMP(b); err == nil {
+			ClassifyICMP(msg)
+		}
+		ParseICMP(stripIPv4Header(b))
+	})
+}
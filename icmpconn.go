@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// errICMPBusy is returned by ICMPConn.Ping when every echo identifier is
+// taken by a ping in flight.
+var errICMPBusy = errors.New("icmp: every echo identifier is in use")
+
+// ICMPConn is one ICMP socket shared by concurrent pings, so a sweep of many
+// IPs does not open a socket per probe. Every ping gets its own echo
+// identifier and a reader goroutine hands each reply or error message to the
+// ping it answers.
+//
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	conn, err := ListenICMP("0.0.0.0")
+//	if err == nil {
+//	    defer conn.Close()
+//	    result, _ := conn.Ping("192.0.2.1", DefaultICMPPayloadSize, DefaultICMPTimeout)
+//	}
+type ICMPConn struct {
+	mu      sync.Mutex
+	nextID  int
+	waiters map[int]icmpWaiter
+	conn    *net.IPConn
+	pcap    *PcapWriter
+}
+
+// icmpWaiter is a ping waiting for the message answering its echo request.
+type icmpWaiter struct {
+	dst     net.IP
+	replies chan icmpReply
+}
+
+// icmpReply is an ICMP message received on an ICMPConn.
+type icmpReply struct {
+	msg      ICMPMessage
+	from     net.IP
+	received time.Time
+	ttl      int
+}
+
+// newICMPConn creates an ICMPConn that is only fed through deliver.
+func newICMPConn() *ICMPConn {
+	return &ICMPConn{nextID: os.Getpid() & 0xffff, waiters: make(map[int]icmpWaiter)}
+}
+
+// ListenICMP opens the shared ICMP socket and starts demultiplexing the
+// messages received on it until Close is called. Opening the socket usually
+// requires root.
+//
+// Parameters:
+// - src: The local address to send from, "0.0.0.0" for any.
+//
+// Returns:
+// - A pointer to the new ICMPConn.
+// - An error if the ICMP socket cannot be opened.
+func ListenICMP(src string) (*ICMPConn, error) {
+	return listenICMP(src, nil)
+}
+
+// listenICMP opens the shared ICMP socket like ListenICMP, capturing its
+// traffic to pcap if it is not nil. The capture is set before the reader
+// starts, which uses it.
+func listenICMP(src string, pcap *PcapWriter) (*ICMPConn, error) {
+	packetConn, err := listenRaw("ip4:icmp", src)
+	if err != nil {
+		return nil, err
+	}
+	c := newICMPConn()
+	c.conn = packetConn.(*net.IPConn)
+	c.pcap = pcap
+	enableTTL(c.conn)
+	go c.read()
+	return c, nil
+}
+
+// read delivers every message received on the socket until it is closed.
+func (c *ICMPConn) read() {
+	buf := make([]byte, 65535)
+	oob := make([]byte, 128)
+	for {
+		n, oobn, _, from, err := c.conn.ReadMsgIP(buf, oob)
+		if err != nil {
+			return
+		}
+		received := time.Now()
+		// The message is handed to another goroutine while buf is reused
+		packet := append([]byte(nil), buf[:n]...)
+		msg, err := ParseICMP(stripIPv4Header(packet))
+		if err != nil {
+			continue
+		}
+		// Our own echo requests, seen again on loopback, are captured when sent
+		if c.pcap != nil && msg.Type != icmpEcho {
+			c.pcap.WritePacket(received, packet)
+		}
+		c.deliver(icmpReply{msg: msg, from: from.IP, received: received, ttl: receivedTTL(packet, oob[:oobn])})
+	}
+}
+
+// Close closes the ICMP socket. Pings still waiting time out.
+//
+// Returns:
+// - An error if the ICMP socket cannot be closed.
+func (c *ICMPConn) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// register reserves an echo identifier no other ping is using.
+//
+// Parameters:
+// - dst: The address being pinged.
+//
+// Returns:
+// - The echo identifier.
+// - The channel the answering message is delivered on.
+// - false if every identifier is in use.
+func (c *ICMPConn) register(dst net.IP) (int, chan icmpReply, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i <= 0xffff; i++ {
+		id := c.nextID
+		c.nextID = (c.nextID + 1) & 0xffff
+		if _, busy := c.waiters[id]; !busy {
+			replies := make(chan icmpReply, 1)
+			c.waiters[id] = icmpWaiter{dst: dst, replies: replies}
+			return id, replies, true
+		}
+	}
+	return 0, nil, false
+}
+
+// unregister releases an echo identifier.
+func (c *ICMPConn) unregister(id int) {
+	c.mu.Lock()
+	delete(c.waiters, id)
+	c.mu.Unlock()
+}
+
+// deliver hands a message to the ping it answers: an echo reply from the
+// pinged address, or a destination-unreachable error quoting the echo
+// request. Other messages, e.g. replies to other processes, are ignored.
+//
+// Parameters:
+// - reply: The received message.
+func (c *ICMPConn) deliver(reply icmpReply) {
+	var dst net.IP
+	switch {
+	case reply.msg.Type == icmpEchoReply:
+		dst = reply.from
+	case reply.msg.Type == icmpDestUnreachable && reply.msg.OriginalProto == protoICMP:
+		dst = reply.msg.OriginalDst
+	default:
+		return
+	}
+	c.mu.Lock()
+	waiter, ok := c.waiters[reply.msg.ID]
+	if ok && waiter.dst.Equal(dst) {
+		delete(c.waiters, reply.msg.ID)
+	} else {
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		waiter.replies <- reply
+	}
+}
+
+// Ping sends one echo request to ip through the shared socket and waits for
+// the matching reply or error message until the timeout expires.
+//
+// Parameters:
+// - ip: The IPv4 address to ping.
+// - size: The echo payload size in bytes.
+// - timeout: How long to wait for a reply.
+//
+// Returns:
+// - The ICMPResult, with RTT and TTL set for echo replies.
+// - An error if the echo request cannot be sent.
+//
+// Example:
+//
+//	result, err := conn.Ping("192.0.2.1", DefaultICMPPayloadSize, time.Second)
+func (c *ICMPConn) Ping(ip string, size int, timeout time.Duration) (ICMPResult, error) {
+	result := ICMPResult{IP: ip, State: ICMPNoReply}
+	dst := net.ParseIP(ip)
+	if dst == nil || dst.To4() == nil {
+		return result, nil
+	}
+	id, replies, ok := c.register(dst)
+	if !ok {
+		return result, errICMPBusy
+	}
+	defer c.unregister(id)
+
+	echo := MarshalEcho(id, 1, EchoPayload(time.Now(), size))
+	if _, err := c.conn.WriteTo(echo, &net.IPAddr{IP: dst}); err != nil {
+		return result, err
+	}
+	if c.pcap != nil {
+		if src, err := localAddrFor(dst); err == nil {
+			c.pcap.WritePacket(time.Now(), ipv4Packet(src, dst, protoICMP, echo))
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case reply := <-replies:
+		result.State = ClassifyICMP(reply.msg)
+		if reply.msg.Type == icmpEchoReply {
+			result.Reachable = true
+			result.RTT, _ = EchoRTT(reply.msg.Data, reply.received)
+			result.TTL = reply.ttl
+		}
+	case <-timer.C:
+	}
+	return result, nil
+}
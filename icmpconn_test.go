@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestICMPConn_Demultiplexes(t *testing.T) {
+	c := newICMPConn()
+	first, firstReplies, _ := c.register(net.ParseIP("192.0.2.1"))
+	second, secondReplies, _ := c.register(net.ParseIP("192.0.2.2"))
+	if first == second {
+		t.Fatalf("Expected distinct echo identifiers, got %d twice", first)
+	}
+
+	// A reply with the right identifier from the wrong host is not ours
+	c.deliver(icmpReply{msg: ICMPMessage{Type: icmpEchoReply, ID: first}, from: net.ParseIP("192.0.2.9")})
+	c.deliver(icmpReply{msg: ICMPMessage{Type: icmpEchoReply, ID: first}, from: net.ParseIP("192.0.2.1"), ttl: 64})
+	c.deliver(icmpReply{msg: ICMPMessage{Type: icmpDestUnreachable, Code: 1, ID: second, OriginalProto: protoICMP, OriginalDst: net.ParseIP("192.0.2.2")}})
+
+	select {
+	case reply := <-firstReplies:
+		if reply.ttl != 64 || !reply.from.Equal(net.ParseIP("192.0.2.1")) {
+			t.Errorf("Expected the echo reply from 192.0.2.1, got %+v", reply)
+		}
+	default:
+		t.Errorf("Expected the echo reply to reach the first ping")
+	}
+	select {
+	case reply := <-secondReplies:
+		if ClassifyICMP(reply.msg) != ICMPUnreachable {
+			t.Errorf("Expected %q for the second ping, got %+v", ICMPUnreachable, reply.msg)
+		}
+	default:
+		t.Errorf("Expected the unreachable error to reach the second ping")
+	}
+
+	c.unregister(first)
+	if reused, _, _ := c.register(net.ParseIP("192.0.2.3")); reused == second {
+		t.Errorf("Expected an identifier in use not to be handed out again")
+	}
+}
+
+func TestICMPConn_Sweep(t *testing.T) {
+	if !Privileged() {
+		t.Skip("raw ICMP sockets require privileges")
+	}
+	conn, err := ListenICMP("0.0.0.0")
+	if err != nil {
+		t.Fatalf("ListenICMP failed: %s", err)
+	}
+	defer conn.Close()
+
+	// Every address in 127.0.0.0/8 answers on the loopback interface
+	const hosts = 64
+	results := make([]ICMPResult, hosts)
+	var wg sync.WaitGroup
+	for i := 0; i < hosts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = conn.Ping(fmt.Sprintf("127.0.0.%d", i+1), DefaultICMPPayloadSize, 2*time.Second)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if expected := fmt.Sprintf("127.0.0.%d", i+1); result.IP != expected || !result.Reachable {
+			t.Errorf("Expected %s to be reachable through the shared socket, got %+v", expected, result)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// ICMPStats summarizes several pings of one IP address, like ping does.
+//
+// Fields:
+// - IP: The pinged IP address.
+// - Sent: The number of echo requests sent.
+// - Received: The number of echo replies received.
+// - Loss: The percentage of echo requests without a reply.
+// - MinRTT: The smallest round-trip time of the replies.
+// - AvgRTT: The average round-trip time of the replies.
+// - MaxRTT: The largest round-trip time of the replies.
+type ICMPStats struct {
+	IP       string        `json:"ip"`
+	Sent     int           `json:"sent"`
+	Received int           `json:"received"`
+	Loss     float64       `json:"loss"`
+	MinRTT   time.Duration `json:"min_rtt"`
+	AvgRTT   time.Duration `json:"avg_rtt"`
+	MaxRTT   time.Duration `json:"max_rtt"`
+}
+
+// String formats the statistics as a report line.
+func (s ICMPStats) String() string {
+	line := fmt.Sprintf("IP: %s, %d sent, %d received, %.0f%% loss", s.IP, s.Sent, s.Received, s.Loss)
+	if s.Received > 0 {
+		line += fmt.Sprintf(", RTT min/avg/max: %s/%s/%s", s.MinRTT, s.AvgRTT, s.MaxRTT)
+	}
+	return line
+}
+
+// ComputeICMPStats aggregates the results of pinging one IP several times.
+//
+// Parameters:
+// - ip: The pinged IP address.
+// - results: The result of every ping.
+//
+// Returns:
+// - The statistics; RTTs are zero if no reply was received.
+//
+// Example:
+//
+//	stats := ComputeICMPStats("192.0.2.1", results)
+func ComputeICMPStats(ip string, results []ICMPResult) ICMPStats {
+	stats := ICMPStats{IP: ip, Sent: len(results)}
+	var total time.Duration
+	for _, result := range results {
+		if !result.Reachable {
+			continue
+		}
+		stats.Received++
+		total += result.RTT
+		if stats.Received == 1 || result.RTT < stats.MinRTT {
+			stats.MinRTT = result.RTT
+		}
+		if result.RTT > stats.MaxRTT {
+			stats.MaxRTT = result.RTT
+		}
+	}
+	if stats.Sent > 0 {
+		stats.Loss = float64(stats.Sent-stats.Received) * 100 / float64(stats.Sent)
+	}
+	if stats.Received > 0 {
+		stats.AvgRTT = total / time.Duration(stats.Received)
+	}
+	return stats
+}
+
+// pingRepeatedly pings an IP ICMPCount times and records the statistics for
+// the report.
+//
+// Parameters:
+// - ip: The IP address to ping.
+//
+// Returns:
+// - The last reply if any ping was answered, otherwise the last result.
+func (t *PortScanner) pingRepeatedly(ip string) ICMPResult {
+	ping := t.pinger
+	if ping == nil {
+		ping = t.scanICMP
+	}
+	count := t.ICMPCount
+	if count < 1 {
+		count = 1
+	}
+
+	results := make([]ICMPResult, 0, count)
+	var reply, last ICMPResult
+	for i := 0; i < count; i++ {
+		last = ping(ip)
+		if last.Reachable {
+			reply = last
+		}
+		results = append(results, last)
+	}
+	if count > 1 {
+		stats := ComputeICMPStats(ip, results)
+		t.reportMu.Lock()
+		t.collected.ICMPStats = append(t.collected.ICMPStats, stats)
+		t.reportMu.Unlock()
+	}
+	if reply.Reachable {
+		return reply
+	}
+	return last
+}
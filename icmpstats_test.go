@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestComputeICMPStats(t *testing.T) {
+	results := []ICMPResult{
+		{Reachable: true, RTT: 10 * time.Millisecond},
+		{Reachable: true, RTT: 30 * time.Millisecond},
+		{State: ICMPNoReply},
+		{Reachable: true, RTT: 20 * time.Millisecond},
+	}
+	stats := ComputeICMPStats("192.0.2.1", results)
+	expected := ICMPStats{IP: "192.0.2.1", Sent: 4, Received: 3, Loss: 25,
+		MinRTT: 10 * time.Millisecond, AvgRTT: 20 * time.Millisecond, MaxRTT: 30 * time.Millisecond}
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+
+	if stats := ComputeICMPStats("192.0.2.1", []ICMPResult{{}, {}}); stats.Loss != 100 || stats.AvgRTT != 0 {
+		t.Errorf("Expected 100%% loss without RTTs, got %+v", stats)
+	}
+}
+
+func TestScan_ICMPCountReportsLoss(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = nil
+	scanner.ICMPCount = 4
+	scanner.Quiet = true
+
+	// The responder drops the second of every four echo requests
+	var mu sync.Mutex
+	sent := 0
+	scanner.pinger = func(ip string) ICMPResult {
+		mu.Lock()
+		defer mu.Unlock()
+		sent++
+		if sent%4 == 2 {
+			return ICMPResult{IP: ip, State: ICMPNoReply}
+		}
+		return ICMPResult{IP: ip, Reachable: true, State: ICMPReachable, RTT: time.Duration(sent) * time.Millisecond}
+	}
+
+	scanner.Scan()
+
+	report := scanner.Report()
+	if len(report.ICMPStats) != 1 {
+		t.Fatalf("Expected statistics for 1 IP, got %v", report.ICMPStats)
+	}
+	stats := report.ICMPStats[0]
+	if stats.Sent != 4 || stats.Received != 3 || stats.Loss != 25 {
+		t.Errorf("Expected 4 sent, 3 received and 25%% loss, got %+v", stats)
+	}
+	if len(report.ICMP) != 1 || !report.ICMP[0].Reachable {
+		t.Errorf("Expected the IP to be reported reachable, got %v", report.ICMP)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, fileName); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	if !strings.Contains(string(data), "IP: 127.0.0.1, 4 sent, 3 received, 25% loss, RTT min/avg/max: 1ms/2.666666ms/4ms") {
+		t.Errorf("Expected the statistics in the report, got:\n%s", data)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// signalAnswer tells the collector a probe got an answer, resetting the
+// IdleTimeout. It never blocks: one pending signal is enough.
+func (t *PortScanner) signalAnswer() {
+	if t.answered == nil {
+		return
+	}
+	select {
+	case t.answered <- struct{}{}:
+	default:
+	}
+}
+
+// abortIdle cancels the scan once IdleTimeout passed without an answer. The
+// workers then skip the remaining endpoints, as for a cancelled scan.
+func (t *PortScanner) abortIdle() {
+	atomic.StoreInt32(&t.idledOut, 1)
+	err := fmt.Errorf("no answer within %s, aborting the scan", t.IdleTimeout)
+	t.logf("Error scanning %s: %s\n", t.Domain, err)
+	t.addError("idle", t.Domain, err)
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// idleTimer fires once a timeout passes without being reset. Without a
+// timeout its channel is nil, so it never fires.
+//
+// Fields:
+// - C: Receives when the timeout passed, nil once stopped.
+type idleTimer struct {
+	C       <-chan time.Time
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+// newIdleTimer starts an idleTimer.
+//
+// Parameters:
+// - timeout: The time without a reset after which it fires, 0 for never.
+//
+// Returns:
+// - The running timer.
+func newIdleTimer(timeout time.Duration) *idleTimer {
+	if timeout <= 0 {
+		return &idleTimer{}
+	}
+	timer := time.NewTimer(timeout)
+	return &idleTimer{C: timer.C, timer: timer, timeout: timeout}
+}
+
+// reset restarts the timeout, unless the timer was stopped.
+func (i *idleTimer) reset() {
+	if i.timer == nil {
+		return
+	}
+	if !i.timer.Stop() {
+		select {
+		case <-i.timer.C:
+		default:
+		}
+	}
+	i.timer.Reset(i.timeout)
+}
+
+// stop stops the timer for good.
+func (i *idleTimer) stop() {
+	if i.timer == nil {
+		return
+	}
+	i.timer.Stop()
+	i.timer = nil
+	i.C = nil
+}
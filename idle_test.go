@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// idleScanner returns a TCP-only scanner of 1000 ports on localhost whose
+// probes are answered by prober.
+func idleScanner(t *testing.T, prober ProberFunc) *PortScanner {
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = allPorts()[:1000]
+	scanner.EnableTCP = true
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+	scanner.Quiet = true
+	scanner.TCPProber = prober
+	return scanner
+}
+
+func TestScan_IdleTimeoutAbortsStalledScan(t *testing.T) {
+	// The network goes down after the first 10 ports: every later probe
+	// hangs until it times out
+	scanner := idleScanner(t, func(ctx context.Context, ip string, port int) (State, error) {
+		if port <= 10 {
+			return StateClosed, nil
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Second):
+		}
+		return StateFiltered, nil
+	})
+	scanner.IdleTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	scanner.Scan()
+	elapsed := time.Since(start)
+
+	if elapsed < scanner.IdleTimeout {
+		t.Errorf("Expected the scan to run for at least the idle timeout, took %s", elapsed)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("Expected the stalled scan to be aborted after the idle timeout, took %s", elapsed)
+	}
+	if !scanner.Cancelled {
+		t.Errorf("Expected the aborted scan to be flagged as cancelled")
+	}
+	var scanErr *ScanError
+	if len(scanner.Errors) != 1 || !errors.As(scanner.Errors[0], &scanErr) || scanErr.Stage != "idle" {
+		t.Errorf("Expected an idle error, got %v", scanner.Errors)
+	}
+	if summary := scanner.Summary(); summary.Closed != 10 {
+		t.Errorf("Expected the 10 ports probed before the stall to be counted, got %d", summary.Closed)
+	}
+}
+
+func TestScan_IdleTimeoutKeepsAnsweringScan(t *testing.T) {
+	// Every probe is slow, but each answers well within the idle timeout
+	scanner := idleScanner(t, func(ctx context.Context, ip string, port int) (State, error) {
+		time.Sleep(time.Millisecond)
+		return StateClosed, nil
+	})
+	scanner.Ports = scanner.Ports[:200]
+	scanner.IdleTimeout = 200 * time.Millisecond
+
+	scanner.Scan()
+
+	if scanner.Cancelled {
+		t.Errorf("Expected a scan that keeps getting answers not to be aborted, errors: %v", scanner.Errors)
+	}
+	if summary := scanner.Summary(); summary.Closed != 200 {
+		t.Errorf("Expected all 200 ports to be probed, got %d", summary.Closed)
+	}
+}
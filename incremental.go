@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"det/service"
+)
+
+// DefaultIncrementalSync is how often the incremental output is flushed to
+// disk with Sync.
+const DefaultIncrementalSync = time.Second
+
+// incrementalWriter appends every reported port to a file as soon as it is
+// found, so a crash before writeResultsToFile runs keeps the findings so far.
+// It is safe for concurrent use by the workers.
+type incrementalWriter struct {
+	mu       sync.Mutex
+	file     *os.File
+	interval time.Duration
+	lastSync time.Time
+	err      error
+}
+
+// openIncremental creates, or truncates, the incremental output file.
+//
+// Parameters:
+// - fileName: The file to write.
+// - interval: How often written results are synced to disk.
+//
+// Returns:
+// - The writer.
+// - An error if the file cannot be created.
+func openIncremental(fileName string, interval time.Duration) (*incrementalWriter, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file: %s", err)
+	}
+	if interval <= 0 {
+		interval = DefaultIncrementalSync
+	}
+	return &incrementalWriter{file: file, interval: interval, lastSync: time.Now()}, nil
+}
+
+// write appends a reported port, syncing the file when the sync interval
+// has passed. The first error is kept and later writes are skipped.
+func (w *incrementalWriter) write(svc service.ServiceVersion) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return
+	}
+	if _, err := fmt.Fprintf(w.file, "IP %s: %s\n", svc.IP, serviceLine(svc.Protocol, svc)); err != nil {
+		w.err = fmt.Errorf("error writing to file: %s", err)
+		return
+	}
+	if time.Since(w.lastSync) >= w.interval {
+		w.lastSync = time.Now()
+		if err := w.file.Sync(); err != nil {
+			w.err = fmt.Errorf("error syncing file: %s", err)
+		}
+	}
+}
+
+// close syncs and closes the file.
+//
+// Returns:
+// - The first error met while writing, syncing or closing.
+func (w *incrementalWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.file.Sync(); err != nil && w.err == nil {
+		w.err = fmt.Errorf("error syncing file: %s", err)
+	}
+	if err := w.file.Close(); err != nil && w.err == nil {
+		w.err = fmt.Errorf("error closing file: %s", err)
+	}
+	return w.err
+}
+
+// startIncremental opens IncrementalOutput, if set, for the duration of a
+// scan.
+//
+// Returns:
+// - A function closing the file, to be deferred.
+func (t *PortScanner) startIncremental() func() {
+	if t.IncrementalOutput == "" {
+		return func() {}
+	}
+	writer, err := openIncremental(t.IncrementalOutput, t.IncrementalSync)
+	if err != nil {
+		t.logf("Error opening incremental output, results are only written at the end: %s\n", err)
+		t.addError("incremental-output", t.IncrementalOutput, err)
+		return func() {}
+	}
+	t.incremental = writer
+	return func() {
+		t.incremental = nil
+		if err := writer.close(); err != nil {
+			t.logf("Error writing incremental output: %s\n", err)
+			t.addError("incremental-output", t.IncrementalOutput, err)
+		}
+	}
+}
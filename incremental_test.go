@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// crashOutputEnv names the output file of the scan TestIncrementalOutput_Crash
+// runs in a child process.
+const crashOutputEnv = "PORT_SCANNER_CRASH_OUTPUT"
+
+func TestIncrementalOutput_Crash(t *testing.T) {
+	if fileName := os.Getenv(crashOutputEnv); fileName != "" {
+		// In the child: exit abruptly once a few open ports were reported
+		scanner, err := NewTarget("127.0.0.1", 1)
+		if err != nil {
+			os.Exit(2)
+		}
+		scanner.Ports = []int{40001, 40002, 40003, 40004, 40005, 40006, 40007, 40008}
+		scanner.Dial = (&mockDialer{}).Dial
+		scanner.HTTPProbe = false
+		scanner.Quiet = true
+		scanner.IncrementalOutput = fileName
+		var open int64
+		scanner.OnResult = func(event ScanEvent) {
+			if event.Type == EventPort && atomic.AddInt64(&open, 1) == 3 {
+				os.Exit(3)
+			}
+		}
+		scanner.Scan()
+		os.Exit(0)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestIncrementalOutput_Crash$")
+	cmd.Env = append(os.Environ(), crashOutputEnv+"="+fileName)
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Fatalf("Expected the scan to exit mid-way with code 3, got %v", err)
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) < 3 || len(lines) >= 16 {
+		t.Fatalf("Expected partial results, got:\n%s", data)
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "IP 127.0.0.1: Port 4000") || !strings.Contains(line, "is Open") {
+			t.Errorf("Expected an open port line, got %q", line)
+		}
+	}
+}
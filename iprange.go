@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseIPRange lists every IP address of a range in the dash form, either
+// with a full end address ("10.0.0.1-10.0.0.50") or, for IPv4, with only
+// the last octet of the end address ("10.0.0.1-50"). Both ends are
+// included.
+//
+// Parameters:
+// - spec: The range.
+//
+// Returns:
+// - The addresses, in order.
+// - An error if spec is invalid, its end comes before its start, or it holds
+// more than DefaultMaxHosts addresses.
+//
+// Example:
+//
+//	ips, err := ParseIPRange("192.168.1.1-3")
+//	// ips: ["192.168.1.1", "192.168.1.2", "192.168.1.3"]
+func ParseIPRange(spec string) ([]string, error) {
+	startSpec, endSpec, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid IP range %q, expected start-end", spec)
+	}
+	start := net.ParseIP(strings.TrimSpace(startSpec))
+	if start == nil {
+		return nil, fmt.Errorf("invalid IP range %q: invalid start address", spec)
+	}
+	endSpec = strings.TrimSpace(endSpec)
+	end := net.ParseIP(endSpec)
+	if end == nil && start.To4() != nil {
+		// The shorthand form only gives the last octet of the end address
+		octet, err := strconv.Atoi(endSpec)
+		if err == nil && octet >= 0 && octet <= 255 {
+			end = append(net.IP(nil), start.To4()...)
+			end[3] = byte(octet)
+		}
+	}
+	if end == nil {
+		return nil, fmt.Errorf("invalid IP range %q: invalid end address", spec)
+	}
+
+	// Compare both ends in the same form, 4 bytes for IPv4
+	if v4 := start.To4(); v4 != nil {
+		start = v4
+		if end = end.To4(); end == nil {
+			return nil, fmt.Errorf("invalid IP range %q: the addresses are of different families", spec)
+		}
+	} else if end.To4() != nil {
+		return nil, fmt.Errorf("invalid IP range %q: the addresses are of different families", spec)
+	}
+	first, last := new(big.Int).SetBytes(start), new(big.Int).SetBytes(end)
+	if last.Cmp(first) < 0 {
+		return nil, fmt.Errorf("invalid IP range %q: %s comes before %s", spec, end, start)
+	}
+	hosts := new(big.Int).Sub(last, first)
+	hosts.Add(hosts, big.NewInt(1))
+	if hosts.Cmp(big.NewInt(DefaultMaxHosts)) > 0 {
+		return nil, fmt.Errorf("%s expands to %s hosts, more than the limit of %d", spec, hosts, DefaultMaxHosts)
+	}
+
+	ips := make([]string, 0, int(hosts.Int64()))
+	ip := append(net.IP(nil), start...)
+	for i := int64(0); i < hosts.Int64(); i++ {
+		ips = append(ips, ip.String())
+		incrementIP(ip)
+	}
+	return ips, nil
+}
+
+// isIPRange reports whether a target is meant as a dash-form IP range: host
+// names may contain dashes, but do not start with an IP address before one.
+func isIPRange(target string) bool {
+	start, _, ok := strings.Cut(target, "-")
+	return ok && net.ParseIP(strings.TrimSpace(start)) != nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIPRange(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []string
+	}{
+		{"192.168.1.1-192.168.1.3", []string{"192.168.1.1", "192.168.1.2", "192.168.1.3"}},
+		{"192.168.1.1-3", []string{"192.168.1.1", "192.168.1.2", "192.168.1.3"}},
+		{"10.0.0.254 - 10.0.1.1", []string{"10.0.0.254", "10.0.0.255", "10.0.1.0", "10.0.1.1"}},
+		{"10.0.0.7-7", []string{"10.0.0.7"}},
+		{"2001:db8::fe-2001:db8::101", []string{"2001:db8::fe", "2001:db8::ff", "2001:db8::100", "2001:db8::101"}},
+	}
+	for _, tt := range tests {
+		ips, err := ParseIPRange(tt.spec)
+		if err != nil {
+			t.Errorf("%s: Expected no error, got %s", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(ips, tt.expected) {
+			t.Errorf("%s: Expected %v, got %v", tt.spec, tt.expected, ips)
+		}
+	}
+}
+
+func TestParseIPRange_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"10.0.0.1",                // no end
+		"10.0.0.50-10.0.0.1",      // reversed
+		"10.0.0.50-1",             // reversed shorthand
+		"10.0.0.1-256",            // octet out of range
+		"10.0.0.1-abc",            // invalid end
+		"example-10.0.0.1",        // invalid start
+		"10.0.0.1-2001:db8::1",    // mixed families
+		"10.0.0.0-10.1.0.0",       // more than DefaultMaxHosts
+		"2001:db8::1-2001:db9::1", // more than DefaultMaxHosts
+		"2001:db8::1-5",           // no shorthand for IPv6
+	} {
+		if ips, err := ParseIPRange(spec); err == nil {
+			t.Errorf("%s: Expected an error, got %d IPs", spec, len(ips))
+		}
+	}
+}
+
+func TestNewTarget_IPRange(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1-5", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	if expected := []string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4", "127.0.0.5"}; !reflect.DeepEqual(scanner.IPs, expected) {
+		t.Errorf("Expected %v, got %v", expected, scanner.IPs)
+	}
+	if _, err := NewTarget("127.0.0.5-1", 1); err == nil {
+		t.Errorf("Expected a reversed range to be rejected")
+	}
+	if !isIPRange("10.0.0.1-50") || isIPRange("my-host.example.com") {
+		t.Errorf("Expected only ranges starting with an IP to be taken as ranges")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedRand is a math/rand source that is safe for concurrent use by workers.
+type lockedRand struct {
+	mu   sync.Mutex
+	once sync.Once
+	rnd  *rand.Rand
+}
+
+// int63n returns a random number in [0, n), seeding the source on first use.
+func (r *lockedRand) int63n(seed int64, n int64) int64 {
+	r.once.Do(func() {
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		r.rnd = rand.New(rand.NewSource(seed))
+	})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.Int63n(n)
+}
+
+// jitterDelay returns a random delay in [0, Jitter), or 0 when Jitter is unset.
+// The sequence is reproducible when RandSeed is set.
+func (t *PortScanner) jitterDelay() time.Duration {
+	if t.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(t.rand.int63n(t.RandSeed, int64(t.Jitter)))
+}
+
+// waitJitter sleeps for a random jitter delay before a probe, returning early
+// if ctx is cancelled. A perfectly regular probe cadence is easy to spot, so
+// the delay makes the scan's traffic pattern less regular.
+//
+// Parameters:
+// - ctx: The context that interrupts the wait when cancelled.
+func (t *PortScanner) waitJitter(ctx context.Context) {
+	delay := t.jitterDelay()
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestJitterDelay_Seeded(t *testing.T) {
+	first := &PortScanner{Jitter: time.Second, RandSeed: 42}
+	second := &PortScanner{Jitter: time.Second, RandSeed: 42}
+	for i := 0; i < 10; i++ {
+		a, b := first.jitterDelay(), second.jitterDelay()
+		if a != b {
+			t.Fatalf("Delay %d: Expected identical seeded delays, got %v and %v", i, a, b)
+		}
+		if a < 0 || a >= time.Second {
+			t.Fatalf("Delay %d: Expected a delay in [0, 1s), got %v", i, a)
+		}
+	}
+
+	if delay := (&PortScanner{}).jitterDelay(); delay != 0 {
+		t.Errorf("Expected no delay without Jitter, got %v", delay)
+	}
+}
+
+func TestWorkerTCP_Jitter(t *testing.T) {
+	const probes = 20
+	const jitter = 10 * time.Millisecond
+
+	dialer := &mockDialer{}
+	scanner := &PortScanner{Dial: dialer.Dial, Jitter: jitter, RandSeed: 1}
+	queue := make(chan Endpoint, probes)
+	for port := 1; port <= probes; port++ {
+		queue <- Endpoint{IP: "127.0.0.1", Port: port}
+	}
+	close(queue)
+	openPorts := make(chan service.ServiceVersion, probes)
+	done := make(chan bool, 1)
+
+	start := time.Now()
+	scanner.WorkerTCP(context.Background(), queue, openPorts, done)
+	average := time.Since(start) / probes
+
+	// Sleeping adds scheduling overhead, so allow some slack above the bound
+	if average <= 0 || average > jitter+5*time.Millisecond {
+		t.Errorf("Expected the average inter-probe delay within (0, %v], got %v", jitter, average)
+	}
+	if average < jitter/10 {
+		t.Errorf("Expected jitter to delay probes, average inter-probe delay was only %v", average)
+	}
+}
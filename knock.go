@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultKnockDelay is the pause after each knock, giving the knock
+	// daemon time to see it before the next knock or the probe.
+	DefaultKnockDelay = 100 * time.Millisecond
+	// DefaultKnockTimeout bounds each knock connect. A knock only needs its
+	// SYN to be sent, so the answer is not waited for long.
+	DefaultKnockTimeout = 200 * time.Millisecond
+)
+
+// knock sends a connect attempt to each port of KnockSequence on ip, in
+// order, before a TCP probe, so services behind port knocking are opened
+// for the scanner. The outcome of each knock is ignored.
+//
+// Timing: every knock waits at most KnockTimeout (or DefaultKnockTimeout)
+// for its connect and is followed by a pause of KnockDelay (or
+// DefaultKnockDelay), so each probe takes len(KnockSequence) times their
+// sum longer. Knock daemons usually expect the sequence within a few
+// seconds, and concurrent workers interleave their knocks, so knocking scans
+// should use a single worker.
+//
+// Parameters:
+// - ip: The IP address to knock on.
+func (t *PortScanner) knock(ip string) {
+	if len(t.KnockSequence) == 0 {
+		return
+	}
+	timeout := t.KnockTimeout
+	if timeout <= 0 {
+		timeout = DefaultKnockTimeout
+	}
+	delay := t.KnockDelay
+	if delay <= 0 {
+		delay = DefaultKnockDelay
+	}
+	dial := t.dial()
+	for _, port := range t.KnockSequence {
+		conn, err := dial("tcp", net.JoinHostPort(ip, strconv.Itoa(port)), timeout)
+		if err == nil {
+			conn.Close()
+		}
+		time.Sleep(delay)
+	}
+}
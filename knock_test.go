@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// knockDialer refuses every connect, except to protected once the knock
+// ports were dialed in order right before it.
+type knockDialer struct {
+	mu        sync.Mutex
+	sequence  []int
+	protected int
+	seen      []int
+}
+
+func (d *knockDialer) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	_, portText, _ := net.SplitHostPort(address)
+	port, _ := strconv.Atoi(portText)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if port == d.protected && len(d.seen) >= len(d.sequence) {
+		knocked := true
+		recent := d.seen[len(d.seen)-len(d.sequence):]
+		for i := range d.sequence {
+			knocked = knocked && recent[i] == d.sequence[i]
+		}
+		if knocked {
+			d.seen = nil
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+	}
+	d.seen = append(d.seen, port)
+	return nil, errors.New("connection refused")
+}
+
+func TestKnockSequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		sequence []int
+		expected string
+	}{
+		{"no knock", nil, "Closed"},
+		{"wrong order", []int{9000, 8000, 7000}, "Closed"},
+		{"correct sequence", []int{7000, 8000, 9000}, "Open"},
+	}
+	for _, test := range tests {
+		dialer := &knockDialer{sequence: []int{7000, 8000, 9000}, protected: 2222}
+		scanner := &PortScanner{
+			Dial:          dialer.Dial,
+			KnockSequence: test.sequence,
+			KnockDelay:    time.Millisecond,
+			ReportStates:  []string{"Open", "Closed"},
+		}
+		runTCPWorker(scanner, "127.0.0.1", []int{2222})
+		result := <-scanner.OpenPorts
+		if result.State != test.expected {
+			t.Errorf("%s: Expected port 2222 to be %s, got %s", test.name, test.expected, result.State)
+		}
+	}
+}
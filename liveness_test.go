@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScan_StopOnFirstOpen(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = allPorts()[:1000]
+	scanner.EnableTCP = true
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+	scanner.StopOnFirstOpen = true
+	scanner.Quiet = true
+
+	var probes int64
+	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		atomic.AddInt64(&probes, 1)
+		if port == 500 {
+			return StateOpen, nil
+		}
+		if port < 500 {
+			return StateClosed, nil
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Millisecond):
+		}
+		return StateClosed, nil
+	})
+
+	start := time.Now()
+	scanner.Scan()
+	elapsed := time.Since(start)
+
+	// The 500 ports after the open one would take 2.5s to probe
+	if elapsed > time.Second {
+		t.Errorf("Expected the scan to return right after the open port, took %s", elapsed)
+	}
+	if probes != 500 {
+		t.Errorf("Expected 500 probes up to the open port, got %d", probes)
+	}
+	report := scanner.Report()
+	if len(report.TCP) != 1 || report.TCP[0].Port != 500 {
+		t.Errorf("Expected only open port 500 in the results, got %+v", report.TCP)
+	}
+	if scanner.Truncated {
+		t.Errorf("Expected a liveness check not to be flagged as truncated")
+	}
+}
+
+func TestScan_StopOnFirstOpenAbandonsConnects(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = allPorts()[:20]
+	scanner.EnableTCP = true
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+	scanner.StopOnFirstOpen = true
+	scanner.Quiet = true
+	scanner.Timeout = 10 * time.Second
+	// Port 1 is open, every other connect hangs until it times out
+	scanner.Dial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		if strings.HasSuffix(address, ":1") {
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+		time.Sleep(timeout)
+		return nil, timeoutError{}
+	}
+
+	start := time.Now()
+	scanner.Scan()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the scan to return right after the open port, not after the connects in flight time out, took %s", elapsed)
+	}
+	if report := scanner.Report(); len(report.TCP) != 1 || report.TCP[0].Port != 1 {
+		t.Errorf("Expected only port 1 to be reported, got %+v", report.TCP)
+	}
+}
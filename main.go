@@ -1,10 +1,19 @@
 package main
 
 import (
+	"context"
 	"det/service"
+	"flag"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,7 +30,7 @@ import (
 //
 //	state := ScanPortTCP("192.168.1.1", 80)
 func ScanPortTCP(ip string, port int) string {
-	address := fmt.Sprintf("%s:%d", ip, port)
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
 	timeout := 10 * time.Second
 	conn, err := net.DialTimeout("tcp", address, timeout)
 	if err != nil {
@@ -31,36 +40,321 @@ func ScanPortTCP(ip string, port int) string {
 	return "Open"
 }
 
-// ScanPortUDP scans a UDP port on a given IP address to determine its state.
+// scanPortTCP scans a TCP port using the scanner's dialer and timeout settings.
+// When AdaptiveTimeout is enabled the round-trip time of every successful
+// connect is fed to the RTT estimator and used to size later timeouts.
+//
+// Parameters:
+// - ip: The IP address to scan.
+// - port: The TCP port to scan.
+//
+// Returns:
+// - A string indicating the state of the port: "Open", "Closed", or
+// "Filtered" when the connect timed out without an answer.
+//
+// Example:
+//
+//	state := scanner.scanPortTCP("192.168.1.1", 80)
+func (t *PortScanner) scanPortTCP(ip string, port int) string {
+	state, _, _ := t.connectTCP(context.Background(), ip, port)
+	return state
+}
+
+// connectTCP scans a TCP port like scanPortTCP, also returning the reason
+// for the state, e.g. "timeout" or "connection refused", and, if BannerWait
+// is set, the banner an open port sent before being closed. A timed out
+// connect is retried up to TCPRetries times with exponential backoff.
+// Cancelling ctx abandons the connect and its retries.
+func (t *PortScanner) connectTCP(ctx context.Context, ip string, port int) (string, string, string) {
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	dial := t.dialContext(ctx)
+	timeout := t.tcpTimeout()
+	start := time.Now()
+	conn, err := dial("tcp", address, timeout)
+	// Only timeouts are retried: a refused connection is a definitive answer
+	for retry := 0; retry < t.TCPRetries && isTimeout(err); retry++ {
+		select {
+		case <-ctx.Done():
+		case <-time.After(t.tcpRetryBackoff(retry)):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		timeout = t.tcpTimeout()
+		start = time.Now()
+		conn, err = dial("tcp", address, timeout)
+	}
+	if err != nil {
+		if refused, ok := err.(*ProxyRefusedError); ok {
+			return refused.proxyState(), ReasonFor(err), ""
+		}
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return "Filtered", ReasonFor(err), ""
+		}
+		return "Closed", ReasonFor(err), ""
+	}
+	if t.AdaptiveTimeout {
+		t.rtt.Observe(time.Since(start))
+	}
+	defer conn.Close()
+	// Zero closes the connection right away, as a plain connect scan does
+	var banner string
+	if t.BannerWait > 0 {
+		banner = readBanner(conn, t.BannerWait)
+	}
+	return "Open", ReasonConnected, banner
+}
+
+// tcpTimeout returns the timeout to use for the next TCP connect.
+func (t *PortScanner) tcpTimeout() time.Duration {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTCPTimeout
+	}
+	if t.rescanning {
+		return t.rescanTimeout()
+	}
+	if t.AdaptiveTimeout {
+		return t.rtt.Timeout(timeout, t.MinTimeout, t.MaxTimeout)
+	}
+	if t.FastConnect {
+		fast := t.FastConnectTimeout
+		if fast <= 0 {
+			fast = DefaultFastConnectTimeout
+		}
+		if fast < timeout {
+			return fast
+		}
+	}
+	return timeout
+}
+
+// dial returns the scanner's dial function. Dial takes precedence, then the
+// configured Dialer, falling back to net.DialTimeout. Connections are counted
+// and limited to MaxOpenConns (see trackDial).
+func (t *PortScanner) dial() DialFunc {
+	return t.dialContext(context.Background())
+}
+
+// dialContext returns the scanner's dial function as dial does, with dials
+// abandoned as soon as ctx is cancelled, so a cancelled scan does not wait
+// for connects in flight to time out.
+//
+// Parameters:
+// - ctx: The context cancelling the dials.
+//
+// Returns:
+// - The dial function.
+func (t *PortScanner) dialContext(ctx context.Context) DialFunc {
+	// The workers share the dial function built for their scan instead of
+	// wrapping a new one for every probe
+	if t.scanDial != nil && ctx == t.scanCtx {
+		return t.scanDial
+	}
+	return t.trackDial(ctx, t.baseDial(ctx))
+}
+
+// baseDial returns the configured dial function without connection
+// tracking, tunnelling TCP through Proxy when one is set.
+func (t *PortScanner) baseDial(ctx context.Context) DialFunc {
+	dial := t.directDial(ctx)
+	if t.Proxy == "" {
+		return dial
+	}
+	tunnel, err := DialHTTPConnect(dial, t.Proxy)
+	if err != nil {
+		return func(network, address string, timeout time.Duration) (net.Conn, error) {
+			return nil, err
+		}
+	}
+	return tunnel
+}
+
+// directDial returns the configured dial function, ignoring Proxy. With
+// AbortClose the dialer closes TCP connections with a RST. UDP dials get a
+// copy of the dialer whose LocalAddr, e.g. set by BindSource, is a UDP
+// address. A custom Dial, which takes no context, is abandoned on
+// cancellation (see cancelableDial).
+func (t *PortScanner) directDial(ctx context.Context) DialFunc {
+	if t.Dial != nil {
+		return cancelableDial(ctx, t.Dial)
+	}
+	dialer := t.Dialer
+	if t.AbortClose {
+		dialer = abortCloseDialer(dialer)
+	}
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	udpDialer := udpLocalDialer(dialer)
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if strings.HasPrefix(network, "udp") {
+			return udpDialer.DialContext(ctx, network, address)
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// dialed is the outcome of a dial run by cancelableDial.
+type dialed struct {
+	conn     net.Conn
+	err      error
+	panicked interface{}
+}
+
+// dialResults recycles the channels cancelableDial waits on, one per dial.
+var dialResults = sync.Pool{New: func() interface{} { return make(chan dialed, 1) }}
+
+// cancelableDial makes a dial function that takes no context return as soon
+// as ctx is cancelled. The abandoned dial finishes in the background and its
+// connection, if any, is closed. A panicking dial panics in the caller, as
+// it would unwrapped.
+//
+// Parameters:
+// - ctx: The context cancelling the dials.
+// - dial: The dial function to wrap.
+//
+// Returns:
+// - The wrapped dial function, or dial itself if ctx is never cancelled.
+func cancelableDial(ctx context.Context, dial DialFunc) DialFunc {
+	if ctx.Done() == nil {
+		return dial
+	}
+	return func(network, address string, timeout time.Duration) (net.Conn, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := dialResults.Get().(chan dialed)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					result <- dialed{panicked: p}
+				}
+			}()
+			conn, err := dial(network, address, timeout)
+			result <- dialed{conn: conn, err: err}
+		}()
+		select {
+		case r := <-result:
+			dialResults.Put(result)
+			if r.panicked != nil {
+				panic(r.panicked)
+			}
+			return r.conn, r.err
+		case <-ctx.Done():
+			go func() {
+				if r := <-result; r.conn != nil {
+					r.conn.Close()
+				}
+				dialResults.Put(result)
+			}()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ScanUDP scans a UDP port on a given IP address to determine its state.
+// Well-known ports are sent a protocol-specific payload (see UDPPayload).
 //
 // Parameters:
 // - port: The UDP port to scan.
 // - domain: The domain or IP address to scan.
 //
 // Returns:
-// - An error if the scan fails, otherwise nil.
+// - An error if the scan fails, otherwise nil. A probe takes at most
+// DefaultUDPWriteTimeout plus DefaultUDPReadTimeout.
 //
 // Example:
 //
-//	err := scanUDP(53, "example.com")
+//	err := ScanUDP(53, "example.com")
 func ScanUDP(port int, domain string) error {
-	address := fmt.Sprintf("%s:%d", domain, port)
-	conn, err := net.DialTimeout("udp", address, 5*time.Second)
+	return scanUDPWith(context.Background(), net.DialTimeout, nil, port, domain, DefaultUDPWriteTimeout, DefaultUDPReadTimeout)
+}
+
+// scanUDP scans a UDP port using the scanner's dial function.
+//
+// Parameters:
+// - ctx: Cancelling it ends the probe at once.
+// - port: The UDP port to scan.
+// - domain: The domain or IP address to scan.
+//
+// Returns:
+// - An error if the scan fails, otherwise nil.
+func (t *PortScanner) scanUDP(ctx context.Context, port int, domain string) error {
+	writeTimeout, readTimeout := t.udpTimeouts()
+	return scanUDPWith(ctx, t.dialContext(ctx), t.unreachable, port, domain, writeTimeout, readTimeout)
+}
+
+// udpTimeouts returns the UDP write and read timeouts, applying the defaults.
+func (t *PortScanner) udpTimeouts() (time.Duration, time.Duration) {
+	writeTimeout, readTimeout := t.UDPWriteTimeout, t.UDPReadTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = DefaultUDPWriteTimeout
+	}
+	if readTimeout <= 0 {
+		readTimeout = DefaultUDPReadTimeout
+	}
+	return writeTimeout, readTimeout
+}
+
+// scanUDPWith sends a UDP probe through dial and waits for a response. The
+// dial and write share writeTimeout, and the whole probe is bounded by
+// writeTimeout plus readTimeout: time the dial and write did not use is left
+// for the read, but time they overran is taken from it. If watcher is not nil,
+// an ICMP port-unreachable for the probe ends the read at once with
+// ErrPortUnreachable. Cancelling ctx ends the read at once with ctx's error.
+func scanUDPWith(ctx context.Context, dial DialFunc, watcher *UnreachableWatcher, port int, domain string, writeTimeout, readTimeout time.Duration) error {
+	start := time.Now()
+	address := net.JoinHostPort(domain, strconv.Itoa(port))
+	conn, err := dial("udp", address, writeTimeout)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	// Send a custom message
-	_, err = conn.Write([]byte("ping"))
+	// Wait for a response until the probe's total budget is spent. The
+	// deadline and the watch are set before writing, so an unreachable
+	// arriving before the read still ends it
+	conn.SetReadDeadline(start.Add(writeTimeout + readTimeout))
+	var unreachable int32
+	local, localOK := conn.LocalAddr().(*net.UDPAddr)
+	remote, remoteOK := conn.RemoteAddr().(*net.UDPAddr)
+	if watcher != nil && localOK && remoteOK {
+		stop := watcher.Watch(local, remote, func() {
+			atomic.StoreInt32(&unreachable, 1)
+			conn.SetReadDeadline(time.Now())
+		})
+		defer stop()
+	}
+
+	// Send the payload the service on this port is most likely to answer
+	conn.SetWriteDeadline(start.Add(writeTimeout))
+	_, err = conn.Write(UDPPayload(port))
 	if err != nil {
 		return err
 	}
 
-	// Set a deadline for reading a response
-	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if done := ctx.Done(); done != nil {
+		finished := make(chan struct{})
+		defer close(finished)
+		go func() {
+			select {
+			case <-done:
+				conn.SetReadDeadline(time.Now())
+			case <-finished:
+			}
+		}()
+	}
 	buf := make([]byte, 1024)
 	_, err = conn.Read(buf)
+	if atomic.LoadInt32(&unreachable) == 1 {
+		return ErrPortUnreachable
+	}
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		return err
 	}
@@ -69,81 +363,409 @@ func ScanUDP(port int, domain string) error {
 }
 
 // ScanICMP scans an IP address using ICMP to determine its reachability.
+// An echo request is sent and the reply is inspected, so an administratively
+// prohibited error is told apart from a host that does not answer.
 //
 // Parameters:
 // - ip: The IP address to scan.
 //
 // Returns:
-// - A string indicating whether the IP is "Reachable" or "Unreachable".
+// - A string indicating whether the IP is "Reachable", "Unreachable",
+// "Unreachable (no reply)" or "Filtered (admin prohibited)".
 //
 // Example:
 //
 //	state := ScanICMP("192.168.1.1")
 func ScanICMP(ip string) string {
-	timeout := 5 * time.Second
-	conn, err := net.DialTimeout("ip4:icmp", ip, timeout)
+	result, _ := pingICMP("0.0.0.0", ip, DefaultICMPPayloadSize, DefaultICMPTimeout)
+	return result.State
+}
+
+// scanICMP pings an IP address from the scanner's bound source address with
+// the configured payload size, through the ICMP socket shared by the scan
+// when one is open. If the ICMP socket cannot be opened, e.g.
+// without root, reachability is checked with TCP connects instead.
+//
+// Parameters:
+// - ip: The IP address to scan.
+//
+// Returns:
+// - The ICMPResult with the reachability state, RTT and TTL.
+func (t *PortScanner) scanICMP(ip string) ICMPResult {
+	src := "0.0.0.0"
+	if t.source != nil {
+		src = t.source.String()
+	}
+	size := t.ICMPPayloadSize
+	if size <= 0 {
+		size = DefaultICMPPayloadSize
+	}
+	var result ICMPResult
+	var err error
+	if t.icmpConn != nil {
+		result, err = t.icmpConn.Ping(ip, size, t.icmpTimeout())
+	} else {
+		result, err = pingICMP(src, ip, size, t.icmpTimeout())
+	}
 	if err != nil {
-		return "Unreachable"
+		// Without raw sockets, fall back to TCP connects rather than
+		// reporting every host as unreachable
+		t.warnUnprivileged(err)
+		return t.dialReachability(ip, t.icmpTimeout())
 	}
-	conn.Close()
-	return "Reachable"
+	return result
+}
+
+// Endpoint identifies a single IP and port pair to probe.
+//
+// Fields:
+// - IP: The IP address to scan.
+// - Port: The port to scan on that IP.
+type Endpoint struct {
+	IP   string
+	Port int
 }
 
-// WorkerTCP scans TCP ports and sends results to channels.
+// Interleave builds the probe order for a scan, visiting the IPs round-robin
+// for every port so that each target makes progress concurrently instead of
+// one slow host holding back all the others.
 //
 // Parameters:
-// - ip: The IP address to scan.
-// - ports: A channel for ports to scan.
-// - results: A channel to send the scan results to.
-// - openPorts: A channel to send open port information to.
+// - ips: The IP addresses to scan.
+// - ports: The ports to scan on each IP.
+//
+// Returns:
+// - The endpoints in enqueue order.
+//
+// Example:
+//
+//	endpoints := Interleave([]string{"10.0.0.1", "10.0.0.2"}, []int{22, 80})
+//	// 10.0.0.1:22, 10.0.0.2:22, 10.0.0.1:80, 10.0.0.2:80
+func Interleave(ips []string, ports []int) []Endpoint {
+	endpoints := make([]Endpoint, 0, len(ips)*len(ports))
+	for _, port := range ports {
+		for _, ip := range ips {
+			endpoints = append(endpoints, Endpoint{IP: ip, Port: port})
+		}
+	}
+	return endpoints
+}
+
+// WorkerTCP scans TCP endpoints with the scanner's TCPProber and sends
+// reported ports to openPorts. Once ctx is cancelled the remaining
+// endpoints are drained without being probed. While the scan is paused
+// (see Pause) no new endpoint is probed.
+//
+// Parameters:
+// - ctx: The context that stops further probing when cancelled.
+// - ports: A channel for endpoints to scan.
+// - openPorts: A channel to send reported port information to.
 // - done: A channel to signal the completion of the work.
-// - services: A map of known services.
 //
 // Example:
 //
-//	go WorkerTCP("192.168.1.1", ports, results, openPorts, done, services)
-func WorkerTCP(ip string, ports, results chan int, openPorts chan service.ServiceVersion, done chan bool, services map[int]string) {
-	for port := range ports {
-		state := ScanPortTCP(ip, port)
-		service := service.DetectService(port, services)
-		results <- port
-		if state == "Open" {
-			openPorts <- service
+//	go scanner.WorkerTCP(ctx, ports, openPorts, done)
+func (t *PortScanner) WorkerTCP(ctx context.Context, ports chan Endpoint, openPorts chan service.ServiceVersion, done chan bool) {
+	atomic.AddInt64(&t.activeWorkers, 1)
+	for endpoint := range ports {
+		t.waitResumed(ctx)
+		if ctx.Err() == nil {
+			t.probeEndpointTCP(ctx, endpoint, openPorts)
 		}
-		fmt.Printf("Port %d: %s, Service: %s, Response: %s\n", port, state, service.Service, service.Response)
+		atomic.AddInt64(&t.completed, 1)
 	}
+	atomic.AddInt64(&t.activeWorkers, -1)
 	done <- true
 }
 
-// WorkerUDP scans UDP ports and sends results to channels.
+// probeEndpointTCP scans one TCP endpoint and reports the result. A panic
+// while probing is recovered and reported as an "Error" result so the worker
+// keeps running.
 //
 // Parameters:
-// - domain: The domain or IP address to scan.
-// - ports: A channel for ports to scan.
-// - results: A channel to send the scan results to.
-// - openPorts: A channel to send open port information to.
+// - ctx: The context that interrupts the jitter wait when cancelled.
+// - endpoint: The endpoint to scan.
+// - openPorts: A channel to send reported port information to.
+func (t *PortScanner) probeEndpointTCP(ctx context.Context, endpoint Endpoint, openPorts chan service.ServiceVersion) {
+	port := endpoint.Port
+	defer t.recoverProbe(endpoint, "TCP", openPorts)
+	if t.overBudget(endpoint.IP) {
+		t.skipOverBudget(endpoint, "TCP", openPorts)
+		return
+	}
+
+	t.waitJitter(ctx)
+	defer t.chargeBudget(endpoint.IP, time.Now())
+	t.knock(endpoint.IP)
+	prober := t.tcpProber()
+	state, detail, failure := probe(ctx, prober, endpoint)
+	state, detail = t.confirmOpen(ctx, prober, endpoint, state, detail)
+	if state != "Open" && ctx.Err() != nil {
+		// A probe cut short by cancellation says nothing about the port
+		return
+	}
+	service := t.detectService(port, "TCP")
+	service.Timestamp = time.Now()
+	service.IP = endpoint.IP
+	service.Protocol = "TCP"
+	service.State = state
+	service.Banner = detail.Banner
+	service.Reason = detail.Reason
+	if failure != "" {
+		service.Response = failure
+	}
+	if state == "Filtered" && t.deferFiltered(endpoint, service) {
+		return
+	}
+	if state == "Open" {
+		service.Severity = t.severity(port)
+		service.HTTP = t.probeHTTP(endpoint.IP, port)
+		service.Backends = t.fingerprintBackends(endpoint.IP, port)
+		service.MultipleBackends = len(service.Backends) > 1
+		service.Vuln = t.knownVuln(service)
+	}
+	atomic.AddInt64(&t.tcpScanned, 1)
+	if state == "Open" {
+		atomic.AddInt64(&t.tcpOpen, 1)
+		t.notifyOpen(service)
+		if t.recordOpen() {
+			t.sendResult(openPorts, service)
+		}
+	} else if t.reportsState(state) {
+		t.sendResult(openPorts, service)
+	}
+	t.countState(state)
+	if t.logsPorts() {
+		t.logPort("%s Port %d: %s, Service: %s, Response: %s\n", endpoint.IP, port, state, service.Service, service.Response)
+	}
+}
+
+// WorkerUDP scans UDP endpoints with the scanner's UDPProber and sends
+// reported ports to openPorts. Once ctx is cancelled the remaining
+// endpoints are drained without being probed. While the scan is paused
+// (see Pause) no new endpoint is probed.
+//
+// Parameters:
+// - ctx: The context that stops further probing when cancelled.
+// - ports: A channel for endpoints to scan.
+// - openPorts: A channel to send reported port information to.
 // - done: A channel to signal the completion of the work.
-// - services: A map of known services.
 //
 // Example:
 //
-//	go WorkerUDP("example.com", ports, results, openPorts, done, services)
-func WorkerUDP(domain string, ports, results chan int, openPorts chan service.ServiceVersion, done chan bool, services map[int]string) {
-	for port := range ports {
-		err := ScanUDP(port, domain)
-		state := "Closed"
-		if err == nil {
-			state = "Open"
-			service := service.DetectService(port, services)
-			openPorts <- service
+//	go scanner.WorkerUDP(ctx, ports, openPorts, done)
+func (t *PortScanner) WorkerUDP(ctx context.Context, ports chan Endpoint, openPorts chan service.ServiceVersion, done chan bool) {
+	atomic.AddInt64(&t.activeWorkers, 1)
+	for endpoint := range ports {
+		t.waitResumed(ctx)
+		if ctx.Err() == nil {
+			t.probeEndpointUDP(ctx, endpoint, openPorts)
 		}
-		results <- port
-		fmt.Printf("Port %d (UDP): %s\n", port, state)
+		atomic.AddInt64(&t.completed, 1)
 	}
+	atomic.AddInt64(&t.activeWorkers, -1)
 	done <- true
 }
 
-// WorkerICMP scans IP addresses for ICMP reachability.
+// probeEndpointUDP scans one UDP endpoint and reports the result. A panic
+// while probing is recovered and reported as an "Error" result so the worker
+// keeps running.
+//
+// Parameters:
+// - ctx: The context that interrupts the jitter wait when cancelled.
+// - endpoint: The endpoint to scan.
+// - openPorts: A channel to send reported port information to.
+func (t *PortScanner) probeEndpointUDP(ctx context.Context, endpoint Endpoint, openPorts chan service.ServiceVersion) {
+	port := endpoint.Port
+	defer t.recoverProbe(endpoint, "UDP", openPorts)
+	if t.overBudget(endpoint.IP) {
+		t.skipOverBudget(endpoint, "UDP", openPorts)
+		return
+	}
+
+	t.waitJitter(ctx)
+	defer t.chargeBudget(endpoint.IP, time.Now())
+	prober := t.udpProber()
+	state, detail, failure := probe(ctx, prober, endpoint)
+	state, detail = t.confirmOpen(ctx, prober, endpoint, state, detail)
+	if state != "Open" && ctx.Err() != nil {
+		// A probe cut short by cancellation says nothing about the port
+		return
+	}
+	service := t.detectService(port, "UDP")
+	service.Timestamp = time.Now()
+	service.IP = endpoint.IP
+	service.Protocol = "UDP"
+	service.State = state
+	service.Banner = detail.Banner
+	service.Reason = detail.Reason
+	if failure != "" {
+		service.Response = failure
+	}
+	if state == "Open" {
+		service.Severity = t.severity(port)
+		service.Vuln = t.knownVuln(service)
+		t.notifyOpen(service)
+		if t.recordOpen() {
+			t.sendResult(openPorts, service)
+		}
+	} else if t.reportsState(state) {
+		t.sendResult(openPorts, service)
+	}
+	t.countState(state)
+	if t.logsPorts() {
+		t.logPort("%s Port %d (UDP): %s\n", endpoint.IP, port, state)
+	}
+}
+
+// recoverProbe recovers from a panic raised while probing an endpoint, logs
+// it and reports the port with the "Error" state. It must be deferred.
+//
+// Parameters:
+// - endpoint: The endpoint being probed.
+// - protocol: The protocol being probed, "TCP" or "UDP".
+// - openPorts: A channel to send the error result to.
+func (t *PortScanner) recoverProbe(endpoint Endpoint, protocol string, openPorts chan service.ServiceVersion) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	t.countState("Error")
+	t.logf("Recovered from panic while probing %s port %d (%s): %v\n", endpoint.IP, endpoint.Port, protocol, r)
+	t.sendResult(openPorts, service.ServiceVersion{
+		IP:        endpoint.IP,
+		Port:      endpoint.Port,
+		Protocol:  protocol,
+		Service:   "Unknown",
+		Response:  fmt.Sprintf("panic: %v", r),
+		State:     "Error",
+		Reason:    "panic",
+		Timestamp: time.Now(),
+	})
+}
+
+// detectService identifies the service on a port from the services of its
+// protocol (see protocolServices), or from Services when ServicesByProtocol
+// is nil, with ServiceOverrides winning for every protocol. It matches
+// DetectService on service.MergeServices(t.Services, t.ServiceOverrides)
+// without copying the maps for every probe.
+//
+// Parameters:
+// - port: The scanned port.
+// - protocol: The protocol the port is scanned over, "TCP" or "UDP".
+//
+// Returns:
+// - The detected service.
+func (t *PortScanner) detectService(port int, protocol string) service.ServiceVersion {
+	if _, ok := t.ServiceOverrides[port]; ok {
+		return service.DetectService(port, t.ServiceOverrides)
+	}
+	services := t.byProtocol
+	if services == nil {
+		services = t.protocolServices()
+	}
+	if services != nil {
+		return service.DetectServiceProtocol(port, protocol, services)
+	}
+	return service.DetectService(port, t.Services)
+}
+
+// protocolServices returns the services by protocol a scan detects with.
+// A custom Services map left with the default ServicesByProtocol stays
+// authoritative, as it is for "-ports known": it is migrated with
+// service.FromFlat instead of being ignored.
+//
+// Returns:
+// - The services by protocol, nil if ServicesByProtocol is nil.
+func (t *PortScanner) protocolServices() service.ProtocolServices {
+	if t.ServicesByProtocol == nil {
+		return nil
+	}
+	if sameMap(t.ServicesByProtocol, service.ServicesByProtocol) && !sameMap(t.Services, service.Services) {
+		return service.FromFlat(t.Services)
+	}
+	return t.ServicesByProtocol
+}
+
+// sameMap reports whether two maps are the same map, not merely equal.
+func sameMap(a, b interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// reportsState reports whether results in the given state belong in the report.
+//
+// Parameters:
+// - state: The scanned state of a port.
+//
+// Returns:
+// - true if state is one of ReportStates, or "Open" when ReportStates is empty.
+func (t *PortScanner) reportsState(state string) bool {
+	if len(t.ReportStates) == 0 {
+		return state == "Open"
+	}
+	for _, s := range t.ReportStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// logf prints a progress message unless the scanner is quiet.
+//
+// Parameters:
+// - format: The fmt.Printf format.
+// - args: The values to format.
+func (t *PortScanner) logf(format string, args ...interface{}) {
+	if !t.Quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// notifyOpen calls OnOpenPort, if set, for a port found open.
+//
+// Parameters:
+// - svc: The open port.
+func (t *PortScanner) notifyOpen(svc service.ServiceVersion) {
+	if t.OnOpenPort != nil {
+		t.OnOpenPort(svc)
+	}
+}
+
+// recordOpen counts an open port found by a worker and stops the scan once
+// MaxResults open ports have been found, or the first one with
+// StopOnFirstOpen.
+//
+// Returns:
+// - true if the open port should be reported, false if it exceeds the limit
+// or "Open" is not one of ReportStates.
+func (t *PortScanner) recordOpen() bool {
+	found := atomic.AddInt64(&t.openFound, 1)
+	if !t.reportsState("Open") {
+		return false
+	}
+	limit := t.resultLimit()
+	if limit <= 0 {
+		return true
+	}
+	if found >= int64(limit) && t.cancel != nil {
+		t.cancel()
+	}
+	return found <= int64(limit)
+}
+
+// resultLimit returns the number of open ports after which the scan stops:
+// 1 with StopOnFirstOpen, else MaxResults, where 0 means no limit.
+func (t *PortScanner) resultLimit() int {
+	if t.StopOnFirstOpen {
+		return 1
+	}
+	return t.MaxResults
+}
+
+// WorkerICMP scans IP addresses for ICMP reachability, pinging each
+// ICMPCount times, and guesses each IP's operating system from the TTL of
+// its reply.
 //
 // Parameters:
 // - ips: A channel for IP addresses to scan.
@@ -152,13 +774,19 @@ func WorkerUDP(domain string, ports, results chan int, openPorts chan service.Se
 //
 // Example:
 //
-//	go WorkerICMP(ips, results, done)
-func WorkerICMP(ips <-chan string, results chan<- string, done chan<- bool) {
+//	go scanner.WorkerICMP(ips, results, done)
+func (t *PortScanner) WorkerICMP(ips <-chan string, results chan<- ICMPResult, done chan<- bool) {
+	atomic.AddInt64(&t.activeWorkers, 1)
 	for ip := range ips {
-		state := ScanICMP(ip)
-		results <- fmt.Sprintf("IP: %s, Response: %s", ip, state)
-		fmt.Printf("IP: %s, Response: %s\n", ip, state)
+		result := t.pingRepeatedly(ip)
+		if result.OSGuess == "" {
+			result.OSGuess = GuessOS(result.TTL)
+		}
+		results <- result
+		t.emit(ScanEvent{Type: EventICMP, ICMP: &result})
+		t.logPort("%s\n", result)
 	}
+	atomic.AddInt64(&t.activeWorkers, -1)
 	done <- true
 }
 
@@ -166,16 +794,101 @@ func WorkerICMP(ips <-chan string, results chan<- string, done chan<- bool) {
 //
 // Fields:
 // - Domain: The domain to scan.
+// - Resolution: The DNS details gathered when the domain was resolved.
 // - IPs: A list of resolved IP addresses for the domain.
 // - Ports: A list of ports to scan.
 // - NumWorkers: The number of worker goroutines to use for scanning.
-// - PortChannel: A channel for distributing ports to workers.
-// - ResultChannel: A channel for receiving scan results.
-// - OpenPorts: A channel for open TCP port information.
-// - OpenPortsUDP: A channel for open UDP port information.
+// - AutoWorkers: Whether Scan runs with a count computed from the CPUs and the scan size instead of NumWorkers, which is left as configured (see AutoWorkerCount).
+// - TCPPortChannel: A channel for distributing endpoints to TCP workers.
+// - UDPPortChannel: A channel for distributing endpoints to UDP workers.
+// - OpenPorts: A channel for reported TCP port information (open ports, by default), moved into the report by Scan as results arrive (see collect).
+// - OpenPortsUDP: A channel for reported UDP port information (open ports, by default), moved into the report like OpenPorts.
 // - ICMPResults: A channel for ICMP reachability results.
 // - Done: A channel to signal the completion of all workers.
-// - Services: A map of known services.
+// - Services: A map of known services, used for every protocol when ServicesByProtocol is nil or left as the default.
+// - ServicesByProtocol: The known services of each protocol, replacing Services once set to a custom map (see service.FromFlat).
+// - ServiceOverrides: User service names that win over Services, e.g. an internal app on port 9000.
+// - Timeout: The TCP connect timeout (defaults to DefaultTCPTimeout).
+// - TCPRetries: The number of times a timed out TCP connect is retried; refused connects are never retried.
+// - TCPRetryBackoff: The wait before the first TCP retry, doubled for each further one (defaults to DefaultTCPRetryBackoff).
+// - TCPRetryJitter: The upper bound of a random delay added to each TCP retry backoff, spreading out retries; reproducible when RandSeed is set.
+// - AdaptiveTimeout: Whether to derive TCP timeouts from observed round-trip times.
+// - MinTimeout: The lower bound for adaptive timeouts.
+// - MaxTimeout: The upper bound for adaptive timeouts.
+// - Dial: The function used to open TCP connections (defaults to net.DialTimeout).
+// - Dialer: A reusable dialer used when Dial is nil, e.g. to bind a local address or set socket options.
+// - AbortClose: Whether TCP probe connections are closed with a RST (SO_LINGER 0) instead of a FIN, leaving no TIME_WAIT; ignored when Dial is set.
+// - Proxy: The URL of an HTTP proxy TCP probes are tunnelled through with CONNECT, e.g. "http://proxy.example:3128".
+// - ProxyThreshold: The fraction of open TCP ports above which a transparent proxy is suspected (0 disables the check).
+// - DowngradeProxied: Whether to report open TCP ports as "Filtered" when a proxy is suspected.
+// - ProxySuspected: Set by Scan when the open fraction exceeded ProxyThreshold.
+// - UDPWriteTimeout: The timeout for dialing and sending a UDP probe (defaults to DefaultUDPWriteTimeout).
+// - SNMPCommunities: The community strings tried when probing UDP port 161 with SNMP (defaults to DefaultSNMPCommunities).
+// - UDPReadTimeout: The time a UDP probe waits for a response (defaults to DefaultUDPReadTimeout).
+// - FastConnect: Whether to run a large batch of short-timeout TCP connects concurrently (see fastconnect.go).
+// - FastConnectBatch: The number of concurrent connects in FastConnect mode.
+// - FastConnectTimeout: The TCP connect timeout in FastConnect mode.
+// - HTTPProbe: Whether open HTTP ports are probed for status, headers and title.
+// - HTTPPorts: The ports probed over HTTP, mapped to whether they use TLS.
+// - VHosts: Host header values the HTTP probe is repeated with, to find name-based virtual hosts on the IP (see HTTPInfo.VHosts).
+// - ICMPCount: The number of pings per IP; above 1 the report includes loss and RTT statistics (defaults to 1).
+// - ICMPPayloadSize: The ICMP echo payload size in bytes (defaults to DefaultICMPPayloadSize).
+// - ICMPTimeout: How long a ping waits for a reply (defaults to DefaultICMPTimeout).
+// - Jitter: The upper bound of a random delay inserted before each TCP and UDP probe.
+// - RandSeed: The seed for the random jitter, making delays reproducible when non-zero.
+// - RandomizeOrder: Whether endpoints are probed in a random order, reproducible when RandSeed is set.
+// - Order: The strategy ordering the ports before they are enqueued, e.g. ServicePriorityOrder; the given order when nil.
+// - AddressFamily: Which resolved addresses to scan (defaults to FamilyAny).
+// - ExcludePorts: Ports that must never be probed.
+// - ExcludeIPs: IP addresses or CIDR ranges that must never be probed.
+// - AppendOutput: Whether writeResultsToFile appends to the output file instead of overwriting it.
+// - SourceIP: The local IP address scans originate from (applied by BindSource).
+// - Interface: The local interface scans originate from when SourceIP is empty (applied by BindSource).
+// - ReportStates: The port states included in the report (defaults to "Open").
+// - MaxResults: The number of open ports after which the scan is stopped (0 means no limit).
+// - StopOnFirstOpen: Whether the scan stops at the first open port, reporting only it, e.g. to check a host is alive.
+// - Truncated: Set by Scan when it stopped early because MaxResults was reached.
+// - Cancelled: Set by ScanContext when its context was cancelled, or IdleTimeout ran out, before the scan completed.
+// - IdleTimeout: How long the scan may go without any port answering, open or closed, before it is aborted, e.g. when the network went down (0 means no limit).
+// - TestMode: For testing only: whether probes are skipped and synthetic results reported instead, with no network I/O (see ForceOpen).
+// - ForceOpen: The TCP ports TestMode reports as open; every other port is reported closed.
+// - Quiet: Whether progress output is suppressed.
+// - Unique: Whether writeResultsToFile collapses a port with the same service and state on several IPs into one line.
+// - GeoLookup: An optional hook annotating each scanned IP with its ASN and country in the report.
+// - ReverseDNS: Whether each scanned IP is looked up in reverse DNS and shown with its host names in the report.
+// - PTRResolver: The resolver used for ReverseDNS (defaults to a cached net.DefaultResolver, or DNSServer if set).
+// - DNSServer: The DNS server, as host:port, the target was resolved through instead of the system's, set by NewTargetWithDNSServer.
+// - OnResult: Called from the workers with every reported result as it arrives, e.g. by an NDJSONWriter. Like OnOpenPort it must be fast.
+// - OnComplete: Called with the report once the scan has finished, e.g. a WebhookNotifier. An error it returns is logged and kept in Errors without failing the scan.
+// - OnOpenPort: Called from the worker as soon as a port is found open, before the scan completes. It blocks the worker, so it must be fast or hand slow work such as a webhook off to another goroutine.
+// - MaxOpenConns: The maximum number of probe connections open at once; new dials block above it (0 means no limit).
+// - TCPProber: The probe technique for TCP ports (defaults to ConnectProber).
+// - UDPProber: The probe technique for UDP ports (defaults to DatagramProber).
+// - FastUDPClose: Whether to listen for ICMP port-unreachable messages and report closed UDP ports as soon as one arrives (needs root).
+// - CongestionControl: Whether the number of UDP probes in flight adapts to packet loss (AIMD).
+// - IncrementalOutput: A file every reported port is appended to as soon as it is found, so a crash keeps partial results.
+// - IncrementalSync: How often IncrementalOutput is synced to disk (defaults to DefaultIncrementalSync).
+// - PerHostBudget: The probing time any single IP may use, summed over the workers probing it; once used up, its remaining endpoints are skipped as "Unscanned (budget)" (0 means no limit).
+// - Errors: The non-fatal errors met during the scan, each a *ScanError, e.g. failed reverse DNS lookups.
+// - Fingerprint: The number of extra connections made to each open TCP port to fingerprint its backends; differing banners or TLS certificates flag a load balancer (0 disables it).
+// - KnockSequence: Ports knocked on with a connect attempt, in order, before every TCP probe, for services behind port knocking (see knock).
+// - KnockDelay: The pause after each knock (defaults to DefaultKnockDelay).
+// - KnockTimeout: The connect timeout of each knock (defaults to DefaultKnockTimeout).
+// - BannerWait: How long an open TCP port is kept open to read a banner the service sends unasked, e.g. an SSH greeting (0 closes it immediately).
+// - UnixSocket: The path of a Unix domain socket scanned instead of IPs and ports, set by NewTarget for a "unix:///path" target.
+// - RiskyPorts: The severity of open ports, replacing service.RiskyPorts (ports not listed are Info).
+// - FlagKnownVulns: Whether open ports whose banner names a known vulnerable version are flagged with a note in Vuln.
+// - KnownVulns: The banner fragments and notes FlagKnownVulns matches, replacing service.KnownVulns.
+// - RescanFiltered: Whether the TCP ports found filtered are probed a second time after the first pass, promoting those that answer then (see rescanFiltered).
+// - RescanTimeout: The connect timeout of the second pass (defaults to DefaultRescanTimeout).
+// - EnableTCP: Whether TCP ports are scanned (NewTarget enables it; with none of the three enabled all are scanned).
+// - EnableUDP: Whether UDP ports are scanned, the slowest protocol (NewTarget enables it).
+// - EnableICMP: Whether the IPs are pinged (NewTarget enables it).
+// - ConfirmOpen: Whether every port found open is probed a second time and only reported Open if both probes agree, otherwise Filtered (see confirmOpen).
+// - SummaryOnly: Whether the output file and the progress output hold only the counts of ports by state (see Summary) instead of a line per port.
+// - MaxHosts: The most IPs a CIDR target may expand to, set by NewCIDRTarget (see DefaultMaxHosts).
+// - PcapFile: A pcap file the packets of the raw-socket probes are captured to, i.e. the ICMP echo requests and the ICMP messages received; needs root, as raw sockets do.
+// - Streaming: Whether results are only delivered to OnResult instead of being kept for the report, bounding memory to the workers (see NewStreamingTarget).
 //
 // Example:
 //
@@ -184,63 +897,312 @@ func WorkerICMP(ips <-chan string, results chan<- string, done chan<- bool) {
 //	    IPs:         []string{"192.168.1.1"},
 //	    Ports:       []int{80, 443},
 //	    NumWorkers:  10,
-//	    PortChannel: make(chan int),
+//	    TCPPortChannel: make(chan Endpoint),
+//	    UDPPortChannel: make(chan Endpoint),
 //	    // ...
 //	}
 type PortScanner struct {
-	Domain        string
-	IPs           []string
-	Ports         []int
-	NumWorkers    int
-	PortChannel   chan int
-	ResultChannel chan int
-	OpenPorts     chan service.ServiceVersion
-	OpenPortsUDP  chan service.ServiceVersion
-	ICMPResults   chan string
-	Done          chan bool
-	Services      map[int]string
+	Domain         string
+	Resolution     Resolution
+	IPs            []string
+	Ports          []int
+	NumWorkers     int
+	AutoWorkers    bool
+	TCPPortChannel chan Endpoint
+	UDPPortChannel chan Endpoint
+	OpenPorts      chan service.ServiceVersion
+	OpenPortsUDP   chan service.ServiceVersion
+	ICMPResults    chan ICMPResult
+	Done           chan bool
+	Services       map[int]string
+
+	ServicesByProtocol service.ProtocolServices
+	ServiceOverrides   map[int]string
+
+	Timeout         time.Duration
+	TCPRetries      int
+	TCPRetryBackoff time.Duration
+	TCPRetryJitter  time.Duration
+	AdaptiveTimeout bool
+	MinTimeout      time.Duration
+	MaxTimeout      time.Duration
+	Dial            DialFunc
+	Dialer          *net.Dialer
+	AbortClose      bool
+	Proxy           string
+
+	ProxyThreshold   float64
+	DowngradeProxied bool
+	ProxySuspected   bool
+
+	UDPWriteTimeout time.Duration
+	UDPReadTimeout  time.Duration
+	SNMPCommunities []string
+
+	FastConnect        bool
+	FastConnectBatch   int
+	FastConnectTimeout time.Duration
+
+	HTTPProbe bool
+	HTTPPorts map[int]bool
+	VHosts    []string
+
+	ICMPPayloadSize int
+	ICMPCount       int
+	ICMPTimeout     time.Duration
+
+	Jitter         time.Duration
+	RandSeed       int64
+	RandomizeOrder bool
+	Order          OrderStrategy
+
+	AddressFamily AddressFamily
+
+	ExcludePorts []int
+	ExcludeIPs   []string
+
+	AppendOutput bool
+
+	SourceIP  string
+	Interface string
+
+	ReportStates []string
+
+	MaxResults      int
+	StopOnFirstOpen bool
+	Truncated       bool
+	Cancelled       bool
+
+	IdleTimeout time.Duration
+
+	TestMode  bool
+	ForceOpen []int
+
+	Quiet  bool
+	Unique bool
+
+	GeoLookup   GeoLookupFunc
+	ReverseDNS  bool
+	PTRResolver AddrResolver
+	DNSServer   string
+
+	OnResult   func(ScanEvent)
+	OnOpenPort func(service.ServiceVersion)
+	OnComplete func(ScanReport) error
+
+	MaxOpenConns int
+
+	TCPProber Prober
+	UDPProber Prober
+
+	FastUDPClose      bool
+	CongestionControl bool
+	Streaming         bool
+
+	PerHostBudget time.Duration
+
+	Errors []error
+
+	Fingerprint int
+
+	IncrementalOutput string
+	IncrementalSync   time.Duration
+
+	KnockSequence []int
+	KnockDelay    time.Duration
+	KnockTimeout  time.Duration
+
+	BannerWait time.Duration
+	UnixSocket string
+
+	RiskyPorts map[int]service.Severity
+	PcapFile   string
+
+	FlagKnownVulns bool
+	KnownVulns     map[string]string
+
+	RescanFiltered bool
+	RescanTimeout  time.Duration
+
+	EnableTCP  bool
+	EnableUDP  bool
+	EnableICMP bool
+
+	ConfirmOpen bool
+	SummaryOnly bool
+
+	MaxHosts int
+
+	rand          lockedRand
+	reportMu      sync.Mutex
+	collected     ScanReport
+	source        net.IP
+	cancel        context.CancelFunc
+	openFound     int64
+	rtt           RTTEstimator
+	skippedIPs    []string
+	skippedPorts  []int
+	tcpScanned    int64
+	completed     int64
+	dialErrors    int64
+	activeWorkers int64
+	total         int64
+	tcpOpen       int64
+	udpWindow     *CongestionWindow
+	unreachable   *UnreachableWatcher
+	openConns     int64
+	connSlots     chan struct{}
+	connSlotsOnce sync.Once
+	rawWarning    sync.Once
+	pauseMu       sync.Mutex
+	resumed       chan struct{}
+	pinger        func(ip string) ICMPResult
+	icmpConn      *ICMPConn
+	incremental   *incrementalWriter
+	errorsMu      sync.Mutex
+	hostSpent     map[string]time.Duration
+	started       time.Time
+	finished      time.Time
+	filtered      []filteredPort
+	rescanning    bool
+	states        stateCounts
+	answered      chan struct{}
+	idledOut      int32
+	workers       int
+	scanCtx       context.Context
+	scanDial      DialFunc
+	byProtocol    service.ProtocolServices
 }
 
 // NewTarget creates a new PortScanner instance with initialized channels and fields.
 //
 // Parameters:
 // - domain: The domain to scan.
-// - numWorkers: The number of worker goroutines to use for scanning.
+// - numWorkers: The number of worker goroutines to use for scanning, at least 1.
 //
 // Returns:
 // - A pointer to a newly created PortScanner instance.
-// - An error if the domain cannot be resolved to an IP address.
+// - An error if numWorkers is below 1 or the domain cannot be resolved to an IP address.
 //
 // Example:
 //
 //	scanner, err := NewTarget("example.com", 100)
 func NewTarget(domain string, numWorkers int) (*PortScanner, error) {
+	return NewTargetWithResolver(domain, numWorkers, net.DefaultResolver)
+}
+
+// NewTargetWithResolver creates a new PortScanner instance, resolving the
+// domain with the given resolver.
+//
+// Parameters:
+// - domain: The domain to scan.
+// - numWorkers: The number of worker goroutines to use for scanning.
+// - resolver: The resolver used to look up the domain.
+//
+// Returns:
+// - A pointer to a newly created PortScanner instance.
+// - An error if numWorkers is below 1 or the domain cannot be resolved to an IP address.
+//
+// Example:
+//
+//	scanner, err := NewTargetWithResolver("example.com", 100, net.DefaultResolver)
+func NewTargetWithResolver(domain string, numWorkers int, resolver Resolver) (*PortScanner, error) {
+	if err := validateWorkers(numWorkers); err != nil {
+		return nil, err
+	}
+	// A Unix socket target has nothing to resolve
+	if path, ok, err := unixSocketPath(domain); ok {
+		if err != nil {
+			return nil, err
+		}
+		return newUnixTarget(domain, path, numWorkers), nil
+	}
+	// CIDR and dash-form ranges are expanded instead of looked up
+	if isCIDR(domain) {
+		return NewCIDRTarget(domain, numWorkers, DefaultMaxHosts)
+	}
+	if isIPRange(domain) {
+		ips, err := ParseIPRange(domain)
+		if err != nil {
+			return nil, err
+		}
+		t := newTargetFromResolution(domain, numWorkers, Resolution{Host: domain, IPs: ips}, false)
+		t.MaxHosts = DefaultMaxHosts
+		return t, nil
+	}
+
 	// Perform DNS lookup to resolve the domain into a list of IP addresses
-	ips, err := net.LookupHost(domain)
+	resolution, err := Resolve(resolver, domain)
 	if err != nil {
 		return nil, err
 	}
+	return newTargetFromResolution(domain, numWorkers, resolution, false), nil
+}
+
+// validateWorkers checks a scanner gets at least one worker: without any,
+// no endpoint would ever be probed.
+//
+// Parameters:
+// - numWorkers: The number of workers asked for.
+//
+// Returns:
+// - An error if numWorkers is below 1.
+func validateWorkers(numWorkers int) error {
+	if numWorkers < 1 {
+		return fmt.Errorf("invalid number of workers %d, expected at least 1", numWorkers)
+	}
+	return nil
+}
+
+// newTargetFromResolution creates a new PortScanner instance for an already
+// resolved domain. In streaming mode the channels are sized to the workers
+// instead of the number of probes.
+func newTargetFromResolution(domain string, numWorkers int, resolution Resolution, streaming bool) *PortScanner {
+	ips := resolution.IPs
 
 	// Initialize a slice to hold all port numbers from 1 to 65535
-	ports := make([]int, 0, 65535)
-	for port := 1; port <= 65535; port++ {
-		ports = append(ports, port)
+	ports := allPorts()
+
+	// Every port is probed once per resolved IP. Beyond maxEndpointBuffer,
+	// e.g. for a CIDR range, enqueueing waits for the workers instead
+	probes := len(ports) * len(ips)
+	if probes > maxEndpointBuffer {
+		probes = maxEndpointBuffer
+	}
+	if streaming {
+		probes = streamBuffer(numWorkers)
 	}
 
 	// Create and return a new PortScanner instance with initialized channels and fields
 	return &PortScanner{
-		Domain:        domain,
-		IPs:           ips,
-		Ports:         ports,
-		NumWorkers:    numWorkers,
-		PortChannel:   make(chan int, len(ports)),
-		ResultChannel: make(chan int, len(ports)),
-		OpenPorts:     make(chan service.ServiceVersion, len(ports)),
-		OpenPortsUDP:  make(chan service.ServiceVersion, len(ports)),
-		ICMPResults:   make(chan string, len(ips)),
-		Done:          make(chan bool, numWorkers*2+len(ips)),
-		Services:      service.Services,
-	}, nil
+		Domain:         domain,
+		Resolution:     resolution,
+		IPs:            ips,
+		Ports:          ports,
+		NumWorkers:     numWorkers,
+		TCPPortChannel: make(chan Endpoint, probes),
+		UDPPortChannel: make(chan Endpoint, probes),
+		OpenPorts:      make(chan service.ServiceVersion, resultBuffer(numWorkers)),
+		OpenPortsUDP:   make(chan service.ServiceVersion, resultBuffer(numWorkers)),
+		ICMPResults:    make(chan ICMPResult, len(ips)),
+		Done:           make(chan bool, numWorkers*2+len(ips)),
+		Services:       service.Services,
+		Timeout:        DefaultTCPTimeout,
+		MinTimeout:     DefaultMinTimeout,
+		MaxTimeout:     DefaultMaxTimeout,
+
+		ServicesByProtocol: service.ServicesByProtocol,
+
+		ProxyThreshold:  DefaultProxyThreshold,
+		ReportStates:    []string{"Open"},
+		HTTPProbe:       true,
+		SNMPCommunities: DefaultSNMPCommunities,
+		HTTPPorts:       DefaultHTTPPorts,
+		Streaming:       streaming,
+		EnableTCP:       true,
+		EnableUDP:       true,
+		EnableICMP:      true,
+	}
 }
 
 // Scan performs the port scanning.
@@ -249,52 +1211,212 @@ func NewTarget(domain string, numWorkers int) (*PortScanner, error) {
 //
 //	scanner.Scan()
 func (t *PortScanner) Scan() {
+	t.ScanContext(context.Background())
+}
+
+// ScanContext performs the port scanning until it completes or ctx is
+// cancelled. Endpoints not yet probed when ctx is cancelled are skipped.
+//
+// Parameters:
+// - ctx: The context controlling the lifetime of the scan.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	scanner.ScanContext(ctx)
+func (t *PortScanner) ScanContext(ctx context.Context) {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	t.cancel = cancel
+	t.markScan(&t.started)
+
+	// Swap the probes for synthetic ones before anything touches the network
+	t.applyTestMode()
+
+	// Open the incremental output before any result can be reported
+	defer t.startIncremental()()
+
+	// Move the results into the report while the workers keep sending, the
+	// result channels only hold a few. The collector also aborts the scan
+	// once IdleTimeout passes without an answer
+	if t.IdleTimeout > 0 {
+		t.answered = make(chan struct{}, 1)
+	}
+	waitCollected := t.collect()
+
+	if t.UnixSocket != "" {
+		t.scanUnixSocket()
+		t.markScan(&t.finished)
+		t.Cancelled = parent.Err() != nil
+		close(t.OpenPorts)
+		close(t.OpenPortsUDP)
+		close(t.ICMPResults)
+		waitCollected()
+		if t.SummaryOnly {
+			t.logf("Summary: %s\n", t.Summary())
+		}
+		t.complete()
+		return
+	}
+
+	// Drop excluded IPs and ports before anything is probed
+	ips, ports := t.filterExcluded()
+	t.annotateIPs(ips)
+	t.reverseLookup(ips)
+	numWorkers := t.autoWorkers(ips, len(ips)*len(ports))
+	// Only start workers for the enabled protocols
+	tcpWorkers, udpWorkers, pingIPs := t.workerCounts(ips, numWorkers)
+	// Keep every worker's file descriptor within the process limit
+	clampedTCP, clampedUDP := t.clampToFileLimit(tcpWorkers, udpWorkers)
+	// Report may read the worker count for the scan's meta while it runs.
+	// NumWorkers is left as configured, so a reused scanner does not shrink
+	t.reportMu.Lock()
+	t.workers = clampedWorkers(numWorkers, tcpWorkers, udpWorkers, clampedTCP, clampedUDP)
+	t.reportMu.Unlock()
+	tcpWorkers, udpWorkers = clampedTCP, clampedUDP
+	// NumWorkers may have been zeroed after NewTarget validated it. Report
+	// that instead of silently probing nothing
+	if err := validateWorkers(numWorkers); err != nil {
+		t.logf("Error starting the scan: %s\n", err)
+		t.addError("workers", t.Domain, err)
+		tcpWorkers, udpWorkers, pingIPs = 0, 0, nil
+	}
+	t.setTotal(len(ips)*len(ports), tcpWorkers, udpWorkers)
+
+	if t.CongestionControl && udpWorkers > 0 {
+		t.udpWindow = NewCongestionWindow(initialUDPWindow, udpWorkers)
+	}
+	if t.FastUDPClose && udpWorkers > 0 {
+		src := "0.0.0.0"
+		if t.source != nil {
+			src = t.source.String()
+		}
+		watcher, err := ListenUnreachable(src)
+		if err != nil {
+			t.logf("Error listening for ICMP unreachables, UDP probes will wait for their deadline: %s\n", err)
+			t.addError("icmp-unreachable", src, err)
+		} else {
+			t.unreachable = watcher
+			defer func() {
+				watcher.Close()
+				t.unreachable = nil
+			}()
+		}
+	}
+
+	// Capture the raw-socket traffic, which only exists with root
+	pcap, closePcap, err := t.openPcap()
+	if err != nil {
+		t.logf("Error opening the packet capture: %s\n", err)
+		t.addError("pcap", t.PcapFile, err)
+	}
+	defer closePcap()
+
+	// Share one ICMP socket between the ICMP workers. Without one, e.g.
+	// without root, every ping opens its own and falls back from there
+	if len(pingIPs) > 0 && t.pinger == nil {
+		src := "0.0.0.0"
+		if t.source != nil {
+			src = t.source.String()
+		}
+		if conn, err := listenICMP(src, pcap); err == nil {
+			t.icmpConn = conn
+			defer func() {
+				conn.Close()
+				t.icmpConn = nil
+			}()
+		}
+	}
+
 	// Create a channel for distributing IP addresses to ICMP workers
-	ipChannel := make(chan string, len(t.IPs))
+	ipChannel := make(chan string, len(pingIPs))
 
-	// Start the specified number of worker goroutines for TCP and UDP scanning
-	for i := 0; i < t.NumWorkers; i++ {
-		go WorkerTCP("", t.PortChannel, t.ResultChannel, t.OpenPorts, t.Done, t.Services)
-		go WorkerUDP("", t.PortChannel, t.ResultChannel, t.OpenPortsUDP, t.Done, t.Services)
+	t.scanDial, t.scanCtx = t.dialContext(ctx), ctx
+	t.byProtocol = t.protocolServices()
+	defer func() { t.scanCtx, t.scanDial, t.byProtocol = nil, nil, nil }()
+
+	// Start the worker goroutines for TCP and UDP scanning
+	for i := 0; i < tcpWorkers; i++ {
+		go t.WorkerTCP(ctx, t.TCPPortChannel, t.OpenPorts, t.Done)
+	}
+	for i := 0; i < udpWorkers; i++ {
+		go t.WorkerUDP(ctx, t.UDPPortChannel, t.OpenPortsUDP, t.Done)
 	}
 
 	// Start a worker goroutine for each IP address for ICMP scanning
-	for i := 0; i < len(t.IPs); i++ {
-		go WorkerICMP(ipChannel, t.ICMPResults, t.Done)
+	for i := 0; i < len(pingIPs); i++ {
+		go t.WorkerICMP(ipChannel, t.ICMPResults, t.Done)
 	}
 
-	// Enqueue all ports to the PortChannel for the TCP and UDP workers
-	for _, port := range t.Ports {
-		fmt.Printf("Enqueueing port %d\n", port)
-		t.PortChannel <- port
+	// Enqueue every IP and port pair, round-robin over the IPs, to both the TCP
+	// and the UDP workers so each port is scanned on both protocols
+	if tcpWorkers > 0 || udpWorkers > 0 {
+		t.eachEndpoint(ips, ports, func(endpoint Endpoint) {
+			if t.logsPorts() {
+				t.logPort("Enqueueing %s port %d\n", endpoint.IP, endpoint.Port)
+			}
+			if tcpWorkers > 0 {
+				t.TCPPortChannel <- endpoint
+			}
+			if udpWorkers > 0 {
+				t.UDPPortChannel <- endpoint
+			}
+		})
 	}
-	close(t.PortChannel) // Close the PortChannel after enqueueing all ports
+	close(t.TCPPortChannel) // Close the port channels after enqueueing all endpoints
+	close(t.UDPPortChannel)
 
 	// Send all resolved IP addresses to the IP channel for the ICMP workers
-	for _, ip := range t.IPs {
+	for _, ip := range pingIPs {
 		ipChannel <- ip
 	}
 	close(ipChannel) // Close the IP channel after sending all IP addresses
 
-	// Wait for the TCP and UDP results
-	for range t.Ports {
-		<-t.ResultChannel
-	}
-
-	// Wait for all worker goroutines to finish their tasks
+	// Wait for all worker goroutines to finish their tasks. Workers only
+	// exit once their port channel is drained, so every endpoint has been
+	// probed by then
 	doneCount := 0
-	for doneCount < t.NumWorkers*2+len(t.IPs) {
+	for doneCount < tcpWorkers+udpWorkers+len(pingIPs) {
 		<-t.Done
 		doneCount++
 	}
 
+	// Give the ports that timed out a second chance before reporting them
+	t.rescanFiltered(ctx)
+
+	t.markScan(&t.finished)
+
+	// Flag scans stopped from outside or for lack of answers, as opposed to
+	// by MaxResults
+	t.Cancelled = parent.Err() != nil || atomic.LoadInt32(&t.idledOut) == 1
+
+	// Flag scans that were stopped by the MaxResults safeguard. Stopping at
+	// the first open port is what StopOnFirstOpen asks for, not a truncation
+	t.Truncated = !t.StopOnFirstOpen && t.MaxResults > 0 && atomic.LoadInt64(&t.openFound) >= int64(t.MaxResults)
+
+	// Flag scans where nearly every TCP port answered, which points to a proxy
+	t.detectProxy()
+
 	// Close the result channels after all workers are done
 	close(t.OpenPorts)
 	close(t.OpenPortsUDP)
 	close(t.ICMPResults)
+	waitCollected()
+
+	if t.SummaryOnly {
+		t.logf("Summary: %s\n", t.Summary())
+	}
+
+	// Hand the finished report to integrations such as a webhook
+	t.complete()
 }
 
-// writeResultsToFile writes the scan results to an output file.
+// writeResultsToFile writes the scan results to an output file. The file is
+// truncated unless t.AppendOutput is set, in which case the results are
+// appended after a per-scan header. Results are taken from t.Report(), so
+// calling it before Scan has finished writes whatever has been found so far.
 //
 // Parameters:
 // - t: A pointer to the PortScanner instance containing the results.
@@ -307,44 +1429,172 @@ func (t *PortScanner) Scan() {
 //
 //	err := writeResultsToFile(target, "output.txt")
 func writeResultsToFile(t *PortScanner, fileName string) error {
-	// Write the collected scan results to an output file
-	file, err := os.Create(fileName)
+	// Materialize the results first so a scan that has not finished can never
+	// block the writer
+	report := t.Report()
+
+	// Write the collected scan results to an output file, appending when
+	// results from several scans should accumulate in one file
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if t.AppendOutput {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(fileName, flags, 0644)
 	if err != nil {
 		return fmt.Errorf("error creating file: %s", err)
 	}
 	defer file.Close()
 
-	// Write open TCP ports and their services to the output file
-	_, err = file.WriteString("Open TCP Ports with Services:\n")
-	if err != nil {
-		return fmt.Errorf("error writing to file: %s", err)
+	// Separate appended scans with a header naming the target
+	if t.AppendOutput {
+		_, err = file.WriteString(fmt.Sprintf("=== Scan of %s at %s ===\n", t.Domain, time.Now().Format(time.RFC3339)))
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
 	}
-	for service := range t.OpenPorts {
-		_, err = file.WriteString(fmt.Sprintf("Port %d (TCP) is Open, Service: %s\n", service.Port, service.Service))
+
+	// Only the counts, for scripts and dashboards
+	if t.SummaryOnly {
+		_, err = file.WriteString("Summary: " + report.Summary.String() + "\n")
 		if err != nil {
 			return fmt.Errorf("error writing to file: %s", err)
 		}
+		return nil
 	}
 
-	// Write open UDP ports and their services to the output file
-	_, err = file.WriteString("Open UDP Ports with Services:\n")
+	// Record how the scan was configured so it can be reproduced
+	_, err = file.WriteString(report.Meta.String() + "\n")
 	if err != nil {
 		return fmt.Errorf("error writing to file: %s", err)
 	}
-	for service := range t.OpenPortsUDP {
-		_, err = file.WriteString(fmt.Sprintf("Port %d (UDP) is Open, Service: %s\n", service.Port, service.Service))
+
+	// Warn when the scan was stopped before completion
+	if t.Truncated {
+		_, err = file.WriteString(fmt.Sprintf("Warning: scan stopped after %d open ports, results are truncated\n", t.MaxResults))
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+
+	// Warn when the scan was cancelled before completion
+	if t.Cancelled {
+		_, err = file.WriteString("Warning: scan cancelled before completion, results are partial\n")
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+
+	// Describe how the target was resolved
+	if t.Resolution.Host != "" {
+		_, err = file.WriteString(t.Resolution.String() + "\n")
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+
+	// Annotate the resolved IPs when a GeoLookup is configured
+	if len(report.Geo) > 0 {
+		_, err = file.WriteString("IP Annotations:\n")
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+		for _, ip := range sortedGeoIPs(report.Geo) {
+			_, err = file.WriteString(fmt.Sprintf("IP %s: %s\n", ip, report.Geo[ip]))
+			if err != nil {
+				return fmt.Errorf("error writing to file: %s", err)
+			}
+		}
+	}
+
+	// Warn when the TCP results are likely produced by a transparent proxy
+	if t.ProxySuspected {
+		_, err = file.WriteString(fmt.Sprintf("Warning: %.0f%% of TCP ports reported Open, results are likely filtered by a transparent proxy\n", t.openFraction()*100))
 		if err != nil {
 			return fmt.Errorf("error writing to file: %s", err)
 		}
 	}
 
+	// Write the reported TCP ports and their services to the output file
+	states := t.ReportStates
+	if len(states) == 0 {
+		states = []string{"Open"}
+	}
+	_, err = file.WriteString(fmt.Sprintf("%s TCP Ports with Services:\n", strings.Join(states, "/")))
+	if err != nil {
+		return fmt.Errorf("error writing to file: %s", err)
+	}
+	tcp := report.TCP
+	if t.ProxySuspected && t.DowngradeProxied {
+		tcp = make([]service.ServiceVersion, len(report.TCP))
+		for i, svc := range report.TCP {
+			if svc.State == "Open" {
+				svc.State = "Filtered"
+			}
+			tcp[i] = svc
+		}
+	}
+	if err := writeServices(file, "TCP", tcp, t.Unique, report.PTR); err != nil {
+		return err
+	}
+
+	// Write the reported UDP ports and their services to the output file
+	_, err = file.WriteString(fmt.Sprintf("%s UDP Ports with Services:\n", strings.Join(states, "/")))
+	if err != nil {
+		return fmt.Errorf("error writing to file: %s", err)
+	}
+	if err := writeServices(file, "UDP", report.UDP, t.Unique, report.PTR); err != nil {
+		return err
+	}
+
 	// Write ICMP reachability results to the output file
 	_, err = file.WriteString("ICMP Reachability Results:\n")
 	if err != nil {
 		return fmt.Errorf("error writing to file: %s", err)
 	}
-	for result := range t.ICMPResults {
-		_, err = file.WriteString(fmt.Sprintf("%s\n", result))
+	for _, result := range report.ICMP {
+		_, err = file.WriteString(fmt.Sprintf("%s\n", result.String()))
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+
+	// Write the per-IP ping statistics when every IP was pinged several times
+	if len(report.ICMPStats) > 0 {
+		_, err = file.WriteString("ICMP Statistics:\n")
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+	for _, stats := range report.ICMPStats {
+		_, err = file.WriteString(fmt.Sprintf("%s\n", stats.String()))
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+
+	// Note the excluded IPs and ports that were skipped
+	if len(t.skippedIPs) > 0 || len(t.skippedPorts) > 0 {
+		_, err = file.WriteString("Skipped:\n")
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+	for _, ip := range t.skippedIPs {
+		_, err = file.WriteString(fmt.Sprintf("IP %s is skipped\n", ip))
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+	for _, port := range t.skippedPorts {
+		_, err = file.WriteString(fmt.Sprintf("Port %d is skipped\n", port))
+		if err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+
+	// Note the IPs cut off by the per-host budget
+	for _, ip := range sortedKeys(report.Unscanned) {
+		_, err = file.WriteString(fmt.Sprintf("IP %s: %d probes unscanned, per-host budget of %s used up\n", ip, report.Unscanned[ip], t.PerHostBudget))
 		if err != nil {
 			return fmt.Errorf("error writing to file: %s", err)
 		}
@@ -353,27 +1603,332 @@ func writeResultsToFile(t *PortScanner, fileName string) error {
 	return nil
 }
 
+// Exit codes returned by run, so shell conditionals can test for open ports.
+const (
+	ExitOpen   = 0 // at least one open port was found
+	ExitClosed = 1 // the scan completed without finding an open port
+	ExitError  = 2 // the scan could not be run or its results not written
+	ExitDrift  = 3 // the open ports differ from the -baseline report
+)
+
 // main function is the entry point of the program.
 // It prompts the user for a domain, performs port scanning,
-// and writes the results to an output file.
+// and writes the results to an output file. With -serve it instead
+// runs an HTTP server exposing scans as JSON.
 func main() {
-	var domain string
-	fmt.Print("Enter domain: ")
-	fmt.Scanln(&domain)
+	os.Exit(run(os.Args[1:], os.Stdin))
+}
+
+// run parses the command line, runs the scan and writes the results.
+//
+// Parameters:
+// - args: The command-line arguments without the program name.
+// - stdin: Where the domain is read from when it is not given as an argument:
+// prompted for on a terminal, otherwise read as newline-separated targets,
+// several of which are scanned concurrently (see runTargets).
+//
+// Returns:
+// - ExitOpen if any open port was found, ExitClosed if none was,
+// ExitDrift if the open ports differ from the -baseline report and
+// ExitError on error. With -watch, the scan is repeated until interrupted
+// and the code reflects the last one (see watch).
+//
+// Example:
+//
+//	os.Exit(run([]string{"-quiet", "-ports", "22,80", "example.com"}, os.Stdin))
+func run(args []string, stdin io.Reader) int {
+	flags := flag.NewFlagSet("det", flag.ContinueOnError)
+	addr := flags.String("serve", "", "run an HTTP server exposing scans as JSON on this address, e.g. :8080")
+	quiet := flags.Bool("quiet", false, "suppress all terminal output and report the result only through the exit code")
+	portSpec := flags.String("ports", "", "ports to scan, e.g. 22,80,8000-8100, or \"known\" for the ports of known services (default all)")
+	output := flags.String("o", "output.txt", "file to write the results to")
+	profile := flags.String("profile", "", "scan profile: quick, full or stealth")
+	summaryOnly := flags.Bool("summary", false, "write only the number of open, closed and filtered ports instead of a line per port")
+	firstOpen := flags.Bool("first-open", false, "stop at the first open port, to check whether the host is alive")
+	flagVulns := flags.Bool("vulns", false, "flag open ports whose banner names a version with a well-known vulnerability")
+	templateName := flags.String("template", "", "write the results with a text/template file, or the example template \"markdown\" or \"nagios\"")
+	combined := flags.Bool("combined", false, "write one table with the TCP and UDP state of each port side by side")
+	proxy := flags.String("proxy", "", "tunnel TCP probes through an HTTP proxy with CONNECT, e.g. http://proxy.example:3128")
+	ndjson := flags.Bool("ndjson", false, "stream results to stdout as newline-delimited JSON while scanning")
+	pcapFile := flags.String("pcap", "", "capture the raw-socket probes and their answers (ICMP) to a pcap file; needs root")
+	dnsServer := flags.String("dns", "", "resolve targets through this DNS server, as host:port, instead of the system's")
+	cpuProfile := flags.String("cpuprofile", "", "write a CPU profile of the run to this file, for go tool pprof")
+	traceFile := flags.String("trace", "", "write an execution trace of the run to this file, for go tool trace")
+	baselineFile := flags.String("baseline", "", "compare the open ports to the report in this file and exit with 3 if they changed; a missing file is created from this scan")
+	watchInterval := flags.Duration("watch", 0, "rerun the scan at this interval, e.g. 5m, printing only the changes since the previous run, until interrupted")
+	format := flags.String("format", "text", "diagnostics format: text, or json to write the errors met to stderr as JSON at the end")
+	if err := flags.Parse(args); err != nil {
+		return ExitError
+	}
+	logf := func(format string, args ...interface{}) {
+		if !*quiet {
+			fmt.Printf(format, args...)
+		}
+	}
+	if *format != "text" && *format != "json" {
+		logf("Error parsing format: unknown format %q\n", *format)
+		return ExitError
+	}
+	if *watchInterval < 0 {
+		logf("Error parsing watch: negative interval %s\n", *watchInterval)
+		return ExitError
+	}
+	if *watchInterval > 0 && *baselineFile != "" {
+		logf("Error parsing watch: -watch cannot be combined with -baseline\n")
+		return ExitError
+	}
+	var resolver Resolver = net.DefaultResolver
+	if *dnsServer != "" {
+		dnsResolver, err := NewDNSResolver(*dnsServer)
+		if err != nil {
+			logf("Error configuring DNS server: %s\n", err)
+			return ExitError
+		}
+		resolver = dnsResolver
+	}
+	stopProfiling, err := startProfiling(*cpuProfile, *traceFile)
+	if err != nil {
+		logf("Error starting profiling: %s\n", err)
+		return ExitError
+	}
+	defer stopProfiling()
+	// reportErrors writes the errors met as a JSON block to stderr, apart
+	// from the results
+	reportErrors := func(errs []error) {
+		if *format == "json" && !*quiet {
+			WriteErrorsJSON(os.Stderr, errs)
+		}
+	}
+
+	if *addr != "" {
+		if err := serve(*addr); err != nil {
+			logf("Error serving: %s\n", err)
+		}
+		return ExitError
+	}
+
+	// Every target's results go to one stream, one JSON object per line
+	ndjsonWriter := NewNDJSONWriter(os.Stdout)
+	// configure applies the command-line options to a scanner, logging the
+	// first one that is invalid
+	configure := func(target *PortScanner, output, pcap string) bool {
+		target.Quiet = *quiet
+		if *ndjson {
+			// Progress output would corrupt the JSON stream
+			target.Quiet = true
+			if !*quiet {
+				target.OnResult = ndjsonWriter.Write
+			}
+		}
+		if *profile != "" {
+			if err := target.ApplyProfile(*profile); err != nil {
+				logf("Error applying profile: %s\n", err)
+				return false
+			}
+		}
+		if *proxy != "" {
+			if _, err := DialHTTPConnect(net.DialTimeout, *proxy); err != nil {
+				logf("Error configuring proxy: %s\n", err)
+				return false
+			}
+			target.Proxy = *proxy
+		}
+		if *portSpec == KnownPortsSpec {
+			target.Ports = PortsFromServices(target.Services)
+		} else if *portSpec != "" {
+			ports, err := ParsePortSpec(*portSpec)
+			if err != nil {
+				logf("Error parsing ports: %s\n", err)
+				return false
+			}
+			target.Ports = ports
+		}
+
+		target.DNSServer = *dnsServer
+		target.SummaryOnly = *summaryOnly
+		target.FlagKnownVulns = *flagVulns
+		target.StopOnFirstOpen = *firstOpen
+
+		// Keep the results found so far on disk until the full report replaces them
+		target.IncrementalOutput = output
+		target.PcapFile = pcap
+		return true
+	}
+	// writeOutput writes a finished scan's results in the selected format
+	writeOutput := func(target *PortScanner, output string) error {
+		if *templateName != "" {
+			return writeTemplateFile(target, *templateName, output)
+		} else if *combined {
+			return writeCombinedFile(target, output)
+		}
+		return writeResultsToFile(target, output)
+	}
+
+	// Prompt on a terminal; a pipe or file lists the targets one per line
+	domain := flags.Arg(0)
+	if domain == "" && isTerminal(stdin) {
+		logf("Enter domain: ")
+		fmt.Fscanln(stdin, &domain)
+	} else if domain == "" {
+		targets, err := ReadTargets(stdin)
+		if err != nil {
+			logf("Error reading targets: %s\n", err)
+			return ExitError
+		}
+		if len(targets) > 1 && *baselineFile != "" {
+			logf("Error comparing to baseline: -baseline takes a single target\n")
+			return ExitError
+		}
+		if len(targets) > 1 && *watchInterval > 0 {
+			logf("Error watching: -watch takes a single target\n")
+			return ExitError
+		}
+		if len(targets) > 1 {
+			return runTargets(targets, resolver, configure, writeOutput, *output, *pcapFile, logf, reportErrors)
+		}
+		if len(targets) == 1 {
+			domain = targets[0]
+		}
+	}
+
+	if *watchInterval > 0 {
+		// The first interrupt stops after the running scan, a second one
+		// aborts it
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+		return watch(ctx, *watchInterval, func() (ScanReport, error) {
+			target, err := NewTargetWithResolver(domain, 100, resolver)
+			if err != nil {
+				return ScanReport{}, fmt.Errorf("error resolving domain: %s", err)
+			}
+			if !configure(target, *output, *pcapFile) {
+				return ScanReport{}, fmt.Errorf("invalid options")
+			}
+			// Only the changes are printed, not every rescan's progress
+			target.Quiet = true
+			target.Scan()
+			if err := writeOutput(target, *output); err != nil {
+				return ScanReport{}, fmt.Errorf("error writing results to file: %s", err)
+			}
+			reportErrors(target.Errors)
+			return target.Report(), nil
+		}, os.Stdout, logf)
+	}
 
 	// Create a new PortScanner instance with 100 worker goroutines
-	target, err := NewTarget(domain, 100)
+	target, err := NewTargetWithResolver(domain, 100, resolver)
 	if err != nil {
-		fmt.Printf("Error resolving domain: %s\n", err)
-		return
+		logf("Error resolving domain: %s\n", err)
+		reportErrors([]error{&ScanError{Stage: "resolve", Target: domain, Err: err}})
+		return ExitError
+	}
+	if !configure(target, *output, *pcapFile) {
+		return ExitError
+	}
+	// Read the baseline before scanning, so a bad one fails fast
+	var baseline ScanReport
+	haveBaseline := false
+	if *baselineFile != "" {
+		baseline, haveBaseline, err = loadBaseline(*baselineFile)
+		if err != nil {
+			logf("Error loading baseline: %s\n", err)
+			reportErrors([]error{&ScanError{Stage: "baseline", Target: *baselineFile, Err: err}})
+			return ExitError
+		}
 	}
 
 	// Start the scanning process
 	target.Scan()
 
 	// Write the results to a file
-	err = writeResultsToFile(target, "output.txt")
-	if err != nil {
-		fmt.Printf("Error writing results to file: %s\n", err)
+	if err := writeOutput(target, *output); err != nil {
+		logf("Error writing results to file: %s\n", err)
+		reportErrors(append(target.Errors, &ScanError{Stage: "output", Target: *output, Err: err}))
+		return ExitError
+	}
+	if *baselineFile != "" && !haveBaseline {
+		// The first run records the baseline later runs are compared to
+		if err := AppendRun(*baselineFile, target.Report()); err != nil {
+			logf("Error saving baseline: %s\n", err)
+			reportErrors(append(target.Errors, &ScanError{Stage: "baseline", Target: *baselineFile, Err: err}))
+			return ExitError
+		}
+		logf("Saved baseline to %s\n", *baselineFile)
+	} else if *baselineFile != "" {
+		if diff := DiffReports(baseline, target.Report()); !diff.Empty() {
+			logf("Changes since the baseline:\n%s\n", diff)
+			reportErrors(target.Errors)
+			return ExitDrift
+		}
+		logf("No changes since the baseline\n")
+	}
+	reportErrors(target.Errors)
+	if atomic.LoadInt64(&target.openFound) == 0 {
+		return ExitClosed
+	}
+	return ExitOpen
+}
+
+// runTargets scans several targets concurrently through a MultiScan, as run
+// does for a list of targets read from stdin. Every target gets its own
+// output and pcap file, named after the given ones by perTargetFile. A
+// target that cannot be resolved is reported and skipped.
+//
+// Parameters:
+// - targets: The targets to scan.
+// - resolver: The resolver used to look up the targets.
+// - configure: Applies the command-line options to a scanner, false if one is invalid.
+// - writeOutput: Writes a finished scan's results to a file.
+// - output: The output file name given on the command line.
+// - pcap: The pcap file name given on the command line, "" for none.
+// - logf: Prints progress unless quiet.
+// - reportErrors: Writes the errors met in the selected diagnostics format.
+//
+// Returns:
+// - ExitError if a target could not be resolved or its results not written,
+// otherwise ExitOpen if any open port was found and ExitClosed if none was.
+func runTargets(targets []string, resolver Resolver, configure func(*PortScanner, string, string) bool, writeOutput func(*PortScanner, string) error,
+	output, pcap string, logf func(string, ...interface{}), reportErrors func([]error)) int {
+	multi := NewMultiScan(context.Background(), targets, 100, resolver)
+	var errs []error
+	for i, scanner := range multi.Scanners {
+		if scanner == nil {
+			logf("Error resolving domain %s: %s\n", targets[i], multi.Errors[i])
+			errs = append(errs, &ScanError{Stage: "resolve", Target: targets[i], Err: multi.Errors[i]})
+			continue
+		}
+		if !configure(scanner, perTargetFile(output, targets[i]), perTargetFile(pcap, targets[i])) {
+			return ExitError
+		}
+	}
+	multi.Start()
+	multi.Wait()
+
+	failed := len(errs) > 0
+	code := ExitClosed
+	for i, scanner := range multi.Scanners {
+		if scanner == nil {
+			continue
+		}
+		errs = append(errs, scanner.Errors...)
+		file := perTargetFile(output, targets[i])
+		if err := writeOutput(scanner, file); err != nil {
+			logf("Error writing results to file: %s\n", err)
+			errs = append(errs, &ScanError{Stage: "output", Target: file, Err: err})
+			failed = true
+			continue
+		}
+		if atomic.LoadInt64(&scanner.openFound) > 0 {
+			code = ExitOpen
+		}
+	}
+	reportErrors(errs)
+	if failed {
+		return ExitError
 	}
+	return code
 }
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestInterleave(t *testing.T) {
+	got := Interleave([]string{"10.0.0.1", "10.0.0.2"}, []int{22, 80})
+	expected := []Endpoint{
+		{IP: "10.0.0.1", Port: 22},
+		{IP: "10.0.0.2", Port: 22},
+		{IP: "10.0.0.1", Port: 80},
+		{IP: "10.0.0.2", Port: 80},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestWorkerTCP_Fairness(t *testing.T) {
+	const slowIP, fastIP = "10.0.0.1", "10.0.0.2"
+	const slowDelay = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	var firstFast time.Duration
+	start := time.Now()
+	dial := func(network, address string, timeout time.Duration) (net.Conn, error) {
+		if strings.HasPrefix(address, slowIP) {
+			time.Sleep(slowDelay)
+		} else {
+			mu.Lock()
+			if firstFast == 0 {
+				firstFast = time.Since(start)
+			}
+			mu.Unlock()
+		}
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	}
+	scanner := &PortScanner{Dial: dial}
+
+	ports := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	endpoints := Interleave([]string{slowIP, fastIP}, ports)
+	queue := make(chan Endpoint, len(endpoints))
+	for _, endpoint := range endpoints {
+		queue <- endpoint
+	}
+	close(queue)
+
+	openPorts := make(chan service.ServiceVersion, len(endpoints))
+	done := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		go scanner.WorkerTCP(context.Background(), queue, openPorts, done)
+	}
+	<-done
+	<-done
+
+	// Without interleaving the fast IP would wait for most of the slow probes
+	if firstFast >= slowDelay {
+		t.Errorf("Expected the fast IP to be probed before the first slow probe finished, got %v", firstFast)
+	}
+}
+
+func TestScanPortTCP_CustomDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	var used bool
+	scanner := &PortScanner{
+		Timeout: time.Second,
+		Dialer: &net.Dialer{
+			KeepAlive: -1,
+			Control: func(network, address string, c syscall.RawConn) error {
+				used = true
+				return nil
+			},
+		},
+	}
+
+	if state := scanner.scanPortTCP("127.0.0.1", port); state != "Open" {
+		t.Errorf("Expected Open, got %s", state)
+	}
+	if !used {
+		t.Errorf("Expected the injected dialer to be used")
+	}
+}
+
+func TestWriteResultsToFile_Append(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	for _, domain := range []string{"first.example", "second.example"} {
+		scanner := &PortScanner{Domain: domain, AppendOutput: true}
+		runTCPWorker(scanner, "127.0.0.1", []int{80})
+		if err := writeResultsToFile(scanner, fileName); err != nil {
+			t.Fatalf("writeResultsToFile failed: %s", err)
+		}
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	for _, expected := range []string{"=== Scan of first.example", "=== Scan of second.example"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in output, got:\n%s", expected, data)
+		}
+	}
+	if strings.Count(string(data), "Open TCP Ports with Services:") != 2 {
+		t.Errorf("Expected two TCP sections, got:\n%s", data)
+	}
+}
+
+func TestScan_MaxResults(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = make([]int, 1000)
+	for i := range scanner.Ports {
+		scanner.Ports[i] = 40001 + i
+	}
+	scanner.Dial = dialer.Dial
+	scanner.MaxResults = 5
+
+	scanner.Scan()
+
+	if !scanner.Truncated {
+		t.Errorf("Expected the scan to be flagged as truncated")
+	}
+	// A connect abandoned by the early stop may still record its dial
+	dialer.mu.Lock()
+	tcpProbes := 0
+	for _, addr := range dialer.addrs {
+		if strings.HasPrefix(addr, "tcp://") {
+			tcpProbes++
+		}
+	}
+	dialer.mu.Unlock()
+	if tcpProbes >= len(scanner.Ports) {
+		t.Errorf("Expected the scan to stop early, got %d TCP probes", tcpProbes)
+	}
+	if open := len(scanner.Report().TCP); open != 5 {
+		t.Errorf("Expected 5 open ports in the results, got %d", open)
+	}
+}
+
+func TestScan_BothProtocols(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{53}
+	scanner.Dial = dialer.Dial
+
+	scanner.Scan()
+
+	probes := map[string]int{}
+	for _, addr := range dialer.addrs {
+		probes[addr]++
+	}
+	for _, expected := range []string{"tcp://127.0.0.1:53", "udp://127.0.0.1:53"} {
+		if probes[expected] != 1 {
+			t.Errorf("Expected exactly one probe to %s, got %d (all probes: %v)", expected, probes[expected], dialer.addrs)
+		}
+	}
+}
+
+func TestWriteResultsToFile_ReportStates(t *testing.T) {
+	dialer := &mockDialer{
+		accept:  func(address string) bool { return strings.HasSuffix(address, ":1") },
+		timeout: func(address string) bool { return strings.HasSuffix(address, ":2") },
+	}
+	tests := []struct {
+		states   []string
+		included []string
+		excluded []string
+	}{
+		{nil, []string{"Port 1 (TCP) is Open"}, []string{"Port 2", "Port 3"}},
+		{[]string{"Open", "Filtered"}, []string{"Port 1 (TCP) is Open", "Port 2 (TCP) is Filtered"}, []string{"Port 3"}},
+		{[]string{"Filtered"}, []string{"Port 2 (TCP) is Filtered"}, []string{"Port 1", "Port 3"}},
+	}
+
+	for _, test := range tests {
+		scanner := &PortScanner{Dial: dialer.Dial, ReportStates: test.states}
+		runTCPWorker(scanner, "127.0.0.1", []int{1, 2, 3})
+		fileName := filepath.Join(t.TempDir(), "output.txt")
+		if err := writeResultsToFile(scanner, fileName); err != nil {
+			t.Fatalf("writeResultsToFile failed: %s", err)
+		}
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatalf("Reading output failed: %s", err)
+		}
+		for _, line := range test.included {
+			if !strings.Contains(string(data), line) {
+				t.Errorf("States %v: Expected %q in report, got:\n%s", test.states, line, data)
+			}
+		}
+		for _, line := range test.excluded {
+			if strings.Contains(string(data), line) {
+				t.Errorf("States %v: Expected %q not in report, got:\n%s", test.states, line, data)
+			}
+		}
+	}
+}
+
+func TestWriteResultsToFile_NoScan(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+
+	written := make(chan error, 1)
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	go func() {
+		written <- writeResultsToFile(scanner, fileName)
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			t.Fatalf("writeResultsToFile failed: %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("writeResultsToFile blocked on a scanner that was never scanned")
+	}
+}
+
+func TestReport_KeepsDrainedResults(t *testing.T) {
+	scanner := &PortScanner{
+		OpenPorts:    make(chan service.ServiceVersion, 2),
+		OpenPortsUDP: make(chan service.ServiceVersion),
+		ICMPResults:  make(chan ICMPResult),
+	}
+	scanner.OpenPorts <- service.ServiceVersion{Port: 22}
+	if report := scanner.Report(); len(report.TCP) != 1 {
+		t.Fatalf("Expected 1 TCP result mid-scan, got %d", len(report.TCP))
+	}
+	scanner.OpenPorts <- service.ServiceVersion{Port: 80}
+	close(scanner.OpenPorts)
+	if report := scanner.Report(); len(report.TCP) != 2 {
+		t.Errorf("Expected both TCP results after the scan, got %d", len(report.TCP))
+	}
+}
+
+func TestScan_RecoversFromProbePanic(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{40001, 40002, 40003}
+	scanner.Dial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		if network == "tcp" && strings.HasSuffix(address, ":40002") {
+			panic("malformed probe")
+		}
+		return dialer.Dial(network, address, timeout)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		scanner.Scan()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Scan deadlocked after a probe panicked")
+	}
+
+	states := map[int]string{}
+	for _, svc := range scanner.Report().TCP {
+		states[svc.Port] = svc.State
+	}
+	expected := map[int]string{40001: "Open", 40002: "Error", 40003: "Open"}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("Expected TCP states %v, got %v", expected, states)
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	open := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	// A port that was just released is closed
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	closed := strconv.Itoa(closedListener.Addr().(*net.TCPAddr).Port)
+	closedListener.Close()
+
+	output := filepath.Join(t.TempDir(), "output.txt")
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"open port", []string{"-quiet", "-o", output, "-ports", open, "127.0.0.1"}, ExitOpen},
+		{"closed port", []string{"-quiet", "-o", output, "-ports", closed, "127.0.0.1"}, ExitClosed},
+		{"invalid ports", []string{"-quiet", "-o", output, "-ports", "0", "127.0.0.1"}, ExitError},
+		{"unknown flag", []string{"-unknown"}, ExitError},
+	}
+	for _, test := range tests {
+		if code := run(test.args, strings.NewReader("")); code != test.expected {
+			t.Errorf("%s: Expected exit code %d, got %d", test.name, test.expected, code)
+		}
+	}
+}
+
+func TestRun_QuietSuppressesOutput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %s", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	output := filepath.Join(t.TempDir(), "output.txt")
+	code := run([]string{"-quiet", "-o", output, "-ports", "1"}, strings.NewReader("127.0.0.1\n"))
+	os.Stdout = stdout
+	w.Close()
+
+	printed, _ := io.ReadAll(r)
+	if len(printed) != 0 {
+		t.Errorf("Expected no output in quiet mode, got %q", printed)
+	}
+	if code == ExitError {
+		t.Errorf("Expected the scan to run, got exit code %d", code)
+	}
+}
+
+func TestWorkerTCP_ServiceOverrides(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner := &PortScanner{
+		Dial:             dialer.Dial,
+		Services:         service.Services,
+		ServiceOverrides: map[int]string{80: "intranet"},
+		Quiet:            true,
+	}
+	runTCPWorker(scanner, "127.0.0.1", []int{22, 80})
+
+	got := map[int]string{}
+	for svc := range scanner.OpenPorts {
+		got[svc.Port] = svc.Service
+	}
+	if got[80] != "intranet" {
+		t.Errorf("Expected the override for port 80 to win, got %s", got[80])
+	}
+	if got[22] != service.Services[22] {
+		t.Errorf("Expected the built-in service %s for port 22, got %s", service.Services[22], got[22])
+	}
+}
+
+func TestDetectService_ByProtocol(t *testing.T) {
+	scanner := &PortScanner{
+		Services:           service.Services,
+		ServicesByProtocol: service.ServicesByProtocol,
+		ServiceOverrides:   map[int]string{514: "logs"},
+	}
+	tests := []struct {
+		port     int
+		protocol string
+		expected string
+	}{
+		{500, "UDP", "isakmp"},
+		{500, "TCP", "Unknown"},
+		{67, "UDP", "dhcps"},
+		{67, "TCP", service.Services[67]},
+		{22, "TCP", "ssh"},
+		{514, "UDP", "logs"},
+		{514, "TCP", "logs"},
+	}
+	for _, test := range tests {
+		svc := scanner.detectService(test.port, test.protocol)
+		if svc.Service != test.expected {
+			t.Errorf("Expected %s for %s port %d, got %s", test.expected, test.protocol, test.port, svc.Service)
+		}
+	}
+
+	scanner.ServicesByProtocol = nil
+	if svc := scanner.detectService(500, "TCP"); svc.Service != service.Services[500] {
+		t.Errorf("Expected the flat Services map without ServicesByProtocol, got %s", svc.Service)
+	}
+}
+
+func TestScan_CustomServices(t *testing.T) {
+	dialer := &mockDialer{accept: func(address string) bool {
+		return address == "127.0.0.1:9000"
+	}}
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{9000}
+	scanner.Dial = dialer.Dial
+	scanner.Quiet = true
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+	scanner.Services = map[int]string{9000: "internal-app"}
+
+	scanner.Scan()
+
+	report := scanner.Report()
+	if len(report.TCP) != 1 || report.TCP[0].Service != "internal-app" {
+		t.Errorf("Expected port 9000 reported as internal-app from the custom Services, got %+v", report.TCP)
+	}
+}
+
+func TestScan_OnOpenPort(t *testing.T) {
+	dialer := &mockDialer{accept: func(address string) bool {
+		return address == "127.0.0.1:40001" || address == "127.0.0.1:40003"
+	}}
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{40001, 40002, 40003, 40004}
+	scanner.Dial = dialer.Dial
+	scanner.Quiet = true
+
+	var mu sync.Mutex
+	fired := map[string]int{}
+	scanner.OnOpenPort = func(svc service.ServiceVersion) {
+		mu.Lock()
+		defer mu.Unlock()
+		fired[svc.Protocol+"/"+strconv.Itoa(svc.Port)]++
+	}
+
+	scanner.Scan()
+
+	// The mock answers UDP reads with EOF, so only TCP ports are open
+	expected := map[string]int{"TCP/40001": 1, "TCP/40003": 1}
+	if !reflect.DeepEqual(fired, expected) {
+		t.Errorf("Expected the hook to fire once per open port %v, got %v", expected, fired)
+	}
+}
+
+func TestWorkerTCP_Timestamps(t *testing.T) {
+	scanner := &PortScanner{Dial: (&mockDialer{}).Dial}
+	ports := []int{1, 2, 3, 4, 5}
+	start := time.Now()
+	runTCPWorker(scanner, "127.0.0.1", ports)
+	end := time.Now()
+
+	var previous time.Time
+	count := 0
+	for svc := range scanner.OpenPorts {
+		count++
+		if svc.Timestamp.IsZero() {
+			t.Errorf("Expected a timestamp for port %d", svc.Port)
+			continue
+		}
+		if svc.Timestamp.Before(start) || svc.Timestamp.After(end) {
+			t.Errorf("Expected the timestamp of port %d within the scan, got %s", svc.Port, svc.Timestamp)
+		}
+		if svc.Timestamp.Before(previous) {
+			t.Errorf("Expected timestamps in probe order, port %d at %s precedes %s", svc.Port, svc.Timestamp, previous)
+		}
+		previous = svc.Timestamp
+	}
+	if count != len(ports) {
+		t.Errorf("Expected %d results, got %d", len(ports), count)
+	}
+}
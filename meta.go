@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Version is the version of the scanner recorded in every report. Release
+// builds set it with -ldflags "-X main.Version=v1.2.3".
+var Version = "dev"
+
+// ScanMeta records how a scan was configured and when it ran, so a report
+// can be audited or the scan reproduced later.
+//
+// Fields:
+// - Target: The scanned domain.
+// - Ports: The scanned ports as a port specification, e.g. "22,80,8000-8100".
+// - Workers: The number of TCP and UDP workers.
+// - Timeout: The TCP connect timeout.
+// - Mode: The TCP probe technique: "connect", "fast-connect" or "custom" for a user TCPProber.
+// - Start: When the scan started, zero if it has not.
+// - End: When the scan finished, zero while it runs.
+// - Version: The scanner Version.
+//
+// Example:
+//
+//	meta := scanner.Report().Meta
+//	fmt.Println(meta)
+type ScanMeta struct {
+	Target  string        `json:"target"`
+	Ports   string        `json:"ports"`
+	Workers int           `json:"workers"`
+	Timeout time.Duration `json:"timeout"`
+	Mode    string        `json:"mode"`
+	Start   time.Time     `json:"start"`
+	End     time.Time     `json:"end"`
+	Version string        `json:"version"`
+}
+
+// String formats the metadata as a single line, e.g. "Scan of example.com:
+// ports 1-1024, 100 workers, timeout 10s, mode connect, started ..., version dev".
+//
+// Returns:
+// - The metadata as a single line.
+func (m ScanMeta) String() string {
+	line := fmt.Sprintf("Scan of %s: ports %s, %d workers, timeout %s, mode %s", m.Target, m.Ports, m.Workers, m.Timeout, m.Mode)
+	if !m.Start.IsZero() {
+		line += ", started " + m.Start.Format(time.RFC3339)
+	}
+	if !m.End.IsZero() {
+		line += ", finished " + m.End.Format(time.RFC3339)
+	}
+	return line + ", version " + m.Version
+}
+
+// meta returns the scanner's configuration and scan times. The caller must
+// hold reportMu.
+func (t *PortScanner) meta() ScanMeta {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTCPTimeout
+	}
+	return ScanMeta{
+		Target:  t.Domain,
+		Ports:   FormatPortSpec(t.Ports),
+		Workers: t.scanWorkers(),
+		Timeout: timeout,
+		Mode:    t.scanMode(),
+		Start:   t.started,
+		End:     t.finished,
+		Version: Version,
+	}
+}
+
+// scanMode names the TCP probe technique for ScanMeta.
+func (t *PortScanner) scanMode() string {
+	if _, ok := t.tcpProber().(ConnectProber); !ok {
+		return "custom"
+	}
+	if t.FastConnect {
+		return "fast-connect"
+	}
+	return "connect"
+}
+
+// markScan records the start or the end of a scan for ScanMeta.
+//
+// Parameters:
+// - at: Where to record the current time, &t.started or &t.finished.
+func (t *PortScanner) markScan(at *time.Time) {
+	t.reportMu.Lock()
+	defer t.reportMu.Unlock()
+	*at = time.Now()
+}
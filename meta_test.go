@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport_MetaReflectsConfiguration(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner, err := NewTarget("127.0.0.1", 7)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{81, 22, 80}
+	scanner.Timeout = 3 * time.Second
+	scanner.FastConnect = true
+	scanner.Dial = dialer.Dial
+	scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	scanner.Quiet = true
+
+	if meta := scanner.Report().Meta; !meta.Start.IsZero() || !meta.End.IsZero() {
+		t.Errorf("Expected no scan times before the scan, got %+v", meta)
+	}
+	before := time.Now()
+	scanner.Scan()
+
+	meta := scanner.Report().Meta
+	expected := ScanMeta{Target: "127.0.0.1", Ports: "22,80-81", Workers: 7, Timeout: 3 * time.Second, Mode: "fast-connect", Version: Version}
+	if meta.Target != expected.Target || meta.Ports != expected.Ports || meta.Workers != expected.Workers ||
+		meta.Timeout != expected.Timeout || meta.Mode != expected.Mode || meta.Version != expected.Version {
+		t.Errorf("Expected meta %+v, got %+v", expected, meta)
+	}
+	if meta.Start.Before(before) || meta.End.Before(meta.Start) {
+		t.Errorf("Expected the scan to start after %s and end after it started, got %s to %s", before, meta.Start, meta.End)
+	}
+
+	file := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, file); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Reading the output failed: %s", err)
+	}
+	if first := strings.SplitN(string(data), "\n", 2)[0]; first != meta.String() {
+		t.Errorf("Expected the output to start with %q, got %q", meta.String(), first)
+	}
+}
+
+func TestScanMeta_Mode(t *testing.T) {
+	custom := ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateOpen, nil })
+	tests := []struct {
+		scanner  *PortScanner
+		expected string
+	}{
+		{&PortScanner{}, "connect"},
+		{&PortScanner{FastConnect: true}, "fast-connect"},
+		{&PortScanner{TCPProber: custom}, "custom"},
+	}
+	for _, tt := range tests {
+		if mode := tt.scanner.scanMode(); mode != tt.expected {
+			t.Errorf("Expected mode %q, got %q", tt.expected, mode)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// ScanMetrics is a snapshot of a scan's activity, for diagnosing slow scans.
+//
+// Fields:
+// - Probes: The TCP and UDP probes completed, as Completed.
+// - Open: The ports found open.
+// - Closed: The ports found closed.
+// - Filtered: The ports that did not answer.
+// - Errors: The probes that failed with an error.
+// - DialErrors: The connections that could not be opened, refused ones included.
+// - ProbesPerSecond: The probes completed per second since the scan started.
+// - ActiveWorkers: The TCP, UDP and ICMP workers currently running.
+//
+// Example:
+//
+//	m := scanner.Metrics()
+//	fmt.Printf("%.0f probes/s, %d dial errors\n", m.ProbesPerSecond, m.DialErrors)
+type ScanMetrics struct {
+	Probes          int64   `json:"probes"`
+	Open            int64   `json:"open"`
+	Closed          int64   `json:"closed"`
+	Filtered        int64   `json:"filtered"`
+	Errors          int64   `json:"errors"`
+	DialErrors      int64   `json:"dial_errors"`
+	ProbesPerSecond float64 `json:"probes_per_second"`
+	ActiveWorkers   int64   `json:"active_workers"`
+}
+
+// Metrics returns a snapshot of the scan's activity. It only reads
+// counters the workers update atomically, so it is safe to call while the
+// scan is running.
+//
+// Returns:
+// - The metrics, all zero before the scan starts.
+//
+// Example:
+//
+//	go scanner.Scan()
+//	time.Sleep(time.Second)
+//	fmt.Printf("%+v\n", scanner.Metrics())
+func (t *PortScanner) Metrics() ScanMetrics {
+	m := ScanMetrics{
+		Probes:        atomic.LoadInt64(&t.completed),
+		Open:          atomic.LoadInt64(&t.states.open),
+		Closed:        atomic.LoadInt64(&t.states.closed),
+		Filtered:      atomic.LoadInt64(&t.states.filtered),
+		Errors:        atomic.LoadInt64(&t.states.errors),
+		DialErrors:    atomic.LoadInt64(&t.dialErrors),
+		ActiveWorkers: atomic.LoadInt64(&t.activeWorkers),
+	}
+
+	// A finished scan's rate is over its whole run, not up to now
+	t.reportMu.Lock()
+	started, finished := t.started, t.finished
+	t.reportMu.Unlock()
+	if finished.Before(started) {
+		finished = time.Time{}
+	}
+	if finished.IsZero() {
+		finished = time.Now()
+	}
+	if elapsed := finished.Sub(started).Seconds(); !started.IsZero() && elapsed > 0 {
+		m.ProbesPerSecond = float64(m.Probes) / elapsed
+	}
+	return m
+}
+
+// PublishMetrics publishes the scan's Metrics through expvar under name, so
+// they are served as JSON on /debug/vars by a process that serves
+// expvar's handler.
+//
+// Parameters:
+// - name: The expvar name, which must be unique in the process; like
+// expvar.Publish, it panics if the name is already published.
+//
+// Example:
+//
+//	scanner.PublishMetrics("scan")
+//	go http.ListenAndServe("localhost:6060", nil)
+//	scanner.Scan()
+func (t *PortScanner) PublishMetrics(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return t.Metrics()
+	}))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// publishedMetrics numbers the expvar names the tests publish.
+var publishedMetrics int64
+
+func TestMetrics_SmallScan(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	closed := closedListener.Addr().(*net.TCPAddr).Port
+	closedListener.Close()
+
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{listener.Addr().(*net.TCPAddr).Port, closed}
+	scanner.EnableTCP = true
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+	scanner.Quiet = true
+	if m := scanner.Metrics(); m != (ScanMetrics{}) {
+		t.Errorf("Expected no metrics before the scan, got %+v", m)
+	}
+	// expvar names cannot be published twice, e.g. with -count
+	name := fmt.Sprintf("TestMetrics_SmallScan_%d", atomic.AddInt64(&publishedMetrics, 1))
+	scanner.PublishMetrics(name)
+	scanner.Scan()
+
+	m := scanner.Metrics()
+	if m.Probes != 2 || m.Open != 1 || m.Closed != 1 || m.Filtered != 0 || m.Errors != 0 {
+		t.Errorf("Expected 2 probes, 1 open and 1 closed, got %+v", m)
+	}
+	if m.DialErrors != 1 {
+		t.Errorf("Expected the refused connect as a dial error, got %d", m.DialErrors)
+	}
+	if m.ProbesPerSecond <= 0 {
+		t.Errorf("Expected a positive probe rate, got %f", m.ProbesPerSecond)
+	}
+	if m.ActiveWorkers != 0 {
+		t.Errorf("Expected no active workers after the scan, got %d", m.ActiveWorkers)
+	}
+	if published := expvar.Get(name).String(); !strings.Contains(published, `"probes":2`) {
+		t.Errorf("Expected the metrics published through expvar, got %s", published)
+	}
+}
+
+func TestMetrics_CountsProbeErrors(t *testing.T) {
+	scanner := stubbedScanner(t)
+	scanner.Ports = []int{22, 80, 443}
+	scanner.EnableTCP = true
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		if port == 443 {
+			return "", errors.New("handshake failed")
+		}
+		return StateClosed, nil
+	})
+	scanner.Scan()
+
+	if m := scanner.Metrics(); m.Probes != 3 || m.Closed != 2 || m.Errors != 1 {
+		t.Errorf("Expected 3 probes, 2 closed and 1 error, got %+v", m)
+	}
+}
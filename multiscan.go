@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// MultiScan scans several hosts concurrently, each under its own context
+// derived from the parent, so a hanging target neither blocks the others nor
+// the overall result, and a single target can be cancelled while the rest
+// proceed.
+//
+// Fields:
+// - Hosts: The targets to scan, in any form NewTarget accepts.
+// - Scanners: The scanner of each host, in the order of Hosts, nil if it could not be resolved. They may be configured between NewMultiScan and Start.
+// - Errors: The error of each host, e.g. a resolution error, nil on success, in the order of Hosts.
+//
+// Example:
+//
+//	multi := NewMultiScan(ctx, []string{"example.com", "example.org"}, 100, net.DefaultResolver)
+//	multi.Start()
+//	time.AfterFunc(time.Minute, func() { multi.Cancel("example.org") })
+//	multi.Wait()
+//	for _, report := range multi.Reports() {
+//	    fmt.Println(report.Target, report.Cancelled)
+//	}
+type MultiScan struct {
+	Hosts    []string
+	Scanners []*PortScanner
+	Errors   []error
+
+	ctx     context.Context
+	cancels []context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewMultiScan creates a scanner for each host concurrently, as
+// NewTargetWithResolver does, so Unix sockets, CIDR and dash-form ranges are
+// accepted too. Lookups go through a CachingResolver, so repeated hostnames
+// cost a single lookup. Nothing is scanned until Start.
+//
+// Parameters:
+// - ctx: The parent context of every target's scan.
+// - hosts: The targets to scan, in any form NewTarget accepts.
+// - numWorkers: The number of worker goroutines of each scan.
+// - resolver: The resolver used to look up the hosts.
+//
+// Returns:
+// - The MultiScan, with a scanner or an error per host, e.g. when it cannot be resolved or numWorkers is below 1.
+//
+// Example:
+//
+//	multi := NewMultiScan(ctx, hosts, 100, net.DefaultResolver)
+func NewMultiScan(ctx context.Context, hosts []string, numWorkers int, resolver Resolver) *MultiScan {
+	if _, ok := resolver.(*CachingResolver); !ok {
+		resolver = NewCachingResolver(resolver, DefaultDNSCacheTTL)
+	}
+	scanners, errs := newTargets(hosts, numWorkers, resolver, DefaultResolveWorkers)
+	return &MultiScan{
+		Hosts:    hosts,
+		Scanners: scanners,
+		Errors:   errs,
+		ctx:      ctx,
+		cancels:  make([]context.CancelFunc, len(hosts)),
+	}
+}
+
+// newTargets creates a scanner for each host with NewTargetWithResolver,
+// with a bounded pool of lookups.
+//
+// Parameters:
+// - hosts: The targets to create scanners for.
+// - numWorkers: The number of worker goroutines of each scanner.
+// - resolver: The resolver used to look up the hosts.
+// - workers: The maximum number of concurrent lookups.
+//
+// Returns:
+// - The scanner of each host, in the order of hosts, nil if it could not be created.
+// - The error of each host, nil on success, in the order of hosts.
+func newTargets(hosts []string, numWorkers int, resolver Resolver, workers int) ([]*PortScanner, []error) {
+	scanners := make([]*PortScanner, len(hosts))
+	errs := make([]error, len(hosts))
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, host string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			scanners[i], errs[i] = NewTargetWithResolver(host, numWorkers, resolver)
+		}(i, host)
+	}
+	wg.Wait()
+	return scanners, errs
+}
+
+// Start scans every resolved host concurrently, each with its own context.
+// It must be called once.
+//
+// Example:
+//
+//	multi.Start()
+//	defer multi.Wait()
+func (m *MultiScan) Start() {
+	for i, scanner := range m.Scanners {
+		if scanner == nil {
+			continue
+		}
+		ctx, cancel := context.WithCancel(m.ctx)
+		m.cancels[i] = cancel
+		m.wg.Add(1)
+		go func(scanner *PortScanner) {
+			defer m.wg.Done()
+			defer cancel()
+			scanner.ScanContext(ctx)
+		}(scanner)
+	}
+}
+
+// Cancel stops the scans of every target named host, letting the others
+// proceed. The cancelled scans report Cancelled.
+//
+// Parameters:
+// - host: The host name, as given in Hosts.
+//
+// Returns:
+// - Whether a started scan of host was found.
+//
+// Example:
+//
+//	multi.Cancel("example.org")
+func (m *MultiScan) Cancel(host string) bool {
+	found := false
+	for i, name := range m.Hosts {
+		if name == host && m.CancelIndex(i) {
+			found = true
+		}
+	}
+	return found
+}
+
+// CancelIndex stops the scan of the target at index i of Hosts, letting the
+// others proceed. The cancelled scan reports Cancelled.
+//
+// Parameters:
+// - i: The index of the target in Hosts.
+//
+// Returns:
+// - Whether a started scan was found at i.
+//
+// Example:
+//
+//	multi.CancelIndex(1)
+func (m *MultiScan) CancelIndex(i int) bool {
+	if i < 0 || i >= len(m.cancels) || m.cancels[i] == nil {
+		return false
+	}
+	m.cancels[i]()
+	return true
+}
+
+// Wait blocks until every started scan has finished or been cancelled.
+//
+// Example:
+//
+//	multi.Start()
+//	multi.Wait()
+func (m *MultiScan) Wait() {
+	m.wg.Wait()
+}
+
+// Reports returns the report of each host, in the order of Hosts. A host
+// that could not be resolved gets a report naming only the target; its
+// error is in Errors.
+//
+// Returns:
+// - The report of each host.
+//
+// Example:
+//
+//	multi.Wait()
+//	for _, report := range multi.Reports() {
+//	    fmt.Println(report.Target, report.Cancelled)
+//	}
+func (m *MultiScan) Reports() []ScanReport {
+	reports := make([]ScanReport, len(m.Hosts))
+	for i, scanner := range m.Scanners {
+		if scanner == nil {
+			reports[i] = ScanReport{Target: m.Hosts[i]}
+			continue
+		}
+		reports[i] = scanner.Report()
+	}
+	return reports
+}
+
+// ScanTargets scans several hosts concurrently and waits for every scan, as
+// a MultiScan that is started right away.
+//
+// Parameters:
+// - ctx: Cancels the scans.
+// - hosts: The host names to scan.
+// - numWorkers: The number of worker goroutines of each scan.
+// - resolver: The resolver used to look up the hosts.
+//
+// Returns:
+// - The scanner of each host, in the order of hosts, nil if it could not be resolved.
+// - The resolution error of each host, nil on success, in the order of hosts.
+//
+// Example:
+//
+//	scanners, errs := ScanTargets(ctx, []string{"example.com", "example.org"}, 100, net.DefaultResolver)
+//	for i, scanner := range scanners {
+//	    if errs[i] == nil {
+//	        writeResultsToFile(scanner, hosts[i]+".txt")
+//	    }
+//	}
+func ScanTargets(ctx context.Context, hosts []string, numWorkers int, resolver Resolver) ([]*PortScanner, []error) {
+	m := NewMultiScan(ctx, hosts, numWorkers, resolver)
+	m.Start()
+	m.Wait()
+	return m.Scanners, m.Errors
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// hangingProber blocks every probe until its scan is cancelled.
+var hangingProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+	<-ctx.Done()
+	return StateFiltered, nil
+})
+
+func TestMultiScan_CancelOneTarget(t *testing.T) {
+	resolver := &stubResolver{hosts: map[string][]string{
+		"hang.example": {"192.0.2.1"},
+		"ok.example":   {"192.0.2.2"},
+	}}
+	multi := NewMultiScan(context.Background(), []string{"hang.example", "ok.example"}, 2, resolver)
+	for i, scanner := range multi.Scanners {
+		if scanner == nil {
+			t.Fatalf("Expected %s to resolve, got %s", multi.Hosts[i], multi.Errors[i])
+		}
+		scanner.Ports = []int{22, 80}
+		scanner.Quiet = true
+		scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+		scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+		scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateOpen, nil })
+	}
+	multi.Scanners[0].TCPProber = hangingProber
+
+	multi.Start()
+	// The healthy target finishes while the other one hangs
+	finished := make(chan struct{})
+	go func() {
+		for multi.Scanners[1].Report().Meta.End.IsZero() {
+			time.Sleep(10 * time.Millisecond)
+		}
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected ok.example to complete while hang.example hangs")
+	}
+
+	if !multi.Cancel("hang.example") {
+		t.Fatalf("Expected hang.example to be cancellable")
+	}
+	waited := make(chan struct{})
+	go func() {
+		multi.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the cancelled scan to stop")
+	}
+
+	reports := multi.Reports()
+	if !reports[0].Cancelled {
+		t.Errorf("Expected hang.example to be marked cancelled")
+	}
+	if reports[1].Cancelled || len(reports[1].TCP) != 2 {
+		t.Errorf("Expected ok.example to complete with 2 open TCP ports, got %+v", reports[1].TCP)
+	}
+	if multi.Cancel("missing.example") {
+		t.Errorf("Expected no scan to cancel for an unknown host")
+	}
+}
+
+func TestNewMultiScan_Ranges(t *testing.T) {
+	resolver := &stubResolver{hosts: map[string][]string{"ok.example": {"192.0.2.9"}}}
+	targets := []string{"ok.example", "192.0.2.0/30", "192.0.2.1-3", "unix:///run/app.sock"}
+	multi := NewMultiScan(context.Background(), targets, 2, resolver)
+	for i, expected := range []int{1, 4, 3, 0} {
+		scanner := multi.Scanners[i]
+		if scanner == nil {
+			t.Fatalf("Expected a scanner for %s, got %s", targets[i], multi.Errors[i])
+		}
+		if len(scanner.IPs) != expected {
+			t.Errorf("Expected %d IPs for %s, got %v", expected, targets[i], scanner.IPs)
+		}
+	}
+	if multi.Scanners[3].UnixSocket != "/run/app.sock" {
+		t.Errorf("Expected a Unix socket target, got %q", multi.Scanners[3].UnixSocket)
+	}
+}
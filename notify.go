@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultWebhookTimeout bounds a WebhookNotifier POST, so an unresponsive
+// endpoint cannot hold up the end of the scan for long.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// WebhookNotifier returns an OnComplete hook that POSTs the scan report as
+// JSON to url, e.g. a Slack or incident system webhook.
+//
+// Parameters:
+// - url: The URL to post the report to.
+//
+// Returns:
+// - The hook, failing when the POST fails or gets a non-2xx status.
+//
+// Example:
+//
+//	scanner.OnComplete = WebhookNotifier("https://hooks.example.com/scan")
+func WebhookNotifier(url string) func(ScanReport) error {
+	client := &http.Client{Timeout: DefaultWebhookTimeout}
+	return func(report ScanReport) error {
+		body, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("error encoding report: %s", err)
+		}
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("error posting report: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("error posting report: %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// complete calls OnComplete, if set, with the finished scan's report. A
+// failing hook does not fail the scan: its error is logged and kept in
+// Errors.
+func (t *PortScanner) complete() {
+	if t.OnComplete == nil {
+		return
+	}
+	if err := t.OnComplete(t.Report()); err != nil {
+		t.logf("Error running the completion hook: %s\n", err)
+		t.addError("on-complete", t.Domain, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubbedScanner returns a scanner of 127.0.0.1 ports 22 and 80 where only
+// port 22 is open and nothing touches the network.
+func stubbedScanner(t *testing.T) *PortScanner {
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{22, 80}
+	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		if port == 22 {
+			return StateOpen, nil
+		}
+		return StateClosed, nil
+	})
+	scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	scanner.Quiet = true
+	return scanner
+}
+
+func TestWebhookNotifier_PostsReport(t *testing.T) {
+	received := make(chan ScanReport, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Expected a JSON POST, got %s with %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var report ScanReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			t.Errorf("Decoding the posted report failed: %s", err)
+		}
+		received <- report
+	}))
+	defer server.Close()
+
+	scanner := stubbedScanner(t)
+	scanner.OnComplete = WebhookNotifier(server.URL)
+	scanner.Scan()
+
+	select {
+	case report := <-received:
+		if report.Target != "127.0.0.1" || len(report.TCP) != 1 || report.TCP[0].Port != 22 {
+			t.Errorf("Expected the report of open port 22 on 127.0.0.1, got %+v", report)
+		}
+	default:
+		t.Fatalf("Expected the report to be posted when the scan completes")
+	}
+	if len(scanner.Errors) != 0 {
+		t.Errorf("Expected no errors, got %v", scanner.Errors)
+	}
+	if report := scanner.Report(); len(report.TCP) != 1 {
+		t.Errorf("Expected the report to stay available after the hook, got %d TCP results", len(report.TCP))
+	}
+}
+
+func TestWebhookNotifier_FailureDoesNotFailScan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	scanner := stubbedScanner(t)
+	scanner.OnComplete = WebhookNotifier(server.URL)
+	scanner.Scan()
+
+	if report := scanner.Report(); len(report.TCP) != 1 {
+		t.Errorf("Expected the scan results despite the failed webhook, got %d TCP results", len(report.TCP))
+	}
+	if len(scanner.Errors) != 1 {
+		t.Fatalf("Expected the webhook failure in Errors, got %v", scanner.Errors)
+	}
+	if scanErr, ok := scanner.Errors[0].(*ScanError); !ok || scanErr.Stage != "on-complete" {
+		t.Errorf("Expected an on-complete error, got %v", scanner.Errors[0])
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// OrderStrategy decides the order in which a scan enqueues its ports. Every
+// port is probed on all IPs before the next one, so the strategy picks which
+// ports are probed first.
+type OrderStrategy interface {
+	// Order returns ports in enqueue order. It returns a new slice and must
+	// keep every port exactly once.
+	Order(ports []int) []int
+}
+
+// SequentialOrder enqueues the ports in ascending order, for a predictable
+// sweep that is easy to follow and to resume.
+type SequentialOrder struct{}
+
+// Order returns the ports sorted in ascending order.
+func (SequentialOrder) Order(ports []int) []int {
+	ordered := append([]int{}, ports...)
+	sort.Ints(ordered)
+	return ordered
+}
+
+// RandomOrder enqueues the ports in a random order, so the probes do not
+// form the ascending sweep that intrusion detection systems look for.
+//
+// Fields:
+// - Seed: The seed of the shuffle, making the order reproducible when non-zero.
+type RandomOrder struct {
+	Seed int64
+}
+
+// Order returns the ports shuffled.
+func (o RandomOrder) Order(ports []int) []int {
+	seed := o.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	ordered := append([]int{}, ports...)
+	rnd.Shuffle(len(ordered), func(i, j int) {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	})
+	return ordered
+}
+
+// ServicePriorityOrder enqueues the ports most likely to be open first, so
+// a scan finds its first open ports quickly. The prioritized ports keep the
+// order of Priority and the rest follow in ascending order.
+//
+// Fields:
+// - Priority: The ports to scan first, most likely open first. TopPorts when empty.
+type ServicePriorityOrder struct {
+	Priority []int
+}
+
+// Order returns the ports in Priority order, followed by the others sorted.
+func (o ServicePriorityOrder) Order(ports []int) []int {
+	priority := o.Priority
+	if len(priority) == 0 {
+		priority = TopPorts
+	}
+	rank := make(map[int]int, len(priority))
+	for i, port := range priority {
+		if _, seen := rank[port]; !seen {
+			rank[port] = i
+		}
+	}
+
+	ordered := append([]int{}, ports...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		ri, iPriority := rank[ordered[i]]
+		rj, jPriority := rank[ordered[j]]
+		switch {
+		case iPriority && jPriority:
+			return ri < rj
+		case iPriority != jPriority:
+			return iPriority
+		default:
+			return ordered[i] < ordered[j]
+		}
+	})
+	return ordered
+}
+
+// orderedPorts returns ports in the order of the scanner's Order strategy,
+// or unchanged when none is set.
+//
+// Parameters:
+// - ports: The ports to scan.
+//
+// Returns:
+// - The ports in enqueue order.
+func (t *PortScanner) orderedPorts(ports []int) []int {
+	if t.Order == nil {
+		return ports
+	}
+	return t.Order.Order(ports)
+}
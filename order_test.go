@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestSequentialOrder(t *testing.T) {
+	ports := []int{443, 22, 8080, 80}
+	ordered := SequentialOrder{}.Order(ports)
+	if expected := []int{22, 80, 443, 8080}; !reflect.DeepEqual(ordered, expected) {
+		t.Errorf("Expected %v, got %v", expected, ordered)
+	}
+	if ports[0] != 443 {
+		t.Errorf("Expected the input ports unchanged, got %v", ports)
+	}
+}
+
+func TestRandomOrder(t *testing.T) {
+	ports := allPorts()[:1000]
+	first := RandomOrder{Seed: 42}.Order(ports)
+	if !reflect.DeepEqual(first, RandomOrder{Seed: 42}.Order(ports)) {
+		t.Errorf("Expected the same order for the same seed")
+	}
+	if reflect.DeepEqual(first, ports) {
+		t.Errorf("Expected the ports shuffled, got them in ascending order")
+	}
+	sorted := append([]int{}, first...)
+	sort.Ints(sorted)
+	if !reflect.DeepEqual(sorted, ports) {
+		t.Errorf("Expected a permutation of the ports")
+	}
+	if ports[0] != 1 || ports[999] != 1000 {
+		t.Errorf("Expected the input ports unchanged")
+	}
+}
+
+func TestServicePriorityOrder(t *testing.T) {
+	tests := []struct {
+		priority []int
+		ports    []int
+		expected []int
+	}{
+		{nil, []int{1, 5000, 443, 2, 22, 80}, []int{22, 80, 443, 5000, 1, 2}},
+		{[]int{3306, 22}, []int{80, 22, 1, 3306}, []int{3306, 22, 1, 80}},
+		{[]int{9999}, []int{3, 2, 1}, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		ordered := ServicePriorityOrder{Priority: tt.priority}.Order(tt.ports)
+		if !reflect.DeepEqual(ordered, tt.expected) {
+			t.Errorf("Priority %v: Expected %v, got %v", tt.priority, tt.expected, ordered)
+		}
+	}
+}
+
+func TestScan_OrderStrategy(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{1, 2, 80, 22}
+	scanner.Order = ServicePriorityOrder{}
+	scanner.EnableTCP = true
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+
+	var mu sync.Mutex
+	var probed []int
+	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		mu.Lock()
+		probed = append(probed, port)
+		mu.Unlock()
+		return StateClosed, nil
+	})
+	scanner.Quiet = true
+	scanner.Scan()
+
+	if expected := []int{22, 80, 1, 2}; !reflect.DeepEqual(probed, expected) {
+		t.Errorf("Expected the ports probed in order %v, got %v", expected, probed)
+	}
+}
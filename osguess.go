@@ -0,0 +1,72 @@
+package main
+
+// Operating system families guessed from the TTL of a response.
+const (
+	OSUnix          = "Linux/Unix"
+	OSWindows       = "Windows"
+	OSNetworkDevice = "Network device"
+)
+
+// GuessOS makes a coarse, passive guess of the remote operating system from
+// the TTL of a response. Systems start from a few well-known TTLs, 64 for
+// Linux and Unix, 128 for Windows and 255 for routers and other networking
+// gear, which every hop decrements, so the guess is the smallest of them at
+// or above ttl.
+//
+// Parameters:
+// - ttl: The TTL of the received IP header.
+//
+// Returns:
+// - OSUnix, OSWindows or OSNetworkDevice, or "" if ttl is unknown.
+//
+// Example:
+//
+//	GuessOS(57) // "Linux/Unix", 7 hops away
+func GuessOS(ttl int) string {
+	switch {
+	case ttl <= 0 || ttl > 255:
+		return ""
+	case ttl <= 64:
+		return OSUnix
+	case ttl <= 128:
+		return OSWindows
+	}
+	return OSNetworkDevice
+}
+
+// ParseIPv4TTL reads the TTL from the header of a raw IPv4 datagram.
+//
+// Parameters:
+// - b: The datagram, starting with its IP header.
+//
+// Returns:
+// - The TTL.
+// - false if b does not start with an IPv4 header.
+//
+// Example:
+//
+//	ttl, ok := ParseIPv4TTL(buf[:n])
+func ParseIPv4TTL(b []byte) (int, bool) {
+	if len(b) < 20 || b[0]>>4 != 4 {
+		return 0, false
+	}
+	return int(b[8]), true
+}
+
+// receivedTTL returns the TTL of a datagram read from a raw socket, from the
+// control message enabled by enableTTL or, where the platform does not
+// report it, from the IP header the socket left in front of the message.
+//
+// Parameters:
+// - packet: The datagram as read.
+// - oob: The control messages read with it.
+//
+// Returns:
+// - The TTL, 0 if unknown.
+func receivedTTL(packet, oob []byte) int {
+	if ttl := parseTTL(oob); ttl > 0 {
+		return ttl
+	}
+	ttl, _ := ParseIPv4TTL(packet)
+	return ttl
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+// Recorded echo replies with their IPv4 header, from hosts at different
+// distances: a Linux host one hop away (TTL 64), a Windows host 11 hops away
+// (TTL 117) and a router 6 hops away (TTL 249).
+const (
+	recordedLinuxReply   = "45000020 1c460000 4001da8b c0000201 c000020a " + recordedEchoReply
+	recordedWindowsReply = "45000020 1c460000 7501a58a c0000202 c000020a " + recordedEchoReply
+	recordedRouterReply  = "45000020 1c460000 f9012189 c0000203 c000020a " + recordedEchoReply
+)
+
+func TestGuessOS_Recorded(t *testing.T) {
+	tests := []struct {
+		name     string
+		recorded string
+		ttl      int
+		expected string
+	}{
+		{"linux", recordedLinuxReply, 64, OSUnix},
+		{"windows", recordedWindowsReply, 117, OSWindows},
+		{"router", recordedRouterReply, 249, OSNetworkDevice},
+	}
+	for _, test := range tests {
+		packet := decodeHex(t, test.recorded)
+		ttl, ok := ParseIPv4TTL(packet)
+		if !ok || ttl != test.ttl {
+			t.Errorf("%s: Expected TTL %d, got %d (%v)", test.name, test.ttl, ttl, ok)
+		}
+		if ttl := receivedTTL(packet, nil); ttl != test.ttl {
+			t.Errorf("%s: Expected the header TTL %d without a control message, got %d", test.name, test.ttl, ttl)
+		}
+		if guess := GuessOS(ttl); guess != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, guess)
+		}
+		if msg, err := ParseICMP(stripIPv4Header(packet)); err != nil || ClassifyICMP(msg) != ICMPReachable {
+			t.Errorf("%s: Expected an echo reply after the header, got %+v (%v)", test.name, msg, err)
+		}
+	}
+}
+
+func TestGuessOS_Unknown(t *testing.T) {
+	for _, ttl := range []int{0, -1, 256} {
+		if guess := GuessOS(ttl); guess != "" {
+			t.Errorf("TTL %d: Expected no guess, got %q", ttl, guess)
+		}
+	}
+	if _, ok := ParseIPv4TTL(decodeHex(t, recordedEchoReply)); ok {
+		t.Errorf("Expected no TTL from a message without an IPv4 header")
+	}
+}
+
+func TestWorkerICMP_GuessesOS(t *testing.T) {
+	scanner := &PortScanner{Quiet: true, pinger: func(ip string) ICMPResult {
+		return ICMPResult{IP: ip, Reachable: true, State: ICMPReachable, TTL: 120}
+	}}
+	ips := make(chan string, 1)
+	ips <- "192.0.2.1"
+	close(ips)
+	results := make(chan ICMPResult, 1)
+	scanner.WorkerICMP(ips, results, make(chan bool, 1))
+
+	if result := <-results; result.OSGuess != OSWindows {
+		t.Errorf("Expected %q in the per-IP result, got %+v", OSWindows, result)
+	}
+}
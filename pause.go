@@ -0,0 +1,68 @@
+package main
+
+import "context"
+
+// Pause stops the workers from starting new probes until Resume is called.
+// Probes already in flight finish and the scan keeps its state, so a paused
+// scan can be resumed where it stopped. Cancelling the scan's context still
+// stops a paused scan. Pausing a paused scan has no effect.
+//
+// Example:
+//
+//	go scanner.Scan()
+//	scanner.Pause()
+//	// ...
+//	scanner.Resume()
+func (t *PortScanner) Pause() {
+	t.pauseMu.Lock()
+	defer t.pauseMu.Unlock()
+	if t.resumed == nil {
+		t.resumed = make(chan struct{})
+	}
+}
+
+// Resume lets the workers of a paused scan start new probes again. Resuming
+// a scan that is not paused has no effect.
+//
+// Example:
+//
+//	scanner.Resume()
+func (t *PortScanner) Resume() {
+	t.pauseMu.Lock()
+	defer t.pauseMu.Unlock()
+	if t.resumed != nil {
+		close(t.resumed)
+		t.resumed = nil
+	}
+}
+
+// Paused reports whether the scan is paused.
+//
+// Returns:
+// - true between Pause and Resume.
+func (t *PortScanner) Paused() bool {
+	t.pauseMu.Lock()
+	defer t.pauseMu.Unlock()
+	return t.resumed != nil
+}
+
+// waitResumed blocks while the scan is paused, returning early if ctx is
+// cancelled.
+//
+// Parameters:
+// - ctx: The context that interrupts the wait when cancelled.
+func (t *PortScanner) waitResumed(ctx context.Context) {
+	for {
+		t.pauseMu.Lock()
+		resumed := t.resumed
+		t.pauseMu.Unlock()
+		if resumed == nil {
+			return
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
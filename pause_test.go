@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// dialCount returns the number of dials the mock dialer has seen.
+func (m *mockDialer) dialCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.addrs)
+}
+
+func TestPauseResume(t *testing.T) {
+	dialer := &mockDialer{delay: 2 * time.Millisecond}
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = make([]int, 100)
+	for i := range scanner.Ports {
+		scanner.Ports[i] = 40001 + i
+	}
+	scanner.Dial = dialer.Dial
+	scanner.Quiet = true
+
+	done := make(chan struct{})
+	go func() {
+		scanner.Scan()
+		close(done)
+	}()
+
+	for dialer.dialCount() < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	scanner.Pause()
+	if !scanner.Paused() {
+		t.Errorf("Expected the scan to be paused")
+	}
+
+	// Let the in-flight probes finish, then check no new ones start
+	time.Sleep(20 * time.Millisecond)
+	paused := dialer.dialCount()
+	time.Sleep(100 * time.Millisecond)
+	if count := dialer.dialCount(); count != paused {
+		t.Errorf("Expected no probes while paused, got %d new ones", count-paused)
+	}
+
+	scanner.Resume()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("Expected the scan to complete after Resume")
+	}
+	if count := dialer.dialCount(); count != 2*len(scanner.Ports) {
+		t.Errorf("Expected %d probes after resuming, got %d", 2*len(scanner.Ports), count)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// pcapMagic marks a pcap file with microsecond timestamps.
+	pcapMagic = 0xa1b2c3d4
+	// pcapSnapLen is the largest packet a capture holds.
+	pcapSnapLen = 65535
+	// pcapLinkTypeRaw means every packet starts with its IP header.
+	pcapLinkTypeRaw = 101
+)
+
+// PcapWriter writes packets in the classic pcap format read by tcpdump and
+// Wireshark, each packet being a raw IPv4 datagram.
+//
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	file, _ := os.Create("scan.pcap")
+//	pcap, err := NewPcapWriter(file)
+//	if err == nil {
+//	    pcap.WritePacket(time.Now(), packet)
+//	}
+type PcapWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewPcapWriter writes the pcap file header and returns a writer for the
+// packets that follow it.
+//
+// Parameters:
+// - w: Where to write the capture.
+//
+// Returns:
+// - A pointer to the new PcapWriter.
+// - An error if the file header cannot be written.
+func NewPcapWriter(w io.Writer) (*PcapWriter, error) {
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(header[4:], 2) // version 2.4
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(header[20:], pcapLinkTypeRaw)
+	if _, err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("error writing pcap header: %s", err)
+	}
+	return &PcapWriter{w: w}, nil
+}
+
+// WritePacket appends a packet to the capture.
+//
+// Parameters:
+// - ts: When the packet was sent or received.
+// - packet: The IPv4 datagram, starting with its IP header.
+//
+// Returns:
+// - An error if the packet cannot be written.
+func (p *PcapWriter) WritePacket(ts time.Time, packet []byte) error {
+	captured := packet
+	if len(captured) > pcapSnapLen {
+		captured = captured[:pcapSnapLen]
+	}
+	record := make([]byte, 16, 16+len(captured))
+	binary.LittleEndian.PutUint32(record[0:], uint32(ts.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(ts.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(captured)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(packet)))
+	record = append(record, captured...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.w.Write(record); err != nil {
+		return fmt.Errorf("error writing pcap packet: %s", err)
+	}
+	return nil
+}
+
+// ipv4Packet wraps a payload sent through a raw socket, where the kernel adds
+// the IP header, in the header it gets on the wire, so it can be captured.
+//
+// Parameters:
+// - src: The source address.
+// - dst: The destination address.
+// - proto: The IP protocol of the payload, e.g. protoICMP.
+// - payload: The ICMP message or TCP segment.
+//
+// Returns:
+// - The IPv4 datagram.
+func ipv4Packet(src, dst net.IP, proto int, payload []byte) []byte {
+	packet := make([]byte, 20+len(payload))
+	packet[0] = 0x45 // version 4, 20 byte header
+	binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)))
+	packet[8] = 64 // TTL
+	packet[9] = byte(proto)
+	copy(packet[12:16], src.To4())
+	copy(packet[16:20], dst.To4())
+	binary.BigEndian.PutUint16(packet[10:], icmpChecksum(packet[:20]))
+	copy(packet[20:], payload)
+	return packet
+}
+
+// openPcap creates PcapFile and returns a writer for it with a function that
+// closes the file, or a nil writer if no capture is configured.
+//
+// Returns:
+// - The PcapWriter, nil without PcapFile.
+// - A function that closes the file.
+// - An error if the file cannot be created.
+func (t *PortScanner) openPcap() (*PcapWriter, func(), error) {
+	if t.PcapFile == "" {
+		return nil, func() {}, nil
+	}
+	file, err := os.Create(t.PcapFile)
+	if err != nil {
+		return nil, func() {}, fmt.Errorf("error creating pcap file: %s", err)
+	}
+	pcap, err := NewPcapWriter(file)
+	if err != nil {
+		file.Close()
+		return nil, func() {}, err
+	}
+	return pcap, func() { file.Close() }, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readPcap decodes the packets of a pcap file written by PcapWriter.
+func readPcap(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	if len(data) < 24 || binary.LittleEndian.Uint32(data) != pcapMagic || binary.LittleEndian.Uint32(data[20:]) != pcapLinkTypeRaw {
+		t.Fatalf("Invalid pcap header")
+	}
+	var packets [][]byte
+	for rest := data[24:]; len(rest) > 0; {
+		if len(rest) < 16 {
+			t.Fatalf("Truncated pcap record header")
+		}
+		size := int(binary.LittleEndian.Uint32(rest[8:]))
+		if len(rest) < 16+size {
+			t.Fatalf("Truncated pcap record")
+		}
+		packets = append(packets, rest[16:16+size])
+		rest = rest[16+size:]
+	}
+	return packets
+}
+
+func TestPcapWriter_Format(t *testing.T) {
+	var buf bytes.Buffer
+	pcap, err := NewPcapWriter(&buf)
+	if err != nil {
+		t.Fatalf("NewPcapWriter failed: %s", err)
+	}
+	echo := MarshalEcho(0x1234, 1, []byte("ping"))
+	packet := ipv4Packet(net.ParseIP("192.0.2.10"), net.ParseIP("192.0.2.1"), protoICMP, echo)
+	if err := pcap.WritePacket(time.Unix(1700000000, 5000), packet); err != nil {
+		t.Fatalf("WritePacket failed: %s", err)
+	}
+
+	packets := readPcap(t, buf.Bytes())
+	if len(packets) != 1 || !bytes.Equal(packets[0], packet) {
+		t.Fatalf("Expected the packet back, got %x", packets)
+	}
+	if icmpChecksum(packet[:20]) != 0 {
+		t.Errorf("Expected a valid IPv4 header checksum")
+	}
+	msg, err := ParseICMP(stripIPv4Header(packets[0]))
+	if err != nil || msg.Type != icmpEcho || msg.ID != 0x1234 {
+		t.Errorf("Expected the echo request inside the captured datagram, got %+v (%v)", msg, err)
+	}
+}
+
+func TestScan_WritesPcap(t *testing.T) {
+	if !Privileged() {
+		t.Skip("packet capture needs raw sockets")
+	}
+	scanner, err := NewTarget("127.0.0.1", 1)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	closed := ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+	scanner.Ports = []int{1}
+	scanner.TCPProber = closed
+	scanner.UDPProber = closed
+	scanner.Quiet = true
+	scanner.PcapFile = filepath.Join(t.TempDir(), "scan.pcap")
+	scanner.Scan()
+
+	data, err := os.ReadFile(scanner.PcapFile)
+	if err != nil {
+		t.Fatalf("Reading the capture failed: %s", err)
+	}
+	types := map[int]bool{}
+	for _, packet := range readPcap(t, data) {
+		if msg, err := ParseICMP(stripIPv4Header(packet)); err == nil {
+			types[msg.Type] = true
+		}
+	}
+	if !types[icmpEcho] || !types[icmpEchoReply] {
+		t.Errorf("Expected the echo request and reply in the capture, got ICMP types %v", types)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// PortGroups maps the name of a kind of service to its usual ports, so a
+// scan can target e.g. every web port without listing them. Entries can be
+// added or replaced before calling NewTargetGroup.
+var PortGroups = map[string][]int{
+	"web":    {80, 443, 591, 3000, 5000, 8000, 8008, 8080, 8081, 8443, 8888, 9443},
+	"db":     {1433, 1521, 3306, 5432, 5984, 6379, 7000, 7199, 9042, 9200, 11211, 27017},
+	"mail":   {25, 110, 143, 465, 587, 993, 995},
+	"remote": {22, 23, 3389, 5900, 5985, 5986},
+	"file":   {20, 21, 69, 139, 445, 873, 2049},
+}
+
+// PortGroup returns the ports of a named group from PortGroups.
+//
+// Parameters:
+// - name: The group name, e.g. "web" or "db".
+//
+// Returns:
+// - The group's ports in ascending order, nil if the group is unknown.
+//
+// Example:
+//
+//	ports := PortGroup("web") // [80 443 591 3000 ...]
+func PortGroup(name string) []int {
+	group, ok := PortGroups[name]
+	if !ok {
+		return nil
+	}
+	ports := append([]int(nil), group...)
+	sort.Ints(ports)
+	return ports
+}
+
+// NewTargetGroup creates a new PortScanner instance that scans only the
+// ports of the named groups.
+//
+// Parameters:
+// - domain: The domain to scan.
+// - numWorkers: The number of worker goroutines to use for scanning.
+// - groups: The names of the port groups to scan, merged without duplicates.
+//
+// Returns:
+// - A pointer to a newly created PortScanner instance.
+// - An error if numWorkers is below 1, a group is unknown or the domain cannot be resolved.
+//
+// Example:
+//
+//	scanner, err := NewTargetGroup("example.com", 100, "web", "db")
+func NewTargetGroup(domain string, numWorkers int, groups ...string) (*PortScanner, error) {
+	if err := validateWorkers(numWorkers); err != nil {
+		return nil, err
+	}
+	ports, err := groupPorts(groups)
+	if err != nil {
+		return nil, err
+	}
+	resolution, err := Resolve(net.DefaultResolver, domain)
+	if err != nil {
+		return nil, err
+	}
+	t := newTargetFromResolution(domain, numWorkers, resolution, false)
+	t.Ports = ports
+	return t, nil
+}
+
+// groupPorts merges the ports of the named groups in ascending order.
+func groupPorts(groups []string) ([]int, error) {
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no port group given")
+	}
+	seen := map[int]bool{}
+	var ports []int
+	for _, name := range groups {
+		group := PortGroup(name)
+		if group == nil {
+			return nil, fmt.Errorf("unknown port group %q", name)
+		}
+		for _, port := range group {
+			if !seen[port] {
+				seen[port] = true
+				ports = append(ports, port)
+			}
+		}
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPortGroup_Web(t *testing.T) {
+	expected := []int{80, 443, 591, 3000, 5000, 8000, 8008, 8080, 8081, 8443, 8888, 9443}
+	if ports := PortGroup("web"); !reflect.DeepEqual(ports, expected) {
+		t.Errorf("Expected %v, got %v", expected, ports)
+	}
+	if ports := PortGroup("nonexistent"); ports != nil {
+		t.Errorf("Expected nil for an unknown group, got %v", ports)
+	}
+}
+
+func TestNewTargetGroup(t *testing.T) {
+	scanner, err := NewTargetGroup("127.0.0.1", 1, "mail", "remote", "mail")
+	if err != nil {
+		t.Fatalf("NewTargetGroup failed: %s", err)
+	}
+	expected := []int{22, 23, 25, 110, 143, 465, 587, 993, 995, 3389, 5900, 5985, 5986}
+	if !reflect.DeepEqual(scanner.Ports, expected) {
+		t.Errorf("Expected ports %v, got %v", expected, scanner.Ports)
+	}
+
+	if _, err := NewTargetGroup("127.0.0.1", 1, "web", "nonexistent"); err == nil {
+		t.Errorf("Expected an error for an unknown group")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KnownPortsSpec is the port specification selecting the ports of every
+// known service.
+const KnownPortsSpec = "known"
+
+// ParsePortSpec parses a port specification such as "22,80,8000-8100" into
+// a sorted list of unique ports.
+//
+// Parameters:
+// - spec: Comma separated ports and inclusive ranges, each within 1-65535.
+//
+// Returns:
+// - The ports in ascending order without duplicates.
+// - An error if the specification is empty or malformed.
+//
+// Example:
+//
+//	ports, err := ParsePortSpec("1-1024,8080")
+func ParsePortSpec(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		low, high := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			low, high = part[:i], part[i+1:]
+		}
+		first, err := parsePort(low)
+		if err != nil {
+			return nil, err
+		}
+		last, err := parsePort(high)
+		if err != nil {
+			return nil, err
+		}
+		if first > last {
+			return nil, fmt.Errorf("invalid port range %q", part)
+		}
+		for port := first; port <= last; port++ {
+			seen[port] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("empty port specification %q", spec)
+	}
+
+	ports := make([]int, 0, len(seen))
+	for port := 1; port <= 65535; port++ {
+		if seen[port] {
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+// parsePort parses a single port number within 1-65535.
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return port, nil
+}
+
+// FormatPortSpec formats ports as a port specification accepted by
+// ParsePortSpec, collapsing consecutive ports into ranges.
+//
+// Parameters:
+// - ports: The ports, in any order.
+//
+// Returns:
+// - The specification, e.g. "22,80-81,443", or "" for no ports.
+//
+// Example:
+//
+//	spec := FormatPortSpec([]int{443, 80, 81, 22}) // "22,80-81,443"
+func FormatPortSpec(ports []int) string {
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[j] == sorted[i] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, strconv.Itoa(sorted[i])+"-"+strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// PortsFromServices returns the ports of a service map, for a scan of only
+// the ports where a service is known.
+//
+// Parameters:
+// - services: A map of services keyed by port, e.g. service.Services.
+//
+// Returns:
+// - The valid ports (1-65535) of the map in ascending order.
+//
+// Example:
+//
+//	target.Ports = PortsFromServices(target.Services)
+func PortsFromServices(services map[int]string) []int {
+	ports := make([]int, 0, len(services))
+	for port := range services {
+		if port >= 1 && port <= 65535 {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
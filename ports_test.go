@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"det/service"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected []int
+	}{
+		{"80", []int{80}},
+		{"22,80,443", []int{22, 80, 443}},
+		{"1-3", []int{1, 2, 3}},
+		{"8080, 1-2 ,80,2", []int{1, 2, 80, 8080}},
+		{"65535", []int{65535}},
+	}
+	for _, test := range tests {
+		got, err := ParsePortSpec(test.spec)
+		if err != nil {
+			t.Errorf("Spec %q: unexpected error %s", test.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Spec %q: Expected %v, got %v", test.spec, test.expected, got)
+		}
+	}
+}
+
+func TestParsePortSpec_Invalid(t *testing.T) {
+	for _, spec := range []string{"", ",", "0", "65536", "a", "10-5", "1-", "-5", "1-2-3"} {
+		if ports, err := ParsePortSpec(spec); err == nil {
+			t.Errorf("Spec %q: Expected an error, got %v", spec, ports)
+		}
+	}
+}
+
+func TestPortsFromServices(t *testing.T) {
+	services := map[int]string{443: "https", 22: "ssh", 80: "http", 0: "reserved", 70000: "invalid"}
+	expected := []int{22, 80, 443}
+	if got := PortsFromServices(services); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	ports := PortsFromServices(service.Services)
+	for _, port := range ports {
+		if _, ok := service.Services[port]; !ok {
+			t.Errorf("Port %d is not in the services map", port)
+		}
+	}
+	if !sort.IntsAreSorted(ports) {
+		t.Errorf("Expected the ports in ascending order")
+	}
+	if len(PortsFromServices(nil)) != 0 {
+		t.Errorf("Expected no ports for a nil map")
+	}
+}
+
+// FuzzParsePortSpec checks that no specification makes ParsePortSpec panic
+// and that parsed ports are sorted, unique and within 1-65535. Run it with:
+//
+//	go test -run '^$' -fuzz FuzzParsePortSpec -fuzztime 30s
+func FuzzParsePortSpec(f *testing.F) {
+	for _, seed := range []string{"80", "22,80,443", "1-1024,8080", "8080, 1-2 ,80,2", "65535", "0", "70000", "5-1", "1-", "-", ",", "", " 1 - 3 ", "+1", "0x50"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, spec string) {
+		ports, err := ParsePortSpec(spec)
+		if err != nil {
+			return
+		}
+		if len(ports) == 0 {
+			t.Fatalf("Expected an error for an empty result from %q", spec)
+		}
+		for i, port := range ports {
+			if port < 1 || port > 65535 {
+				t.Fatalf("Port %d out of range from %q", port, spec)
+			}
+			if i > 0 && port <= ports[i-1] {
+				t.Fatalf("Ports not sorted and unique from %q: %v", spec, ports)
+			}
+		}
+	})
+}
+
+func TestFormatPortSpec(t *testing.T) {
+	tests := []struct {
+		ports    []int
+		expected string
+	}{
+		{nil, ""},
+		{[]int{80}, "80"},
+		{[]int{443, 80, 81, 22}, "22,80-81,443"},
+		{[]int{3, 1, 2, 2}, "1-3"},
+		{allPorts(), "1-65535"},
+	}
+	for _, tt := range tests {
+		if spec := FormatPortSpec(tt.ports); spec != tt.expected {
+			t.Errorf("Expected %q for %v, got %q", tt.expected, tt.ports, spec)
+		}
+		if tt.expected == "" {
+			continue
+		}
+		if ports, err := ParsePortSpec(tt.expected); err != nil || FormatPortSpec(ports) != tt.expected {
+			t.Errorf("Expected %q to round-trip through ParsePortSpec, got %v (%v)", tt.expected, ports, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ICMPReachableTCP is the reachability state of a host that answered a TCP
+// connect when ICMP could not be used.
+const ICMPReachableTCP = "Reachable (TCP connect)"
+
+// reachabilityPorts are the TCP ports connected to when checking reachability
+// without ICMP.
+var reachabilityPorts = []int{80, 443}
+
+// listenRaw opens a raw socket. It is a variable so tests can simulate
+// missing privileges.
+var listenRaw = net.ListenPacket
+
+// Privileged reports whether raw sockets can be opened, which ICMP pings,
+// FIN/NULL/Xmas scans and fast UDP closes need. This usually requires root or
+// CAP_NET_RAW.
+//
+// Returns:
+// - true if a raw ICMP socket could be opened.
+//
+// Example:
+//
+//	if !Privileged() {
+//	    fmt.Println("running unprivileged, ICMP falls back to TCP connects")
+//	}
+func Privileged() bool {
+	conn, err := listenRaw("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// warnUnprivileged logs, once per scanner, that raw sockets are unavailable.
+//
+// Parameters:
+// - err: The error opening the raw socket.
+func (t *PortScanner) warnUnprivileged(err error) {
+	t.rawWarning.Do(func() {
+		t.logf("Warning: raw sockets are unavailable (%s), checking reachability with TCP connects instead of ICMP\n", err)
+		t.addError("icmp", "", err)
+	})
+}
+
+// dialReachability checks whether a host is up without ICMP by connecting to
+// a few common TCP ports. Both an accepted and a refused connect prove the
+// host answered.
+//
+// Parameters:
+// - ip: The IP address to check.
+// - timeout: The connect timeout for each port.
+//
+// Returns:
+// - The ICMPResult, Reachable with the connect RTT if the host answered.
+func (t *PortScanner) dialReachability(ip string, timeout time.Duration) ICMPResult {
+	result := ICMPResult{IP: ip, State: ICMPNoReply}
+	for _, port := range reachabilityPorts {
+		start := time.Now()
+		conn, err := t.dial()("tcp", net.JoinHostPort(ip, strconv.Itoa(port)), timeout)
+		if err == nil {
+			conn.Close()
+		}
+		if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+			result.Reachable = true
+			result.State = ICMPReachableTCP
+			result.RTT = time.Since(start)
+			return result
+		}
+	}
+	return result
+}
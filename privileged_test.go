@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// denyRaw makes raw sockets fail as they do without privileges until the
+// test ends.
+func denyRaw(t *testing.T) {
+	listenRaw = func(network, address string) (net.PacketConn, error) {
+		return nil, &net.OpError{Op: "listen", Net: network, Err: os.NewSyscallError("socket", syscall.EPERM)}
+	}
+	t.Cleanup(func() { listenRaw = net.ListenPacket })
+}
+
+func TestPrivileged_Denied(t *testing.T) {
+	denyRaw(t)
+	if Privileged() {
+		t.Errorf("Expected Privileged to be false when raw sockets are denied")
+	}
+}
+
+func TestScanICMP_FallsBackToConnect(t *testing.T) {
+	denyRaw(t)
+	dialer := &mockDialer{accept: func(address string) bool { return address == "127.0.0.1:443" }}
+	scanner := &PortScanner{Dial: dialer.Dial, Quiet: true}
+
+	result := scanner.scanICMP("127.0.0.1")
+	if !result.Reachable || result.State != ICMPReachableTCP {
+		t.Errorf("Expected %q through a TCP connect, got %+v", ICMPReachableTCP, result)
+	}
+
+	unreachable := &mockDialer{timeout: func(address string) bool { return true }}
+	scanner = &PortScanner{Dial: unreachable.Dial, Quiet: true}
+	if result := scanner.scanICMP("127.0.0.1"); result.Reachable || result.State != ICMPNoReply {
+		t.Errorf("Expected %q when no connect is answered, got %+v", ICMPNoReply, result)
+	}
+}
+
+func TestScan_UnprivilegedDoesNotFail(t *testing.T) {
+	denyRaw(t)
+	dialer := &mockDialer{}
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{22}
+	scanner.Dial = dialer.Dial
+	scanner.FastUDPClose = true
+	scanner.Quiet = true
+
+	scanner.Scan()
+
+	report := scanner.Report()
+	if len(report.TCP) != 1 {
+		t.Errorf("Expected the TCP scan to complete, got %v", report.TCP)
+	}
+	if len(report.ICMP) != 1 || report.ICMP[0].State != ICMPReachableTCP {
+		t.Errorf("Expected reachability from the TCP fallback, got %v", report.ICMP)
+	}
+}
+
+func TestScanICMP_FallbackHonorsTimeout(t *testing.T) {
+	denyRaw(t)
+	tests := []struct {
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{0, DefaultICMPTimeout},
+		{300 * time.Millisecond, 300 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		dialer := &mockDialer{timeout: func(address string) bool { return true }}
+		scanner := &PortScanner{Dial: dialer.Dial, Quiet: true, ICMPTimeout: tt.timeout}
+		scanner.scanICMP("127.0.0.1")
+
+		if len(dialer.timeouts) == 0 {
+			t.Fatalf("Expected the fallback to dial")
+		}
+		for _, timeout := range dialer.timeouts {
+			if timeout != tt.expected {
+				t.Errorf("Expected connect timeout %s for ICMPTimeout %s, got %s", tt.expected, tt.timeout, timeout)
+			}
+		}
+	}
+}
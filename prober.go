@@ -0,0 +1,193 @@
+package main
+
+import "context"
+
+// State is the scanned state of a port.
+type State string
+
+// Port states reported by probes.
+const (
+	StateOpen     State = "Open"
+	StateClosed   State = "Closed"
+	StateFiltered State = "Filtered"
+	StateError    State = "Error"
+)
+
+// Prober probes a single port with some scan technique, e.g. a TCP connect,
+// a SYN scan or a protocol handshake.
+//
+// Example:
+//
+//	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+//	    return StateOpen, nil
+//	})
+type Prober interface {
+	// Probe returns the state of port on ip, or an error if the port could
+	// not be probed at all.
+	Probe(ctx context.Context, ip string, port int) (State, error)
+}
+
+// BannerProber is a Prober that can also capture a banner identifying the
+// service, e.g. an SNMP sysDescr. Workers prefer ProbeBanner when a prober
+// implements it.
+type BannerProber interface {
+	Prober
+	// ProbeBanner returns the state of port on ip and the banner captured
+	// while probing it, if any.
+	ProbeBanner(ctx context.Context, ip string, port int) (State, string, error)
+}
+
+// ProbeDetail is everything a DetailedProber learned about a port.
+//
+// Fields:
+// - State: The state of the port.
+// - Banner: The banner captured while probing, if any.
+// - Reason: The signal the state was derived from, e.g. "timeout" or "connection refused".
+type ProbeDetail struct {
+	State  State
+	Banner string
+	Reason string
+}
+
+// DetailedProber is a Prober that also explains the state it reports.
+// Workers prefer ProbeDetail when a prober implements it.
+type DetailedProber interface {
+	Prober
+	// ProbeDetail returns the state of port on ip with its banner and reason.
+	ProbeDetail(ctx context.Context, ip string, port int) (ProbeDetail, error)
+}
+
+// ProberFunc adapts a function to the Prober interface.
+type ProberFunc func(ctx context.Context, ip string, port int) (State, error)
+
+// Probe calls f(ctx, ip, port).
+func (f ProberFunc) Probe(ctx context.Context, ip string, port int) (State, error) {
+	return f(ctx, ip, port)
+}
+
+// ConnectProber probes TCP ports with a full connect, using the scanner's
+// dial function and timeouts. It is the default TCP prober.
+//
+// Fields:
+// - Scanner: The scanner whose dial settings and timeouts are used.
+type ConnectProber struct {
+	Scanner *PortScanner
+}
+
+// Probe connects to the port.
+//
+// Returns:
+// - StateOpen, StateClosed, or StateFiltered when the connect timed out.
+// - Always nil.
+func (p ConnectProber) Probe(ctx context.Context, ip string, port int) (State, error) {
+	state, _, _ := p.Scanner.connectTCP(ctx, ip, port)
+	return State(state), nil
+}
+
+// ProbeDetail connects to the port.
+//
+// Returns:
+// - The state, with the reason from the connect result, e.g. "connection
+// refused", and the banner read within the scanner's BannerWait.
+// - Always nil.
+func (p ConnectProber) ProbeDetail(ctx context.Context, ip string, port int) (ProbeDetail, error) {
+	state, reason, banner := p.Scanner.connectTCP(ctx, ip, port)
+	return ProbeDetail{State: State(state), Banner: banner, Reason: reason}, nil
+}
+
+// DatagramProber probes UDP ports by sending a payload and waiting for an
+// answer, using the scanner's dial function, timeouts, CongestionControl and
+// FastUDPClose settings. Port 161 is probed with SNMP GET requests for
+// sysDescr.0, using the scanner's SNMPCommunities. It is the default UDP prober.
+//
+// Fields:
+// - Scanner: The scanner whose settings are used.
+type DatagramProber struct {
+	Scanner *PortScanner
+}
+
+// Probe sends a UDP probe to the port.
+//
+// Returns:
+// - StateOpen if the port answered, otherwise StateClosed.
+// - Always nil.
+func (p DatagramProber) Probe(ctx context.Context, ip string, port int) (State, error) {
+	state, _, err := p.ProbeBanner(ctx, ip, port)
+	return state, err
+}
+
+// ProbeBanner sends a UDP probe to the port.
+//
+// Returns:
+// - StateOpen if the port answered, otherwise StateClosed.
+// - The SNMP sysDescr for an SNMP agent, otherwise "".
+// - Always nil.
+func (p DatagramProber) ProbeBanner(ctx context.Context, ip string, port int) (State, string, error) {
+	detail, err := p.ProbeDetail(ctx, ip, port)
+	return detail.State, detail.Banner, err
+}
+
+// ProbeDetail sends a UDP probe to the port.
+//
+// Returns:
+// - The state and banner as for ProbeBanner, with the reason, e.g. "ICMP port-unreachable".
+// - Always nil.
+func (p DatagramProber) ProbeDetail(ctx context.Context, ip string, port int) (ProbeDetail, error) {
+	t := p.Scanner
+	if port == snmpPort && len(t.SNMPCommunities) > 0 {
+		var resp SNMPResponse
+		err := t.windowed(func() (err error) {
+			writeTimeout, readTimeout := t.udpTimeouts()
+			resp, err = probeSNMP(t.dialContext(ctx), ip, port, t.SNMPCommunities, writeTimeout, readTimeout)
+			return err
+		})
+		if err != nil {
+			return ProbeDetail{State: StateClosed, Reason: udpReason(err)}, nil
+		}
+		return ProbeDetail{State: StateOpen, Banner: resp.SysDescr, Reason: ReasonResponse}, nil
+	}
+	if err := t.windowed(func() error { return t.scanUDP(ctx, port, ip) }); err != nil {
+		return ProbeDetail{State: StateClosed, Reason: udpReason(err)}, nil
+	}
+	return ProbeDetail{State: StateOpen, Reason: ReasonResponse}, nil
+}
+
+// tcpProber returns TCPProber, defaulting to a ConnectProber.
+func (t *PortScanner) tcpProber() Prober {
+	if t.TCPProber != nil {
+		return t.TCPProber
+	}
+	return ConnectProber{Scanner: t}
+}
+
+// udpProber returns UDPProber, defaulting to a DatagramProber.
+func (t *PortScanner) udpProber() Prober {
+	if t.UDPProber != nil {
+		return t.UDPProber
+	}
+	return DatagramProber{Scanner: t}
+}
+
+// probe runs a prober, capturing a banner and reason if it is a
+// DetailedProber or BannerProber, and turns an error into StateError.
+//
+// Returns:
+// - The probed state.
+// - The captured banner and reason, if any.
+// - The error response to report, empty if the probe succeeded.
+func probe(ctx context.Context, prober Prober, endpoint Endpoint) (string, ProbeDetail, string) {
+	var detail ProbeDetail
+	var err error
+	switch p := prober.(type) {
+	case DetailedProber:
+		detail, err = p.ProbeDetail(ctx, endpoint.IP, endpoint.Port)
+	case BannerProber:
+		detail.State, detail.Banner, err = p.ProbeBanner(ctx, endpoint.IP, endpoint.Port)
+	default:
+		detail.State, err = prober.Probe(ctx, endpoint.IP, endpoint.Port)
+	}
+	if err != nil {
+		return string(StateError), ProbeDetail{Reason: ReasonFor(err)}, "error: " + err.Error()
+	}
+	return string(detail.State), detail, ""
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestWorkerTCP_StubProber(t *testing.T) {
+	canned := map[int]State{22: StateOpen, 23: StateClosed, 25: StateFiltered}
+	scanner := &PortScanner{
+		ReportStates: []string{"Open", "Closed", "Filtered", "Error"},
+		Quiet:        true,
+		TCPProber: ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+			if port == 26 {
+				return "", errors.New("handshake failed")
+			}
+			return canned[port], nil
+		}),
+	}
+	runTCPWorker(scanner, "127.0.0.1", []int{22, 23, 25, 26})
+
+	got := map[int]string{}
+	responses := map[int]string{}
+	for svc := range scanner.OpenPorts {
+		got[svc.Port] = svc.State
+		responses[svc.Port] = svc.Response
+	}
+	for port, state := range canned {
+		if got[port] != string(state) {
+			t.Errorf("Port %d: Expected %s, got %s", port, state, got[port])
+		}
+	}
+	if got[26] != string(StateError) || responses[26] != "error: handshake failed" {
+		t.Errorf("Port 26: Expected an Error result for a failed probe, got %s (%s)", got[26], responses[26])
+	}
+}
+
+func TestWorkerUDP_StubProber(t *testing.T) {
+	scanner := &PortScanner{
+		Quiet: true,
+		UDPProber: ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+			if port == 53 {
+				return StateOpen, nil
+			}
+			return StateClosed, nil
+		}),
+	}
+	ports := make(chan Endpoint, 2)
+	ports <- Endpoint{IP: "127.0.0.1", Port: 53}
+	ports <- Endpoint{IP: "127.0.0.1", Port: 54}
+	close(ports)
+	openPorts := make(chan service.ServiceVersion, 2)
+	done := make(chan bool, 1)
+	scanner.WorkerUDP(context.Background(), ports, openPorts, done)
+	close(openPorts)
+
+	var open []int
+	for svc := range openPorts {
+		open = append(open, svc.Port)
+	}
+	if len(open) != 1 || open[0] != 53 {
+		t.Errorf("Expected only port 53 reported open, got %v", open)
+	}
+}
+
+func TestConnectProber_Default(t *testing.T) {
+	dialer := &mockDialer{accept: func(address string) bool { return address == "127.0.0.1:80" }}
+	scanner := &PortScanner{Dial: dialer.Dial}
+	for port, expected := range map[int]State{80: StateOpen, 81: StateClosed} {
+		state, err := scanner.tcpProber().Probe(context.Background(), "127.0.0.1", port)
+		if err != nil || state != expected {
+			t.Errorf("Port %d: Expected %s, got %s (%v)", port, expected, state, err)
+		}
+	}
+}
+
+func TestConnectProber_CancelAbandonsConnect(t *testing.T) {
+	// A dial that hangs for its whole timeout, as to a host dropping SYNs
+	hanging := func(network, address string, timeout time.Duration) (net.Conn, error) {
+		time.Sleep(timeout)
+		return nil, timeoutError{}
+	}
+	scanner := &PortScanner{Dial: hanging, Timeout: 10 * time.Second, TCPRetries: 2}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	ConnectProber{Scanner: scanner}.ProbeDetail(ctx, "192.0.2.1", 80)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the cancelled connect to return at once, took %s", elapsed)
+	}
+}
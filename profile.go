@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scan profile names accepted by ApplyProfile.
+const (
+	ProfileQuick   = "quick"
+	ProfileFull    = "full"
+	ProfileStealth = "stealth"
+)
+
+// TopPorts are the 100 most commonly open TCP ports, as ranked by nmap.
+var TopPorts = []int{
+	7, 9, 13, 21, 22, 23, 25, 26, 37, 53, 79, 80, 81, 88, 106, 110, 111, 113, 119, 135,
+	139, 143, 144, 179, 199, 389, 427, 443, 444, 445, 465, 513, 514, 515, 543, 544, 548, 554, 587, 631,
+	646, 873, 990, 993, 995, 1025, 1026, 1027, 1028, 1029, 1110, 1433, 1720, 1723, 1755, 1900, 2000, 2001, 2049, 2121,
+	2717, 3000, 3128, 3306, 3389, 3986, 4899, 5000, 5009, 5051, 5060, 5101, 5190, 5357, 5432, 5631, 5666, 5800, 5900, 6000,
+	6001, 6646, 7070, 8000, 8008, 8009, 8080, 8081, 8443, 8888, 9100, 9999, 10000, 32768, 49152, 49153, 49154, 49155, 49156, 49157,
+}
+
+const (
+	// quickWorkers is the number of workers used by the quick profile.
+	quickWorkers = 500
+	// quickTimeout is the TCP connect and UDP read timeout of the quick profile.
+	quickTimeout = time.Second
+	// fullWorkers is the number of workers used by the full profile.
+	fullWorkers = 100
+	// stealthWorkers is the number of workers used by the stealth profile.
+	stealthWorkers = 5
+	// stealthJitter is the upper bound of the random delay before each probe
+	// in the stealth profile.
+	stealthJitter = 2 * time.Second
+)
+
+// allPorts returns every port from 1 to 65535.
+func allPorts() []int {
+	ports := make([]int, 0, 65535)
+	for port := 1; port <= 65535; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// ApplyProfile sets the scanner's tunables to a named preset:
+// - quick: the TopPorts with short timeouts and high concurrency.
+// - full: every port with the default timeouts.
+// - stealth: a low probe rate with jitter and a randomized probe order.
+//
+// Settings a profile does not mention are left unchanged.
+//
+// Parameters:
+// - name: The profile name, case-insensitive.
+//
+// Returns:
+// - An error if the profile is unknown.
+//
+// Example:
+//
+//	if err := scanner.ApplyProfile("quick"); err != nil {
+//	    fmt.Println(err)
+//	}
+func (t *PortScanner) ApplyProfile(name string) error {
+	switch strings.ToLower(name) {
+	case ProfileQuick:
+		t.Ports = append([]int{}, TopPorts...)
+		t.NumWorkers = quickWorkers
+		t.Timeout = quickTimeout
+		t.UDPWriteTimeout = quickTimeout / 2
+		t.UDPReadTimeout = quickTimeout
+		t.Jitter = 0
+		t.RandomizeOrder = false
+	case ProfileFull:
+		t.Ports = allPorts()
+		t.NumWorkers = fullWorkers
+		t.Timeout = DefaultTCPTimeout
+		t.UDPWriteTimeout = DefaultUDPWriteTimeout
+		t.UDPReadTimeout = DefaultUDPReadTimeout
+		t.Jitter = 0
+		t.RandomizeOrder = false
+	case ProfileStealth:
+		t.NumWorkers = stealthWorkers
+		t.Timeout = DefaultTCPTimeout
+		t.UDPWriteTimeout = DefaultUDPWriteTimeout
+		t.UDPReadTimeout = DefaultUDPReadTimeout
+		t.Jitter = stealthJitter
+		t.RandomizeOrder = true
+	default:
+		return fmt.Errorf("unknown profile %q, expected %s, %s or %s", name, ProfileQuick, ProfileFull, ProfileStealth)
+	}
+	return nil
+}
+
+// shuffleEndpoints randomizes the probe order in place. The order is
+// reproducible when RandSeed is set.
+//
+// Parameters:
+// - endpoints: The endpoints to shuffle.
+func (t *PortScanner) shuffleEndpoints(endpoints []Endpoint) {
+	for i := len(endpoints) - 1; i > 0; i-- {
+		j := t.rand.int63n(t.RandSeed, int64(i+1))
+		endpoints[i], endpoints[j] = endpoints[j], endpoints[i]
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		ports     int
+		workers   int
+		timeout   time.Duration
+		jitter    time.Duration
+		randomize bool
+	}{
+		{ProfileQuick, 100, quickWorkers, quickTimeout, 0, false},
+		{ProfileFull, 65535, fullWorkers, DefaultTCPTimeout, 0, false},
+		{ProfileStealth, 3, stealthWorkers, DefaultTCPTimeout, stealthJitter, true},
+	}
+	for _, test := range tests {
+		scanner := &PortScanner{Ports: []int{22, 80, 443}, Jitter: time.Second, RandomizeOrder: !test.randomize}
+		if err := scanner.ApplyProfile(test.name); err != nil {
+			t.Fatalf("%s: ApplyProfile failed: %s", test.name, err)
+		}
+		if len(scanner.Ports) != test.ports {
+			t.Errorf("%s: Expected %d ports, got %d", test.name, test.ports, len(scanner.Ports))
+		}
+		if scanner.NumWorkers != test.workers {
+			t.Errorf("%s: Expected %d workers, got %d", test.name, test.workers, scanner.NumWorkers)
+		}
+		if scanner.Timeout != test.timeout {
+			t.Errorf("%s: Expected timeout %v, got %v", test.name, test.timeout, scanner.Timeout)
+		}
+		if scanner.Jitter != test.jitter {
+			t.Errorf("%s: Expected jitter %v, got %v", test.name, test.jitter, scanner.Jitter)
+		}
+		if scanner.RandomizeOrder != test.randomize {
+			t.Errorf("%s: Expected RandomizeOrder %v, got %v", test.name, test.randomize, scanner.RandomizeOrder)
+		}
+	}
+}
+
+func TestApplyProfile_Unknown(t *testing.T) {
+	scanner := &PortScanner{NumWorkers: 10}
+	if err := scanner.ApplyProfile("aggressive"); err == nil {
+		t.Errorf("Expected an error for an unknown profile")
+	}
+	if scanner.NumWorkers != 10 {
+		t.Errorf("Expected an unknown profile to leave the scanner unchanged, got %d workers", scanner.NumWorkers)
+	}
+}
+
+func TestShuffleEndpoints_Seeded(t *testing.T) {
+	endpoints := Interleave([]string{"192.0.2.1", "192.0.2.2"}, []int{1, 2, 3, 4, 5, 6, 7, 8})
+	shuffle := func() []Endpoint {
+		scanner := &PortScanner{RandSeed: 42}
+		shuffled := append([]Endpoint{}, endpoints...)
+		scanner.shuffleEndpoints(shuffled)
+		return shuffled
+	}
+
+	first := shuffle()
+	if !reflect.DeepEqual(first, shuffle()) {
+		t.Errorf("Expected the same order for the same seed")
+	}
+	if reflect.DeepEqual(first, endpoints) {
+		t.Errorf("Expected the order to be randomized, got %v", first)
+	}
+	seen := map[Endpoint]bool{}
+	for _, endpoint := range first {
+		seen[endpoint] = true
+	}
+	if len(seen) != len(endpoints) {
+		t.Errorf("Expected every endpoint exactly once, got %v", first)
+	}
+}
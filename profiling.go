@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling starts a CPU profile and an execution trace of the scan,
+// for telling whether a slow scan is bound by goroutine scheduling, channel
+// contention or syscalls. Inspect them with "go tool pprof" and "go tool
+// trace".
+//
+// Parameters:
+// - cpuProfile: The file to write the CPU profile to, "" for none.
+// - traceFile: The file to write the execution trace to, "" for none.
+//
+// Returns:
+// - A function that stops profiling and closes the files; it must be called for the files to be complete.
+// - An error if a file cannot be created or profiling cannot start.
+//
+// Example:
+//
+//	stop, err := startProfiling("cpu.out", "trace.out")
+//	if err != nil {
+//	    return err
+//	}
+//	defer stop()
+func startProfiling(cpuProfile, traceFile string) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if cpuProfile != "" {
+		file, err := os.Create(cpuProfile)
+		if err != nil {
+			return func() {}, fmt.Errorf("error creating CPU profile: %s", err)
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return func() {}, fmt.Errorf("error starting CPU profile: %s", err)
+		}
+		stops = append(stops, func() {
+			pprof.StopCPUProfile()
+			file.Close()
+		})
+	}
+	if traceFile != "" {
+		file, err := os.Create(traceFile)
+		if err != nil {
+			stop()
+			return func() {}, fmt.Errorf("error creating trace: %s", err)
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			stop()
+			return func() {}, fmt.Errorf("error starting trace: %s", err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			file.Close()
+		})
+	}
+	return stop, nil
+}
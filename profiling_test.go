@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRun_WritesProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cpuProfile := filepath.Join(dir, "cpu.out")
+	traceFile := filepath.Join(dir, "trace.out")
+	args := []string{"-quiet", "-o", filepath.Join(dir, "output.txt"), "-ports", "1",
+		"-cpuprofile", cpuProfile, "-trace", traceFile, "127.0.0.1"}
+	if code := run(args, nil); code == ExitError {
+		t.Fatalf("Expected the scan to run, got exit code %d", code)
+	}
+	for _, file := range []string{cpuProfile, traceFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Errorf("Expected %s to be created: %s", file, err)
+		} else if info.Size() == 0 {
+			t.Errorf("Expected %s not to be empty", file)
+		}
+	}
+}
+
+func TestStartProfiling_Errors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing", "cpu.out")
+	if _, err := startProfiling(missing, ""); err == nil {
+		t.Errorf("Expected an error for an unwritable CPU profile")
+	}
+	if _, err := startProfiling("", missing); err == nil {
+		t.Errorf("Expected an error for an unwritable trace")
+	}
+
+	// A failed trace stops the CPU profile it started with
+	if _, err := startProfiling(filepath.Join(t.TempDir(), "cpu.out"), missing); err == nil {
+		t.Errorf("Expected an error for an unwritable trace")
+	}
+	stop, err := startProfiling(filepath.Join(t.TempDir(), "cpu.out"), "")
+	if err != nil {
+		t.Fatalf("Expected profiling to start again, got %s", err)
+	}
+	stop()
+
+	// Without files nothing is started
+	stop, err = startProfiling("", "")
+	if err != nil {
+		t.Errorf("Expected no error without profiles, got %s", err)
+	}
+	stop()
+}
@@ -0,0 +1,54 @@
+package main
+
+import "sync/atomic"
+
+// Completed returns the number of TCP and UDP endpoint probes finished so
+// far, counting endpoints skipped after cancellation. A full scan completes
+// the number of IP and port pairs once per enabled protocol. It is safe to call while the scan is
+// running.
+//
+// Returns:
+// - The number of completed probes.
+//
+// Example:
+//
+//	total := 2 * len(scanner.IPs) * len(scanner.Ports)
+//	fmt.Printf("%d/%d probes done\n", scanner.Completed(), total)
+func (t *PortScanner) Completed() int64 {
+	return atomic.LoadInt64(&t.completed)
+}
+
+// Progress returns how far the running scan is, for an application that
+// polls at its own cadence rather than taking a callback. It only reads
+// counters, so it is safe to call while the scan is running.
+//
+// Returns:
+// - The number of TCP and UDP probes completed so far, as Completed.
+// - The number of probes the scan makes in total, 0 until it has started.
+// - The number of open ports found so far.
+//
+// Example:
+//
+//	scanned, total, open := scanner.Progress()
+//	fmt.Printf("%d/%d probes, %d open\n", scanned, total, open)
+func (t *PortScanner) Progress() (scanned, total int, openFound int) {
+	return int(atomic.LoadInt64(&t.completed)), int(atomic.LoadInt64(&t.total)), int(atomic.LoadInt64(&t.openFound))
+}
+
+// setTotal records the number of probes the scan makes for Progress: one
+// per IP and port pair for each protocol that has workers.
+//
+// Parameters:
+// - endpoints: The number of IP and port pairs scanned.
+// - tcpWorkers: The number of TCP workers.
+// - udpWorkers: The number of UDP workers.
+func (t *PortScanner) setTotal(endpoints, tcpWorkers, udpWorkers int) {
+	var total int64
+	if tcpWorkers > 0 {
+		total += int64(endpoints)
+	}
+	if udpWorkers > 0 {
+		total += int64(endpoints)
+	}
+	atomic.StoreInt64(&t.total, total)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScan_TerminatesWithCompletionCounter(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = make([]int, 200)
+	for i := range scanner.Ports {
+		scanner.Ports[i] = i + 1
+	}
+	onlyHTTP := ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		if port == 80 {
+			return StateOpen, nil
+		}
+		return StateClosed, nil
+	})
+	scanner.TCPProber = onlyHTTP
+	scanner.UDPProber = onlyHTTP
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	scanner.Quiet = true
+
+	finished := make(chan struct{})
+	go func() {
+		scanner.Scan()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the scan to terminate, still running after %d probes", scanner.Completed())
+	}
+
+	if completed := scanner.Completed(); completed != 400 {
+		t.Errorf("Expected 400 completed probes, got %d", completed)
+	}
+	report := scanner.Report()
+	if tcp, udp := len(report.TCP), len(report.UDP); tcp != 1 || udp != 1 {
+		t.Errorf("Expected only the open port in the results, got %d TCP and %d UDP", tcp, udp)
+	}
+}
+
+func TestProgress_AdvancesToCompletion(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = make([]int, 100)
+	for i := range scanner.Ports {
+		scanner.Ports[i] = i + 1
+	}
+	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		time.Sleep(time.Millisecond)
+		if port%10 == 0 {
+			return StateOpen, nil
+		}
+		return StateClosed, nil
+	})
+	scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	scanner.Quiet = true
+
+	if scanned, total, open := scanner.Progress(); scanned != 0 || total != 0 || open != 0 {
+		t.Errorf("Expected no progress before the scan, got %d/%d and %d open", scanned, total, open)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		scanner.Scan()
+		close(finished)
+	}()
+
+	var lastScanned, lastOpen int
+	for done := false; !done; {
+		select {
+		case <-finished:
+			done = true
+		case <-time.After(time.Millisecond):
+		}
+		scanned, total, open := scanner.Progress()
+		if scanned < lastScanned || open < lastOpen {
+			t.Fatalf("Expected the counters to only advance, went from %d and %d to %d and %d", lastScanned, lastOpen, scanned, open)
+		}
+		if total != 0 && total != 200 {
+			t.Fatalf("Expected a total of 200 probes, got %d", total)
+		}
+		if scanned > total {
+			t.Fatalf("Expected at most %d probes scanned, got %d", total, scanned)
+		}
+		lastScanned, lastOpen = scanned, open
+	}
+
+	if scanned, total, open := scanner.Progress(); scanned != 200 || total != 200 || open != 10 {
+		t.Errorf("Expected 200/200 probes and 10 open, got %d/%d and %d open", scanned, total, open)
+	}
+}
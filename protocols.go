@@ -0,0 +1,44 @@
+package main
+
+// enabledProtocols reports which protocols Scan probes, from EnableTCP,
+// EnableUDP and EnableICMP. NewTarget enables all three; a PortScanner built
+// without it has none enabled, which also scans all three rather than
+// nothing.
+//
+// Returns:
+// - Whether TCP ports are scanned.
+// - Whether UDP ports are scanned.
+// - Whether the IPs are pinged.
+func (t *PortScanner) enabledProtocols() (bool, bool, bool) {
+	if !t.EnableTCP && !t.EnableUDP && !t.EnableICMP {
+		return true, true, true
+	}
+	return t.EnableTCP, t.EnableUDP, t.EnableICMP
+}
+
+// workerCounts returns the number of TCP and UDP workers and the IPs to
+// ping, leaving out the protocols that are not enabled so no worker is
+// started for them.
+//
+// Parameters:
+// - ips: The IPs being scanned.
+// - numWorkers: The number of workers of the scan (see autoWorkers).
+//
+// Returns:
+// - The number of TCP workers, 0 if TCP is disabled.
+// - The number of UDP workers, 0 if UDP is disabled.
+// - The IPs the ICMP workers ping, none if ICMP is disabled.
+func (t *PortScanner) workerCounts(ips []string, numWorkers int) (int, int, []string) {
+	scanTCP, scanUDP, scanICMP := t.enabledProtocols()
+	var tcpWorkers, udpWorkers int
+	if scanTCP {
+		tcpWorkers = t.tcpWorkerCount(numWorkers)
+	}
+	if scanUDP {
+		udpWorkers = numWorkers
+	}
+	if !scanICMP {
+		ips = nil
+	}
+	return tcpWorkers, udpWorkers, ips
+}
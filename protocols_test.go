@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestScan_OnlyTCPEnabled(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{22, 80, 443}
+	scanner.EnableUDP = false
+	scanner.EnableICMP = false
+
+	var tcpProbes, udpProbes, pings int64
+	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		atomic.AddInt64(&tcpProbes, 1)
+		return StateOpen, nil
+	})
+	scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		atomic.AddInt64(&udpProbes, 1)
+		return StateOpen, nil
+	})
+	scanner.pinger = func(ip string) ICMPResult {
+		atomic.AddInt64(&pings, 1)
+		return ICMPResult{IP: ip, State: ICMPReachable, Reachable: true}
+	}
+	scanner.Quiet = true
+	scanner.Scan()
+
+	if tcpProbes != 3 {
+		t.Errorf("Expected 3 TCP probes, got %d", tcpProbes)
+	}
+	if udpProbes != 0 || pings != 0 {
+		t.Errorf("Expected no UDP probes and no pings, got %d and %d", udpProbes, pings)
+	}
+	report := scanner.Report()
+	if len(report.TCP) != 3 || len(report.UDP) != 0 || len(report.ICMP) != 0 {
+		t.Errorf("Expected only TCP results, got %d TCP, %d UDP and %d ICMP", len(report.TCP), len(report.UDP), len(report.ICMP))
+	}
+	if completed := scanner.Completed(); completed != 3 {
+		t.Errorf("Expected 3 completed probes, got %d", completed)
+	}
+}
+
+func TestEnabledProtocols(t *testing.T) {
+	tests := []struct {
+		scanner        *PortScanner
+		tcp, udp, icmp bool
+	}{
+		{&PortScanner{}, true, true, true},
+		{&PortScanner{EnableICMP: true}, false, false, true},
+		{&PortScanner{EnableTCP: true, EnableUDP: true}, true, true, false},
+	}
+	for _, tt := range tests {
+		tcp, udp, icmp := tt.scanner.enabledProtocols()
+		if tcp != tt.tcp || udp != tt.udp || icmp != tt.icmp {
+			t.Errorf("Expected %v/%v/%v, got %v/%v/%v", tt.tcp, tt.udp, tt.icmp, tcp, udp, icmp)
+		}
+	}
+}
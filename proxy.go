@@ -0,0 +1,39 @@
+package main
+
+import "sync/atomic"
+
+const (
+	// DefaultProxyThreshold is the open-port fraction above which a scan is
+	// considered to be answered by a transparent proxy or captive portal.
+	DefaultProxyThreshold = 0.9
+
+	// proxyMinPorts is the minimum number of scanned TCP ports needed before
+	// the proxy heuristic is applied, so tiny scans are never flagged.
+	proxyMinPorts = 10
+)
+
+// openFraction returns the fraction of scanned TCP ports that were reported Open.
+func (t *PortScanner) openFraction() float64 {
+	scanned := atomic.LoadInt64(&t.tcpScanned)
+	if scanned == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&t.tcpOpen)) / float64(scanned)
+}
+
+// detectProxy sets ProxySuspected when more than ProxyThreshold of the scanned
+// TCP ports came back Open. Networks with a transparent proxy accept every
+// connection, which would otherwise make every port look open.
+//
+// Example:
+//
+//	scanner.detectProxy()
+//	if scanner.ProxySuspected {
+//	    fmt.Println("results are likely filtered by a proxy")
+//	}
+func (t *PortScanner) detectProxy() {
+	if t.ProxyThreshold <= 0 || atomic.LoadInt64(&t.tcpScanned) < proxyMinPorts {
+		return
+	}
+	t.ProxySuspected = t.openFraction() > t.ProxyThreshold
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"det/service"
+)
+
+// runTCPWorker scans the given ports with a single TCP worker and closes the
+// open-port channels so the report writer can drain them.
+func runTCPWorker(scanner *PortScanner, ip string, ports []int) {
+	scanner.TCPPortChannel = make(chan Endpoint, len(ports))
+	scanner.OpenPorts = make(chan service.ServiceVersion, len(ports))
+	scanner.OpenPortsUDP = make(chan service.ServiceVersion)
+	scanner.ICMPResults = make(chan ICMPResult)
+	scanner.Done = make(chan bool, 1)
+	for _, port := range ports {
+		scanner.TCPPortChannel <- Endpoint{IP: ip, Port: port}
+	}
+	close(scanner.TCPPortChannel)
+	scanner.WorkerTCP(context.Background(), scanner.TCPPortChannel, scanner.OpenPorts, scanner.Done)
+	close(scanner.OpenPorts)
+	close(scanner.OpenPortsUDP)
+	close(scanner.ICMPResults)
+}
+
+func TestDetectProxy_AllOpen(t *testing.T) {
+	dialer := &mockDialer{}
+	scanner := &PortScanner{
+		Dial:             dialer.Dial,
+		ProxyThreshold:   DefaultProxyThreshold,
+		DowngradeProxied: true,
+	}
+	ports := make([]int, 20)
+	for i := range ports {
+		ports[i] = i + 1
+	}
+	runTCPWorker(scanner, "127.0.0.1", ports)
+	scanner.detectProxy()
+
+	if !scanner.ProxySuspected {
+		t.Fatalf("Expected proxy to be suspected when every port is open")
+	}
+
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, fileName); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	if !strings.Contains(string(data), "transparent proxy") {
+		t.Errorf("Expected proxy warning in report, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "Port 1 (TCP) is Filtered") {
+		t.Errorf("Expected open ports downgraded to Filtered, got:\n%s", data)
+	}
+}
+
+func TestDetectProxy_FewOpen(t *testing.T) {
+	dialer := &mockDialer{accept: func(address string) bool {
+		return strings.HasSuffix(address, ":1")
+	}}
+	scanner := &PortScanner{Dial: dialer.Dial, ProxyThreshold: DefaultProxyThreshold}
+	ports := make([]int, 20)
+	for i := range ports {
+		ports[i] = i + 1
+	}
+	runTCPWorker(scanner, "127.0.0.1", ports)
+	scanner.detectProxy()
+
+	if scanner.ProxySuspected {
+		t.Errorf("Expected no proxy suspicion with a single open port")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// AddrResolver looks up the host names of an IP address (PTR records).
+// *net.Resolver and *CachingResolver satisfy this interface; tests can
+// substitute a stub.
+type AddrResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// defaultPTRResolver caches the reverse lookups of every scan that does not
+// configure a PTRResolver.
+var defaultPTRResolver AddrResolver = NewCachingResolver(net.DefaultResolver, DefaultDNSCacheTTL)
+
+// ptrResolver returns PTRResolver, then a resolver querying DNSServer,
+// defaulting to a cached net.DefaultResolver.
+func (t *PortScanner) ptrResolver() AddrResolver {
+	if t.PTRResolver != nil {
+		return t.PTRResolver
+	}
+	if t.DNSServer != "" {
+		if resolver, err := NewDNSResolver(t.DNSServer); err == nil {
+			return resolver
+		}
+	}
+	return defaultPTRResolver
+}
+
+// reverseLookup looks up the host names of every IP when ReverseDNS is set
+// and keeps them for the report. At most DefaultResolveWorkers lookups run
+// at once. IPs without a PTR record are left out.
+//
+// Parameters:
+// - ips: The IP addresses to look up.
+func (t *PortScanner) reverseLookup(ips []string) {
+	if !t.ReverseDNS {
+		return
+	}
+	resolver := t.ptrResolver()
+	hosts := make([][]string, len(ips))
+	slots := make(chan struct{}, DefaultResolveWorkers)
+	var wg sync.WaitGroup
+	for i, ip := range ips {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, ip string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			names, err := resolver.LookupAddr(context.Background(), ip)
+			if err != nil {
+				t.logf("Error looking up the PTR record of %s: %s\n", ip, err)
+				t.addError("ptr", ip, err)
+				return
+			}
+			hosts[i] = trimDots(names)
+		}(i, ip)
+	}
+	wg.Wait()
+
+	ptr := make(map[string][]string, len(ips))
+	for i, ip := range ips {
+		if len(hosts[i]) > 0 {
+			ptr[ip] = hosts[i]
+		}
+	}
+	t.reportMu.Lock()
+	t.collected.PTR = ptr
+	t.reportMu.Unlock()
+}
+
+// trimDots removes the trailing dot of fully qualified host names.
+func trimDots(names []string) []string {
+	trimmed := make([]string, len(names))
+	for i, name := range names {
+		if len(name) > 1 && name[len(name)-1] == '.' {
+			name = name[:len(name)-1]
+		}
+		trimmed[i] = name
+	}
+	return trimmed
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReverseDNS_Report(t *testing.T) {
+	stub := &stubResolver{
+		hosts: map[string][]string{"127.0.0.1": {"127.0.0.1"}},
+		ptrs:  map[string][]string{"127.0.0.1": {"localhost.example.", "web.example."}},
+	}
+	scanner, err := NewTargetWithResolver("127.0.0.1", 2, stub)
+	if err != nil {
+		t.Fatalf("NewTargetWithResolver failed: %s", err)
+	}
+	scanner.Ports = []int{80}
+	scanner.Dial = (&mockDialer{}).Dial
+	scanner.Quiet = true
+	scanner.ReverseDNS = true
+	scanner.PTRResolver = stub
+
+	scanner.Scan()
+
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, fileName); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	if !strings.Contains(string(data), "IP 127.0.0.1 (localhost.example, web.example):") {
+		t.Errorf("Expected the PTR records in the IP header, got:\n%s", data)
+	}
+	expected := map[string][]string{"127.0.0.1": {"localhost.example", "web.example"}}
+	if ptr := scanner.Report().PTR; !reflect.DeepEqual(ptr, expected) {
+		t.Errorf("Expected PTR %v, got %v", expected, ptr)
+	}
+}
+
+func TestReverseDNS_Cached(t *testing.T) {
+	stub := &stubResolver{ptrs: map[string][]string{
+		"192.0.2.1": {"a.example."},
+		"192.0.2.2": {"b.example."},
+	}}
+	scanner := &PortScanner{ReverseDNS: true, PTRResolver: NewCachingResolver(stub, time.Minute)}
+	ips := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}
+	scanner.reverseLookup(ips)
+	scanner.reverseLookup(ips)
+
+	// The missing record of 192.0.2.3 is not cached and is looked up twice
+	if stub.lookups != 4 {
+		t.Errorf("Expected 4 lookups, got %d", stub.lookups)
+	}
+	if _, ok := scanner.collected.PTR["192.0.2.3"]; ok {
+		t.Errorf("Expected no PTR entry for an IP without a record")
+	}
+}
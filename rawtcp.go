@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// TCP header flags.
+const (
+	TCPFlagFIN uint8 = 0x01
+	TCPFlagSYN uint8 = 0x02
+	TCPFlagRST uint8 = 0x04
+	TCPFlagPSH uint8 = 0x08
+	TCPFlagACK uint8 = 0x10
+	TCPFlagURG uint8 = 0x20
+)
+
+// Flag combinations of the stealth scans supported by ScanTCPFlags.
+const (
+	ScanFIN  = TCPFlagFIN
+	ScanNULL = uint8(0)
+	ScanXmas = TCPFlagFIN | TCPFlagPSH | TCPFlagURG
+)
+
+// Port states reported by ScanTCPFlags.
+const (
+	FlagScanClosed       = "Closed"
+	FlagScanOpenFiltered = "Open|Filtered"
+)
+
+const (
+	// tcpHeaderLen is the length of a TCP header without options.
+	tcpHeaderLen = 20
+	// flagScanWindow is the window size advertised by flag scan probes.
+	flagScanWindow = 1024
+)
+
+// ErrRawSocketUnavailable is returned by ScanTCPFlags when raw sockets cannot
+// be opened, usually because the scanner is not running as root.
+var ErrRawSocketUnavailable = errors.New("raw sockets are unavailable")
+
+// TCPSegment is a decoded TCP header.
+//
+// Fields:
+// - SrcPort: The source port.
+// - DstPort: The destination port.
+// - Seq: The sequence number.
+// - Ack: The acknowledgment number.
+// - Flags: The TCP flags.
+type TCPSegment struct {
+	SrcPort int
+	DstPort int
+	Seq     uint32
+	Ack     uint32
+	Flags   uint8
+}
+
+// MarshalTCPFlags builds a TCP header carrying the given flags and no
+// payload, with a checksum over the IPv4 pseudo-header.
+//
+// Parameters:
+// - src: The source IPv4 address.
+// - dst: The destination IPv4 address.
+// - srcPort: The source port.
+// - dstPort: The destination port.
+// - seq: The sequence number.
+// - flags: The TCP flags, e.g. ScanXmas.
+//
+// Returns:
+// - The encoded TCP header.
+//
+// Example:
+//
+//	segment := MarshalTCPFlags(src, dst, 40000, 80, 1000, ScanFIN)
+func MarshalTCPFlags(src, dst net.IP, srcPort, dstPort int, seq uint32, flags uint8) []byte {
+	b := make([]byte, tcpHeaderLen)
+	binary.BigEndian.PutUint16(b[0:], uint16(srcPort))
+	binary.BigEndian.PutUint16(b[2:], uint16(dstPort))
+	binary.BigEndian.PutUint32(b[4:], seq)
+	b[12] = tcpHeaderLen / 4 << 4
+	b[13] = flags
+	binary.BigEndian.PutUint16(b[14:], flagScanWindow)
+
+	pseudo := make([]byte, 0, 12+len(b))
+	pseudo = append(pseudo, src.To4()...)
+	pseudo = append(pseudo, dst.To4()...)
+	pseudo = append(pseudo, 0, 6, 0, byte(len(b)))
+	pseudo = append(pseudo, b...)
+	binary.BigEndian.PutUint16(b[16:], icmpChecksum(pseudo))
+	return b
+}
+
+// ParseTCP decodes a TCP header without its IP header.
+//
+// Parameters:
+// - b: The raw TCP segment.
+//
+// Returns:
+// - The decoded TCPSegment.
+// - An error if the segment is truncated.
+func ParseTCP(b []byte) (TCPSegment, error) {
+	if len(b) < tcpHeaderLen {
+		return TCPSegment{}, errors.New("tcp: segment too short")
+	}
+	return TCPSegment{
+		SrcPort: int(binary.BigEndian.Uint16(b[0:])),
+		DstPort: int(binary.BigEndian.Uint16(b[2:])),
+		Seq:     binary.BigEndian.Uint32(b[4:]),
+		Ack:     binary.BigEndian.Uint32(b[8:]),
+		Flags:   b[13],
+	}, nil
+}
+
+// ClassifyFlagResponse maps the answer to a FIN, NULL or Xmas probe to a port
+// state. Per RFC 793 a closed port answers these probes with a RST while an
+// open port drops them, so only a RST is conclusive.
+//
+// Parameters:
+// - segment: The TCP segment received in answer to the probe.
+//
+// Returns:
+// - FlagScanClosed for a RST, FlagScanOpenFiltered otherwise.
+func ClassifyFlagResponse(segment TCPSegment) string {
+	if segment.Flags&TCPFlagRST != 0 {
+		return FlagScanClosed
+	}
+	return FlagScanOpenFiltered
+}
+
+// ScanTCPFlags probes a TCP port with a crafted segment carrying the given
+// flags, e.g. a FIN, NULL or Xmas scan, which can slip past some stateless
+// firewalls. It needs raw sockets, so it usually requires root.
+//
+// Parameters:
+// - ip: The IPv4 address to scan.
+// - port: The TCP port to scan.
+// - flags: The TCP flags of the probe: ScanFIN, ScanNULL or ScanXmas.
+// - timeout: How long to wait for a RST.
+//
+// Returns:
+// - FlagScanClosed if the port answered with a RST, FlagScanOpenFiltered if
+// it did not answer.
+// - ErrRawSocketUnavailable if raw sockets cannot be opened, or another error
+// if the probe cannot be sent.
+//
+// Example:
+//
+//	state, err := ScanTCPFlags("192.168.1.1", 80, ScanXmas, 2*time.Second)
+func ScanTCPFlags(ip string, port int, flags uint8, timeout time.Duration) (string, error) {
+	dst := net.ParseIP(ip).To4()
+	if dst == nil {
+		return "", fmt.Errorf("error scanning %s: not an IPv4 address", ip)
+	}
+	src, err := localAddrFor(dst)
+	if err != nil {
+		return "", fmt.Errorf("error finding a source address: %s", err)
+	}
+	packetConn, err := listenRaw("ip4:tcp", src.String())
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrRawSocketUnavailable, err)
+	}
+	defer packetConn.Close()
+
+	srcPort := 32768 + rand.Intn(28232)
+	segment := MarshalTCPFlags(src, dst, srcPort, port, rand.Uint32(), flags)
+	if _, err := packetConn.WriteTo(segment, &net.IPAddr{IP: dst}); err != nil {
+		return "", fmt.Errorf("error sending probe: %s", err)
+	}
+
+	packetConn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := packetConn.ReadFrom(buf)
+		if err != nil {
+			// No answer before the deadline
+			return FlagScanOpenFiltered, nil
+		}
+		reply, err := ParseTCP(buf[:n])
+		if err != nil || reply.SrcPort != port || reply.DstPort != srcPort {
+			continue
+		}
+		if addr, ok := from.(*net.IPAddr); !ok || !addr.IP.Equal(dst) {
+			continue
+		}
+		if state := ClassifyFlagResponse(reply); state == FlagScanClosed {
+			return state, nil
+		}
+	}
+}
+
+// localAddrFor returns the local address the kernel routes to dst from.
+func localAddrFor(dst net.IP) (net.IP, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(dst.String(), "9"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
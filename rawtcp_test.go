@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Recorded TCP segments between 192.0.2.10:40000 and 192.0.2.1:80, without
+// their IPv4 header.
+const (
+	recordedFINProbe  = "9c40 0050 000003e8 00000000 5001 0400 875f 0000"
+	recordedNULLProbe = "9c40 0050 000003e8 00000000 5000 0400 8760 0000"
+	recordedXmasProbe = "9c40 0050 000003e8 00000000 5029 0400 8737 0000"
+	// RST/ACK answering the FIN and Xmas probes, acknowledging the FIN.
+	recordedRSTAfterFIN = "0050 9c40 00000000 000003e9 5014 0000 8b4b 0000"
+	// RST/ACK answering the NULL probe.
+	recordedRSTAfterNULL = "0050 9c40 00000000 000003e8 5014 0000 8b4c 0000"
+)
+
+func TestMarshalTCPFlags_Recorded(t *testing.T) {
+	src := net.ParseIP("192.0.2.10")
+	dst := net.ParseIP("192.0.2.1")
+	tests := []struct {
+		name     string
+		flags    uint8
+		recorded string
+	}{
+		{"FIN", ScanFIN, recordedFINProbe},
+		{"NULL", ScanNULL, recordedNULLProbe},
+		{"Xmas", ScanXmas, recordedXmasProbe},
+	}
+	for _, test := range tests {
+		got := MarshalTCPFlags(src, dst, 40000, 80, 1000, test.flags)
+		expected := decodeHex(t, test.recorded)
+		if string(got) != string(expected) {
+			t.Errorf("%s: Expected segment %x, got %x", test.name, expected, got)
+		}
+	}
+}
+
+func TestClassifyFlagResponse_Recorded(t *testing.T) {
+	tests := []struct {
+		name     string
+		segment  string
+		expected string
+		ack      uint32
+	}{
+		{"RST after FIN", recordedRSTAfterFIN, FlagScanClosed, 1001},
+		{"RST after Xmas", recordedRSTAfterFIN, FlagScanClosed, 1001},
+		{"RST after NULL", recordedRSTAfterNULL, FlagScanClosed, 1000},
+		// A stray probe, e.g. our own segment seen on loopback, is no answer
+		{"FIN probe", recordedFINProbe, FlagScanOpenFiltered, 0},
+		{"Xmas probe", recordedXmasProbe, FlagScanOpenFiltered, 0},
+	}
+	for _, test := range tests {
+		segment, err := ParseTCP(decodeHex(t, test.segment))
+		if err != nil {
+			t.Fatalf("%s: ParseTCP failed: %s", test.name, err)
+		}
+		if state := ClassifyFlagResponse(segment); state != test.expected {
+			t.Errorf("%s: Expected %q, got %q", test.name, test.expected, state)
+		}
+		if segment.Ack != test.ack {
+			t.Errorf("%s: Expected ack %d, got %d", test.name, test.ack, segment.Ack)
+		}
+	}
+	if _, err := ParseTCP([]byte{0, 80}); err == nil {
+		t.Errorf("Expected an error for a truncated segment")
+	}
+}
+
+func TestScanTCPFlags_Loopback(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	open := listener.Addr().(*net.TCPAddr).Port
+
+	closedListener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	closed := closedListener.Addr().(*net.TCPAddr).Port
+	closedListener.Close()
+
+	for _, flags := range []uint8{ScanFIN, ScanNULL, ScanXmas} {
+		state, err := ScanTCPFlags("127.0.0.1", closed, flags, 200*time.Millisecond)
+		if errors.Is(err, ErrRawSocketUnavailable) {
+			t.Skipf("Raw sockets unavailable: %s", err)
+		}
+		if err != nil || state != FlagScanClosed {
+			t.Errorf("Flags %#x on a closed port: Expected %q, got %q (%v)", flags, FlagScanClosed, state, err)
+		}
+		state, err = ScanTCPFlags("127.0.0.1", open, flags, 200*time.Millisecond)
+		if err != nil || state != FlagScanOpenFiltered {
+			t.Errorf("Flags %#x on an open port: Expected %q, got %q (%v)", flags, FlagScanOpenFiltered, state, err)
+		}
+	}
+}
+
+// FuzzParseTCP checks that no segment read from the raw socket makes
+// ParseTCP panic. Run it with:
+//
+//	go test -run '^$' -fuzz FuzzParseTCP -fuzztime 30s
+func FuzzParseTCP(f *testing.F) {
+	for _, recorded := range []string{recordedFINProbe, recordedRSTAfterFIN, recordedRSTAfterNULL} {
+		f.Add(decodeHex(f, recorded))
+	}
+	f.Add([]byte{0x00, 0x50})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ParseTCP(b)
+	})
+}
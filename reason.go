@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// Reasons reported alongside a port's state, naming the signal the state
+// was derived from.
+const (
+	ReasonConnected       = "connection accepted"
+	ReasonRefused         = "connection refused"
+	ReasonTimeout         = "timeout"
+	ReasonHostUnreachable = "host unreachable"
+	ReasonNetUnreachable  = "network unreachable"
+	ReasonBlocked         = "blocked by local firewall"
+	ReasonPortUnreachable = "ICMP port-unreachable"
+	ReasonResponse        = "response received"
+	ReasonUnconfirmed     = "open not confirmed by a second probe"
+	ReasonTestMode        = "synthetic result of TestMode"
+)
+
+// ReasonFor names the signal behind a failed probe, so a bare "Filtered" or
+// "Closed" can be explained.
+//
+// Parameters:
+// - err: The error returned while probing.
+//
+// Returns:
+// - One of the Reason constants, or the error text for unrecognized errors.
+//
+// Example:
+//
+//	_, err := net.DialTimeout("tcp", "192.0.2.1:80", time.Second)
+//	fmt.Println(ReasonFor(err)) // "timeout"
+func ReasonFor(err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrPortUnreachable):
+		return ReasonPortUnreachable
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ReasonRefused
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return ReasonHostUnreachable
+	case errors.Is(err, syscall.ENETUNREACH):
+		return ReasonNetUnreachable
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return ReasonBlocked
+	}
+	return err.Error()
+}
+
+// udpReason names the signal behind a failed UDP probe. A connected UDP
+// socket reports an ICMP port-unreachable as ECONNREFUSED.
+func udpReason(err error) string {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ReasonPortUnreachable
+	}
+	return ReasonFor(err)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// connectError wraps a syscall error the way a failed net.Dial reports it.
+func connectError(errno syscall.Errno) error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+}
+
+func TestWorkerTCP_Reason(t *testing.T) {
+	tests := []struct {
+		err      error
+		state    string
+		expected string
+	}{
+		{nil, "Open", ReasonConnected},
+		{timeoutError{}, "Filtered", ReasonTimeout},
+		{connectError(syscall.ECONNREFUSED), "Closed", ReasonRefused},
+		{connectError(syscall.EHOSTUNREACH), "Closed", ReasonHostUnreachable},
+		{connectError(syscall.ENETUNREACH), "Closed", ReasonNetUnreachable},
+		{errors.New("something odd"), "Closed", "something odd"},
+	}
+	for _, test := range tests {
+		err := test.err
+		scanner := &PortScanner{
+			Dial: func(network, address string, timeout time.Duration) (net.Conn, error) {
+				if err != nil {
+					return nil, err
+				}
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			},
+			ReportStates: []string{"Open", "Closed", "Filtered"},
+		}
+		runTCPWorker(scanner, "127.0.0.1", []int{80})
+		result, ok := <-scanner.OpenPorts
+		if !ok {
+			t.Fatalf("Expected a result for error %v", test.err)
+		}
+		if result.State != test.state {
+			t.Errorf("Expected state %s for error %v, got %s", test.state, test.err, result.State)
+		}
+		if result.Reason != test.expected {
+			t.Errorf("Expected reason %q for error %v, got %q", test.expected, test.err, result.Reason)
+		}
+	}
+}
+
+func TestUDPReason(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{ErrPortUnreachable, ReasonPortUnreachable},
+		{&net.OpError{Op: "read", Net: "udp", Err: os.NewSyscallError("read", syscall.ECONNREFUSED)}, ReasonPortUnreachable},
+		{timeoutError{}, ReasonTimeout},
+	}
+	for _, test := range tests {
+		if reason := udpReason(test.err); reason != test.expected {
+			t.Errorf("Expected reason %q for error %v, got %q", test.expected, test.err, reason)
+		}
+	}
+}
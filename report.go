@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"det/service"
+)
+
+// ScanReport is the materialized result of a completed scan, suitable for
+// JSON encoding.
+//
+// Fields:
+// - Meta: How the scan was configured and when it ran.
+// - Target: The scanned domain.
+// - Resolution: The DNS details of the target.
+// - TCP: The reported TCP ports.
+// - UDP: The reported UDP ports.
+// - ICMP: The ICMP reachability results.
+// - ICMPStats: The loss and RTT statistics of each IP, when it was pinged several times.
+// - Geo: The GeoInfo of each scanned IP, when a GeoLookup is configured.
+// - Unscanned: The number of probes skipped per IP because it used up PerHostBudget.
+// - PTR: The host names of each scanned IP with a PTR record, when ReverseDNS is set.
+// - ProxySuspected: Whether a transparent proxy likely answered the TCP probes.
+// - Truncated: Whether the scan stopped early because MaxResults was reached.
+// - Cancelled: Whether the scan was cancelled before it completed.
+// - Summary: The number of TCP and UDP ports probed in each state.
+//
+// Example:
+//
+//	scanner.Scan()
+//	report := scanner.Report()
+//	json.NewEncoder(os.Stdout).Encode(report)
+type ScanReport struct {
+	Meta           ScanMeta                 `json:"meta"`
+	Target         string                   `json:"target"`
+	Resolution     Resolution               `json:"resolution"`
+	TCP            []service.ServiceVersion `json:"tcp"`
+	UDP            []service.ServiceVersion `json:"udp"`
+	ICMP           []ICMPResult             `json:"icmp"`
+	ICMPStats      []ICMPStats              `json:"icmp_stats,omitempty"`
+	Geo            map[string]GeoInfo       `json:"geo,omitempty"`
+	PTR            map[string][]string      `json:"ptr,omitempty"`
+	Unscanned      map[string]int           `json:"unscanned,omitempty"`
+	ProxySuspected bool                     `json:"proxy_suspected"`
+	Truncated      bool                     `json:"truncated"`
+	Cancelled      bool                     `json:"cancelled"`
+	Summary        ScanSummary              `json:"summary"`
+}
+
+// Report drains the result channels into a ScanReport. It never blocks: if
+// Scan has not finished, the report holds the results found so far. Drained
+// results are kept, so later calls return everything found up to that point.
+//
+// Returns:
+// - The scan results.
+//
+// Example:
+//
+//	scanner.Scan()
+//	report := scanner.Report()
+func (t *PortScanner) Report() ScanReport {
+	t.reportMu.Lock()
+	defer t.reportMu.Unlock()
+	t.collected.TCP = append(t.collected.TCP, drain(t.OpenPorts)...)
+	t.collected.UDP = append(t.collected.UDP, drain(t.OpenPortsUDP)...)
+	t.collected.ICMP = append(t.collected.ICMP, drain(t.ICMPResults)...)
+
+	var geo map[string]GeoInfo
+	if t.collected.Geo != nil {
+		geo = make(map[string]GeoInfo, len(t.collected.Geo))
+		for ip, info := range t.collected.Geo {
+			geo[ip] = info
+		}
+	}
+
+	var ptr map[string][]string
+	if t.collected.PTR != nil {
+		ptr = make(map[string][]string, len(t.collected.PTR))
+		for ip, names := range t.collected.PTR {
+			ptr[ip] = append([]string(nil), names...)
+		}
+	}
+
+	var unscanned map[string]int
+	if t.collected.Unscanned != nil {
+		unscanned = make(map[string]int, len(t.collected.Unscanned))
+		for ip, count := range t.collected.Unscanned {
+			unscanned[ip] = count
+		}
+	}
+
+	return ScanReport{
+		Meta:           t.meta(),
+		Target:         t.Domain,
+		Resolution:     t.Resolution,
+		TCP:            append([]service.ServiceVersion{}, t.collected.TCP...),
+		UDP:            append([]service.ServiceVersion{}, t.collected.UDP...),
+		ICMP:           append([]ICMPResult{}, t.collected.ICMP...),
+		ICMPStats:      append([]ICMPStats(nil), t.collected.ICMPStats...),
+		Geo:            geo,
+		PTR:            ptr,
+		Unscanned:      unscanned,
+		ProxySuspected: t.ProxySuspected,
+		Truncated:      t.Truncated,
+		Cancelled:      t.Cancelled,
+		Summary:        t.Summary(),
+	}
+}
+
+// drain receives every value currently available on ch without blocking,
+// stopping when ch is empty or closed.
+func drain[T any](ch chan T) []T {
+	var values []T
+	for {
+		select {
+		case value, ok := <-ch:
+			if !ok {
+				return values
+			}
+			values = append(values, value)
+		default:
+			return values
+		}
+	}
+}
+
+// ServiceGroup is a port reported with the same service and state on
+// several IPs.
+//
+// Fields:
+// - Service: The reported port, with the IP of its first occurrence.
+// - IPs: Every IP the port was reported on, in order.
+type ServiceGroup struct {
+	Service service.ServiceVersion `json:"service"`
+	IPs     []string               `json:"ips"`
+}
+
+// GroupServices collapses results with the same port, service and state
+// across IPs, so a port open on every IP of a multi-IP target is listed once.
+//
+// Parameters:
+// - services: The results to group.
+//
+// Returns:
+// - The groups, sorted by port.
+//
+// Example:
+//
+//	for _, group := range GroupServices(report.TCP) {
+//	    fmt.Printf("Port %d is open on %d IPs\n", group.Service.Port, len(group.IPs))
+//	}
+func GroupServices(services []service.ServiceVersion) []ServiceGroup {
+	type groupKey struct {
+		port           int
+		service, state string
+	}
+	var groups []ServiceGroup
+	index := map[groupKey]int{}
+	for _, svc := range sortedByIP(services) {
+		key := groupKey{svc.Port, svc.Service, svc.State}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, ServiceGroup{Service: svc})
+		}
+		if svc.IP != "" {
+			groups[i].IPs = append(groups[i].IPs, svc.IP)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Service.Port < groups[j].Service.Port
+	})
+	return groups
+}
+
+// sortedByIP returns a copy of services sorted by IP, then port.
+func sortedByIP(services []service.ServiceVersion) []service.ServiceVersion {
+	sorted := append([]service.ServiceVersion{}, services...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].IP != sorted[j].IP {
+			return sorted[i].IP < sorted[j].IP
+		}
+		return sorted[i].Port < sorted[j].Port
+	})
+	return sorted
+}
+
+// writeServices writes the reported ports of one protocol, grouped under a
+// header per IP, or collapsed across IPs when unique is set.
+//
+// Parameters:
+// - w: Where to write.
+// - protocol: The protocol named in each line, "TCP" or "UDP".
+// - services: The reported ports.
+// - unique: Whether to collapse identical results across IPs.
+// - ptr: The host names shown in the header of each IP, may be nil.
+//
+// Returns:
+// - An error if writing fails.
+func writeServices(w io.Writer, protocol string, services []service.ServiceVersion, unique bool, ptr map[string][]string) error {
+	if unique {
+		for _, group := range GroupServices(services) {
+			svc := group.Service
+			line := serviceLine(protocol, svc)
+			if len(group.IPs) > 0 {
+				line += fmt.Sprintf(" (on %d IPs: %s)", len(group.IPs), strings.Join(group.IPs, ", "))
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return fmt.Errorf("error writing to file: %s", err)
+			}
+		}
+		return nil
+	}
+
+	ip := ""
+	for _, svc := range sortedByIP(services) {
+		if svc.IP != ip {
+			ip = svc.IP
+			header := "IP " + ip
+			if names := ptr[ip]; len(names) > 0 {
+				header += " (" + strings.Join(names, ", ") + ")"
+			}
+			if _, err := fmt.Fprintf(w, "%s:\n", header); err != nil {
+				return fmt.Errorf("error writing to file: %s", err)
+			}
+		}
+		if _, err := fmt.Fprintln(w, serviceLine(protocol, svc)); err != nil {
+			return fmt.Errorf("error writing to file: %s", err)
+		}
+	}
+	return nil
+}
+
+// serviceLine formats a reported port as a report line.
+func serviceLine(protocol string, svc service.ServiceVersion) string {
+	line := fmt.Sprintf("Port %d (%s) is %s, Service: %s", svc.Port, protocol, svc.State, svc.Service)
+	if svc.Banner != "" {
+		line += fmt.Sprintf(", Banner: %q", svc.Banner)
+	}
+	// Only flag exposures worth triaging
+	if svc.Severity.Rank() > service.SeverityInfo.Rank() {
+		line += fmt.Sprintf(", Severity: %s", svc.Severity)
+	}
+	if svc.Vuln != "" {
+		line += fmt.Sprintf(", Vulnerable: %s", svc.Vuln)
+	}
+	if svc.MultipleBackends {
+		line += fmt.Sprintf(", multiple backends detected (%d)", len(svc.Backends))
+	}
+	if svc.Reason != "" && svc.State != "Open" {
+		line += fmt.Sprintf(", Reason: %s", svc.Reason)
+	}
+	return line
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"det/service"
+)
+
+// DefaultRescanTimeout is the connect timeout of the second pass over
+// filtered ports when RescanTimeout is not set, longer than the usual
+// timeout since the first attempt already timed out.
+const DefaultRescanTimeout = 3 * time.Second
+
+// deferFiltered holds back a TCP port found filtered in the first pass when
+// RescanFiltered is set, so it is only reported after rescanFiltered has
+// probed it again.
+//
+// Parameters:
+// - endpoint: The filtered endpoint.
+// - svc: Its first-pass result, reported as is if the rescan is cancelled.
+//
+// Returns:
+// - Whether the port was held back for the second pass.
+func (t *PortScanner) deferFiltered(endpoint Endpoint, svc service.ServiceVersion) bool {
+	if !t.RescanFiltered || t.rescanning {
+		return false
+	}
+	t.reportMu.Lock()
+	t.filtered = append(t.filtered, filteredPort{endpoint: endpoint, first: svc})
+	t.reportMu.Unlock()
+	return true
+}
+
+// filteredPort is a port held back by deferFiltered.
+type filteredPort struct {
+	endpoint Endpoint
+	first    service.ServiceVersion
+}
+
+// rescanFiltered probes the TCP ports found filtered in the first pass once
+// more, with RescanTimeout (or DefaultRescanTimeout), and reports their
+// final state: ports that answer now are promoted to Open or Closed, the
+// others stay Filtered. A packet lost under load or a slow host thus no
+// longer hides an open port.
+//
+// Parameters:
+// - ctx: Cancels the second pass, the held back ports are then reported with their first-pass result.
+func (t *PortScanner) rescanFiltered(ctx context.Context) {
+	t.reportMu.Lock()
+	filtered := t.filtered
+	t.filtered = nil
+	t.reportMu.Unlock()
+	if len(filtered) == 0 {
+		return
+	}
+
+	if ctx.Err() != nil {
+		for _, port := range filtered {
+			if t.reportsState(port.first.State) {
+				t.sendResult(t.OpenPorts, port.first)
+			}
+		}
+		return
+	}
+
+	t.logf("Rescanning %d filtered TCP ports\n", len(filtered))
+	t.rescanning = true
+	defer func() { t.rescanning = false }()
+
+	endpoints := make(chan Endpoint, len(filtered))
+	for _, port := range filtered {
+		endpoints <- port.endpoint
+	}
+	close(endpoints)
+
+	workers := t.scanWorkers()
+	if workers <= 0 || workers > len(filtered) {
+		workers = len(filtered)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for endpoint := range endpoints {
+				t.waitResumed(ctx)
+				t.probeEndpointTCP(ctx, endpoint, t.OpenPorts)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// rescanTimeout returns the connect timeout of the second pass.
+func (t *PortScanner) rescanTimeout() time.Duration {
+	if t.RescanTimeout > 0 {
+		return t.RescanTimeout
+	}
+	return DefaultRescanTimeout
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRescanFiltered_PromotesPortsThatAnswer(t *testing.T) {
+	var attempts int64
+	dialer := &mockDialer{
+		// Port 80 drops the first SYN, 81 never answers, 82 refuses
+		timeout: func(address string) bool {
+			switch address {
+			case "127.0.0.1:80":
+				return atomic.AddInt64(&attempts, 1) == 1
+			case "127.0.0.1:81":
+				return true
+			}
+			return false
+		},
+		accept: func(address string) bool { return address != "127.0.0.1:82" },
+	}
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{80, 81, 82}
+	scanner.Timeout = time.Second
+	scanner.RescanFiltered = true
+	scanner.RescanTimeout = 5 * time.Second
+	scanner.ReportStates = []string{"Open", "Filtered"}
+	scanner.Dial = dialer.Dial
+	// Only the probe dials count, the HTTP probe of port 80 would add one
+	scanner.HTTPProbe = false
+	scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	scanner.Quiet = true
+	scanner.Scan()
+
+	states := map[int][]string{}
+	for _, svc := range scanner.Report().TCP {
+		states[svc.Port] = append(states[svc.Port], svc.State)
+	}
+	if len(states[80]) != 1 || states[80][0] != "Open" {
+		t.Errorf("Expected port 80 to be reported Open once after the rescan, got %v", states[80])
+	}
+	if len(states[81]) != 1 || states[81][0] != "Filtered" {
+		t.Errorf("Expected port 81 to be reported Filtered once, got %v", states[81])
+	}
+	if len(states[82]) != 0 {
+		t.Errorf("Expected closed port 82 not to be reported, got %v", states[82])
+	}
+
+	var first, second []time.Duration
+	for i, addr := range dialer.addrs {
+		if addr != "tcp://127.0.0.1:80" {
+			continue
+		}
+		if first == nil {
+			first = append(first, dialer.timeouts[i])
+		} else {
+			second = append(second, dialer.timeouts[i])
+		}
+	}
+	if len(first) != 1 || first[0] != time.Second {
+		t.Errorf("Expected the first pass to use the 1s timeout, got %v", first)
+	}
+	if len(second) != 1 || second[0] != 5*time.Second {
+		t.Errorf("Expected the rescan to use RescanTimeout, got %v", second)
+	}
+}
+
+func TestRescanFiltered_Disabled(t *testing.T) {
+	var attempts int64
+	dialer := &mockDialer{timeout: func(address string) bool { return atomic.AddInt64(&attempts, 1) == 1 }}
+	scanner := &PortScanner{ReportStates: []string{"Open", "Filtered"}, Dial: dialer.Dial, Quiet: true}
+	runTCPWorker(scanner, "127.0.0.1", []int{80})
+
+	var states []string
+	for svc := range scanner.OpenPorts {
+		states = append(states, svc.State)
+	}
+	if len(states) != 1 || states[0] != "Filtered" {
+		t.Errorf("Expected a single Filtered result without RescanFiltered, got %v", states)
+	}
+}
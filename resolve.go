@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// Resolver looks up the addresses and canonical name of a host.
+// *net.Resolver satisfies this interface; tests can substitute a stub.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// Resolution holds the DNS details gathered for a scan target.
+//
+// Fields:
+// - Host: The host name that was resolved.
+// - CNAME: The canonical name of the host, empty if it has none.
+// - IPs: Every IP address the host resolved to.
+//
+// Example:
+//
+//	res := Resolution{Host: "www.example.com", CNAME: "example.cdn.net", IPs: []string{"192.0.2.1"}}
+type Resolution struct {
+	Host  string   `json:"host"`
+	CNAME string   `json:"cname,omitempty"`
+	IPs   []string `json:"ips"`
+}
+
+// LocalhostIPs are the addresses "localhost" resolves to, in scan order.
+// Resolve uses them instead of asking the system resolver, whose answer
+// and order for localhost differ between systems. IPv4 comes first; reorder
+// or trim them to scan IPv6 first or only one family.
+var LocalhostIPs = []string{"127.0.0.1", "::1"}
+
+// Resolve looks up the IP addresses and canonical name of a host.
+// IP literals and "localhost" are resolved without a lookup (see
+// LocalhostIPs). A failed CNAME lookup is not an error; the CNAME is simply left empty.
+//
+// Parameters:
+// - resolver: The resolver to query.
+// - host: The host name to resolve.
+//
+// Returns:
+// - The gathered Resolution.
+// - An error if the host cannot be resolved to an IP address.
+//
+// Example:
+//
+//	res, err := Resolve(net.DefaultResolver, "example.com")
+func Resolve(resolver Resolver, host string) (Resolution, error) {
+	if res, ok := resolveLocal(host); ok {
+		return res, nil
+	}
+	ctx := context.Background()
+	ips, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return Resolution{}, err
+	}
+	res := Resolution{Host: host, IPs: ips}
+	if cname, err := resolver.LookupCNAME(ctx, host); err == nil {
+		cname = strings.TrimSuffix(cname, ".")
+		if cname != host {
+			res.CNAME = cname
+		}
+	}
+	return res, nil
+}
+
+// resolveLocal resolves the hosts that need no lookup: IP literals stand for
+// themselves and "localhost" for LocalhostIPs.
+//
+// Parameters:
+// - host: The host name to resolve.
+//
+// Returns:
+// - The Resolution of the host.
+// - false if the host must be looked up.
+func resolveLocal(host string) (Resolution, bool) {
+	if net.ParseIP(host) != nil {
+		return Resolution{Host: host, IPs: []string{host}}, true
+	}
+	if strings.EqualFold(strings.TrimSuffix(host, "."), "localhost") {
+		return Resolution{Host: host, IPs: append([]string{}, LocalhostIPs...)}, true
+	}
+	return Resolution{}, false
+}
+
+// String formats the resolution for the report header.
+func (r Resolution) String() string {
+	s := "Target: " + r.Host
+	if r.CNAME != "" {
+		s += " (CNAME: " + r.CNAME + ")"
+	}
+	return s + "\nResolved IPs: " + strings.Join(r.IPs, ", ")
+}
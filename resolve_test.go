@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubResolver answers lookups from fixed tables and counts the queries.
+type stubResolver struct {
+	hosts   map[string][]string
+	cnames  map[string]string
+	ptrs    map[string][]string
+	mu      sync.Mutex
+	lookups int
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	r.lookups++
+	r.mu.Unlock()
+	ips, ok := r.hosts[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+func (r *stubResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.mu.Lock()
+	r.lookups++
+	r.mu.Unlock()
+	names, ok := r.ptrs[addr]
+	if !ok {
+		return nil, errors.New("no PTR record")
+	}
+	return names, nil
+}
+
+func (r *stubResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if cname, ok := r.cnames[host]; ok {
+		return cname, nil
+	}
+	return host + ".", nil
+}
+
+func TestNewTargetWithResolver_Resolution(t *testing.T) {
+	resolver := &stubResolver{
+		hosts:  map[string][]string{"www.example.com": {"192.0.2.1", "192.0.2.2"}},
+		cnames: map[string]string{"www.example.com": "example.cdn.net."},
+	}
+	scanner, err := NewTargetWithResolver("www.example.com", 1, resolver)
+	if err != nil {
+		t.Fatalf("NewTargetWithResolver failed: %s", err)
+	}
+
+	expected := Resolution{Host: "www.example.com", CNAME: "example.cdn.net", IPs: []string{"192.0.2.1", "192.0.2.2"}}
+	if !reflect.DeepEqual(scanner.Resolution, expected) {
+		t.Errorf("Expected resolution %+v, got %+v", expected, scanner.Resolution)
+	}
+	if !reflect.DeepEqual(scanner.IPs, expected.IPs) {
+		t.Errorf("Expected IPs %v, got %v", expected.IPs, scanner.IPs)
+	}
+
+	close(scanner.OpenPorts)
+	close(scanner.OpenPortsUDP)
+	close(scanner.ICMPResults)
+	fileName := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, fileName); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	for _, line := range []string{"Target: www.example.com (CNAME: example.cdn.net)", "Resolved IPs: 192.0.2.1, 192.0.2.2"} {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("Expected %q in report header, got:\n%s", line, data)
+		}
+	}
+}
+
+func TestNewTargetWithResolver_Error(t *testing.T) {
+	if _, err := NewTargetWithResolver("missing.example", 1, &stubResolver{}); err == nil {
+		t.Errorf("Expected an error for an unresolvable host")
+	}
+}
+
+func TestScan_SamePortOnSeveralIPs(t *testing.T) {
+	ips := []string{"127.0.0.1", "127.0.0.2", "127.0.0.3"}
+	resolver := &stubResolver{hosts: map[string][]string{"www.example.com": ips}}
+	scanner, err := NewTargetWithResolver("www.example.com", 4, resolver)
+	if err != nil {
+		t.Fatalf("NewTargetWithResolver failed: %s", err)
+	}
+	dialer := &mockDialer{accept: func(address string) bool { return strings.HasSuffix(address, ":443") }}
+	scanner.Ports = []int{443, 444}
+	scanner.Dial = dialer.Dial
+	scanner.Quiet = true
+
+	scanner.Scan()
+
+	var reported []string
+	for _, svc := range scanner.Report().TCP {
+		reported = append(reported, svc.IP)
+	}
+	sort.Strings(reported)
+	if !reflect.DeepEqual(reported, ips) {
+		t.Errorf("Expected port 443 reported once per IP %v, got %v", ips, reported)
+	}
+
+	dir := t.TempDir()
+	grouped := filepath.Join(dir, "grouped.txt")
+	if err := writeResultsToFile(scanner, grouped); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(grouped)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	for _, ip := range ips {
+		if !strings.Contains(string(data), "IP "+ip+":\nPort 443 (TCP) is Open") {
+			t.Errorf("Expected port 443 listed under IP %s, got:\n%s", ip, data)
+		}
+	}
+
+	scanner.Unique = true
+	unique := filepath.Join(dir, "unique.txt")
+	if err := writeResultsToFile(scanner, unique); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err = os.ReadFile(unique)
+	if err != nil {
+		t.Fatalf("Reading output failed: %s", err)
+	}
+	expected := "Port 443 (TCP) is Open, Service: https (on 3 IPs: 127.0.0.1, 127.0.0.2, 127.0.0.3)\n"
+	if strings.Count(string(data), "Port 443 (TCP)") != 1 || !strings.Contains(string(data), expected) {
+		t.Errorf("Expected port 443 collapsed into %q, got:\n%s", expected, data)
+	}
+}
+
+func TestNewTargetWithResolver_Localhost(t *testing.T) {
+	// The system resolver would answer localhost with ::1 first here
+	resolver := &stubResolver{hosts: map[string][]string{"localhost": {"::1", "127.0.0.1"}}}
+	for _, host := range []string{"localhost", "LOCALHOST", "localhost.", "127.0.0.1", "::1"} {
+		scanner, err := NewTargetWithResolver(host, 1, resolver)
+		if err != nil {
+			t.Fatalf("%s: NewTargetWithResolver failed: %s", host, err)
+		}
+		expected := LocalhostIPs
+		if !strings.HasPrefix(strings.ToLower(host), "localhost") {
+			expected = []string{host}
+		}
+		if !reflect.DeepEqual(scanner.IPs, expected) {
+			t.Errorf("%s: Expected IPs %v, got %v", host, expected, scanner.IPs)
+		}
+	}
+	if resolver.lookups != 0 {
+		t.Errorf("Expected no lookups, got %d", resolver.lookups)
+	}
+	if LocalhostIPs[0] != "127.0.0.1" {
+		t.Errorf("Expected IPv4 first, got %v", LocalhostIPs)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultDNSCacheTTL is how long a CachingResolver keeps an answer.
+const DefaultDNSCacheTTL = 5 * time.Minute
+
+// errNoAddrResolver is returned by CachingResolver.LookupAddr when the
+// wrapped resolver cannot do reverse lookups.
+var errNoAddrResolver = errors.New("resolver does not support reverse lookups")
+
+// DefaultResolveWorkers is the number of concurrent lookups ResolveTargets
+// performs.
+const DefaultResolveWorkers = 16
+
+// cacheEntry is a cached or in-flight lookup. done is closed once the
+// answer is available, so concurrent lookups of the same name wait for the
+// first one instead of querying again.
+type cacheEntry struct {
+	done    chan struct{}
+	ips     []string
+	cname   string
+	err     error
+	expires time.Time
+}
+
+// CachingResolver wraps a Resolver and remembers its answers for a TTL, so a
+// hostname repeated in a target list is looked up only once.
+//
+// Fields:
+// - Resolver: The resolver queried on a cache miss.
+// - TTL: How long answers are kept, DefaultDNSCacheTTL when zero.
+//
+// Example:
+//
+//	resolver := NewCachingResolver(net.DefaultResolver, time.Minute)
+//	res, err := Resolve(resolver, "example.com")
+type CachingResolver struct {
+	Resolver Resolver
+	TTL      time.Duration
+
+	mu     sync.Mutex
+	hosts  map[string]*cacheEntry
+	cnames map[string]*cacheEntry
+	addrs  map[string]*cacheEntry
+	now    func() time.Time
+}
+
+// NewCachingResolver creates a CachingResolver.
+//
+// Parameters:
+// - resolver: The resolver queried on a cache miss.
+// - ttl: How long answers are kept.
+//
+// Returns:
+// - The CachingResolver.
+//
+// Example:
+//
+//	resolver := NewCachingResolver(net.DefaultResolver, DefaultDNSCacheTTL)
+func NewCachingResolver(resolver Resolver, ttl time.Duration) *CachingResolver {
+	return &CachingResolver{Resolver: resolver, TTL: ttl}
+}
+
+// LookupHost returns the cached addresses of host, querying the wrapped
+// resolver when they are missing or expired. Failed lookups are not cached.
+func (r *CachingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	entry := r.lookup(&r.hosts, host, func(entry *cacheEntry) {
+		entry.ips, entry.err = r.Resolver.LookupHost(ctx, host)
+	})
+	return entry.ips, entry.err
+}
+
+// LookupCNAME returns the cached canonical name of host, querying the
+// wrapped resolver when it is missing or expired. Failed lookups are not
+// cached.
+func (r *CachingResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	entry := r.lookup(&r.cnames, host, func(entry *cacheEntry) {
+		entry.cname, entry.err = r.Resolver.LookupCNAME(ctx, host)
+	})
+	return entry.cname, entry.err
+}
+
+// LookupAddr returns the cached host names of addr, querying the wrapped
+// resolver when they are missing or expired. Failed lookups are not cached.
+// The wrapped resolver must implement AddrResolver.
+func (r *CachingResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	entry := r.lookup(&r.addrs, addr, func(entry *cacheEntry) {
+		resolver, ok := r.Resolver.(AddrResolver)
+		if !ok {
+			entry.err = errNoAddrResolver
+			return
+		}
+		entry.ips, entry.err = resolver.LookupAddr(ctx, addr)
+	})
+	return entry.ips, entry.err
+}
+
+// lookup returns the entry for host in cache, running query to fill it when
+// it is missing or expired.
+func (r *CachingResolver) lookup(cache *map[string]*cacheEntry, host string, query func(*cacheEntry)) *cacheEntry {
+	r.mu.Lock()
+	if *cache == nil {
+		*cache = map[string]*cacheEntry{}
+	}
+	now := r.clock()
+	if entry, ok := (*cache)[host]; ok {
+		select {
+		case <-entry.done:
+			if now.Before(entry.expires) {
+				r.mu.Unlock()
+				return entry
+			}
+		default:
+			// Another goroutine is looking the host up
+			r.mu.Unlock()
+			<-entry.done
+			return entry
+		}
+	}
+	entry := &cacheEntry{done: make(chan struct{})}
+	(*cache)[host] = entry
+	r.mu.Unlock()
+
+	query(entry)
+
+	r.mu.Lock()
+	entry.expires = r.clock().Add(r.ttl())
+	if entry.err != nil && (*cache)[host] == entry {
+		delete(*cache, host)
+	}
+	close(entry.done)
+	r.mu.Unlock()
+	return entry
+}
+
+// ttl returns the configured TTL, or DefaultDNSCacheTTL when unset.
+func (r *CachingResolver) ttl() time.Duration {
+	if r.TTL > 0 {
+		return r.TTL
+	}
+	return DefaultDNSCacheTTL
+}
+
+// clock returns the current time, from the test hook when set.
+func (r *CachingResolver) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
+
+// ResolveTargets resolves several hosts concurrently with a bounded pool of
+// lookups.
+//
+// Parameters:
+// - resolver: The resolver to query, ideally a CachingResolver.
+// - hosts: The host names to resolve.
+// - workers: The maximum number of concurrent lookups, DefaultResolveWorkers if not positive.
+//
+// Returns:
+// - The Resolution of each host, in the order of hosts.
+// - The error of each host's lookup, nil on success, in the order of hosts.
+//
+// Example:
+//
+//	resolutions, errs := ResolveTargets(NewCachingResolver(net.DefaultResolver, DefaultDNSCacheTTL), hosts, 0)
+func ResolveTargets(resolver Resolver, hosts []string, workers int) ([]Resolution, []error) {
+	if workers <= 0 {
+		workers = DefaultResolveWorkers
+	}
+	resolutions := make([]Resolution, len(hosts))
+	errs := make([]error, len(hosts))
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, host string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			resolutions[i], errs[i] = Resolve(resolver, host)
+		}(i, host)
+	}
+	wg.Wait()
+	return resolutions, errs
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCachingResolver_LooksUpOnceWithinTTL(t *testing.T) {
+	stub := &stubResolver{hosts: map[string][]string{"example.com": {"192.0.2.1"}}}
+	now := time.Unix(0, 0)
+	resolver := NewCachingResolver(stub, time.Minute)
+	resolver.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ips, err := resolver.LookupHost(context.Background(), "example.com")
+		if err != nil {
+			t.Fatalf("LookupHost failed: %s", err)
+		}
+		if !reflect.DeepEqual(ips, []string{"192.0.2.1"}) {
+			t.Errorf("Expected [192.0.2.1], got %v", ips)
+		}
+	}
+	if stub.lookups != 1 {
+		t.Errorf("Expected 1 lookup within the TTL, got %d", stub.lookups)
+	}
+
+	now = now.Add(2 * time.Minute)
+	resolver.LookupHost(context.Background(), "example.com")
+	if stub.lookups != 2 {
+		t.Errorf("Expected a new lookup after the TTL, got %d lookups", stub.lookups)
+	}
+}
+
+func TestCachingResolver_DoesNotCacheErrors(t *testing.T) {
+	stub := &stubResolver{}
+	resolver := NewCachingResolver(stub, time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.LookupHost(context.Background(), "missing.example"); err == nil {
+			t.Errorf("Expected an error for an unknown host")
+		}
+	}
+	if stub.lookups != 2 {
+		t.Errorf("Expected failed lookups to be retried, got %d lookups", stub.lookups)
+	}
+}
+
+func TestResolveTargets_RepeatedHost(t *testing.T) {
+	stub := &stubResolver{hosts: map[string][]string{
+		"a.example": {"192.0.2.1"},
+		"b.example": {"192.0.2.2"},
+	}}
+	hosts := []string{"a.example", "b.example", "a.example", "missing.example", "a.example"}
+	resolutions, errs := ResolveTargets(NewCachingResolver(stub, time.Minute), hosts, 4)
+
+	for i, host := range hosts {
+		if host == "missing.example" {
+			if errs[i] == nil {
+				t.Errorf("Expected an error for %s", host)
+			}
+			continue
+		}
+		if errs[i] != nil {
+			t.Errorf("Resolving %s failed: %s", host, errs[i])
+		}
+		if !reflect.DeepEqual(resolutions[i].IPs, stub.hosts[host]) {
+			t.Errorf("Expected %s to resolve to %v, got %v", host, stub.hosts[host], resolutions[i].IPs)
+		}
+	}
+	if stub.lookups != 3 {
+		t.Errorf("Expected 3 lookups for 3 distinct hosts, got %d", stub.lookups)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import "syscall"
+
+// raiseFileLimit raises the soft open-file limit to the hard limit.
+//
+// Returns:
+// - The soft limit in effect afterwards, or 0 if it cannot be determined.
+func raiseFileLimit() uint64 {
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		return 0
+	}
+	if limit.Cur < limit.Max {
+		raised := limit
+		raised.Cur = raised.Max
+		if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &raised); err == nil {
+			limit = raised
+		}
+	}
+	return uint64(limit.Cur)
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+// raiseFileLimit is a no-op on Windows, which has no RLIMIT_NOFILE.
+//
+// Returns:
+// - 0, meaning the limit is unknown.
+func raiseFileLimit() uint64 {
+	return 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ScanError is a non-fatal error met during a scan, e.g. a failed reverse
+// DNS lookup. The scan carries on and the error is kept in Errors.
+//
+// Fields:
+// - Stage: The scan step that failed, e.g. "ptr" or "geo".
+// - Target: The IP address or file the step failed for, empty if none.
+// - Err: The underlying error.
+type ScanError struct {
+	Stage  string
+	Target string
+	Err    error
+}
+
+// Error formats the error with its stage and target.
+func (e *ScanError) Error() string {
+	if e.Target == "" {
+		return fmt.Sprintf("%s: %s", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Stage, e.Target, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ScanError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error as an object with its stage, target and
+// message.
+func (e *ScanError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Stage  string `json:"stage"`
+		Target string `json:"target,omitempty"`
+		Error  string `json:"error"`
+	}{e.Stage, e.Target, e.Err.Error()})
+}
+
+// addError keeps a non-fatal error in Errors. It is safe for concurrent use.
+//
+// Parameters:
+// - stage: The scan step that failed.
+// - target: The IP address or file the step failed for.
+// - err: The error.
+func (t *PortScanner) addError(stage, target string, err error) {
+	t.errorsMu.Lock()
+	defer t.errorsMu.Unlock()
+	t.Errors = append(t.Errors, &ScanError{Stage: stage, Target: target, Err: err})
+}
+
+// WriteErrorsJSON writes errors as a JSON object {"errors": [...]}, one entry
+// per error, so pipeline consumers can read diagnostics apart from results.
+// A *ScanError keeps its stage and target; other errors only have a message.
+//
+// Parameters:
+// - w: Where to write, typically os.Stderr.
+// - errs: The errors, e.g. a scanner's Errors.
+//
+// Returns:
+// - An error if writing fails.
+//
+// Example:
+//
+//	scanner.Scan()
+//	WriteErrorsJSON(os.Stderr, scanner.Errors)
+func WriteErrorsJSON(w io.Writer, errs []error) error {
+	entries := make([]json.Marshaler, len(errs))
+	for i, err := range errs {
+		scanErr, ok := err.(*ScanError)
+		if !ok {
+			scanErr = &ScanError{Err: err}
+		}
+		entries[i] = scanErr
+	}
+	return json.NewEncoder(w).Encode(struct {
+		Errors []json.Marshaler `json:"errors"`
+	}{entries})
+}
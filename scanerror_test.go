@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestScan_ResolutionErrorInErrors(t *testing.T) {
+	stub := &stubResolver{hosts: map[string][]string{"127.0.0.1": {"127.0.0.1"}}}
+	scanner, err := NewTargetWithResolver("127.0.0.1", 1, stub)
+	if err != nil {
+		t.Fatalf("NewTargetWithResolver failed: %s", err)
+	}
+	scanner.Ports = []int{80}
+	scanner.Dial = (&mockDialer{}).Dial
+	scanner.HTTPProbe = false
+	scanner.Quiet = true
+	scanner.ReverseDNS = true
+	scanner.PTRResolver = stub
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+
+	scanner.Scan()
+
+	if len(scanner.Errors) != 1 {
+		t.Fatalf("Expected 1 error, got %v", scanner.Errors)
+	}
+	var scanErr *ScanError
+	if !errors.As(scanner.Errors[0], &scanErr) {
+		t.Fatalf("Expected a *ScanError, got %T", scanner.Errors[0])
+	}
+	if scanErr.Stage != "ptr" || scanErr.Target != "127.0.0.1" || scanErr.Err.Error() != "no PTR record" {
+		t.Errorf("Expected the failed PTR lookup of 127.0.0.1, got %+v", scanErr)
+	}
+}
+
+func TestWriteErrorsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	errs := []error{
+		&ScanError{Stage: "ptr", Target: "192.0.2.1", Err: errors.New("no PTR record")},
+		errors.New("plain failure"),
+	}
+	if err := WriteErrorsJSON(&buf, errs); err != nil {
+		t.Fatalf("WriteErrorsJSON failed: %s", err)
+	}
+	expected := `{"errors":[{"stage":"ptr","target":"192.0.2.1","error":"no PTR record"},{"stage":"","error":"plain failure"}]}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %s, got %s", expected, buf.String())
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"det/server"
+)
+
+// serveWorkers is the number of worker goroutines used for each server job.
+const serveWorkers = 100
+
+// scanJob runs a scan for the HTTP server.
+//
+// Parameters:
+// - ctx: The context bounding the scan.
+// - req: The target and port specification to scan.
+// - publish: Receives every ScanEvent as the scan finds it.
+// - snapshot: Receives the scanner's Report, so the job reports the results so far.
+//
+// Returns:
+// - The ScanReport of the completed scan.
+// - An error if the target cannot be resolved or the ports are invalid.
+func scanJob(ctx context.Context, req server.ScanRequest, publish func(event interface{}), snapshot func(report func() interface{})) (interface{}, error) {
+	target, err := NewTarget(req.Target, serveWorkers)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving domain: %s", err)
+	}
+	if req.Ports != "" {
+		ports, err := ParsePortSpec(req.Ports)
+		if err != nil {
+			return nil, err
+		}
+		target.Ports = ports
+	}
+	// The results go to the job, not to the server's terminal
+	target.Quiet = true
+	target.OnResult = func(event ScanEvent) { publish(event) }
+	snapshot(func() interface{} { return target.Report() })
+	target.ScanContext(ctx)
+	return target.Report(), nil
+}
+
+// serve runs the HTTP server exposing scans as jobs.
+//
+// Parameters:
+// - addr: The address to listen on, e.g. ":8080".
+//
+// Returns:
+// - The error that stopped the server.
+func serve(addr string) error {
+	fmt.Printf("Serving scans on %s\n", addr)
+	return http.ListenAndServe(addr, server.NewHandler(context.Background(), scanJob))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"det/server"
+)
+
+func TestScanJob(t *testing.T) {
+	var live func() interface{}
+	result, err := scanJob(context.Background(), server.ScanRequest{Target: "127.0.0.1", Ports: "40001-40002"}, func(interface{}) {},
+		func(report func() interface{}) { live = report })
+	if err != nil {
+		t.Fatalf("scanJob failed: %s", err)
+	}
+	if live == nil {
+		t.Fatalf("Expected scanJob to expose its report while running")
+	}
+	if report, ok := live().(ScanReport); !ok || report.Target != "127.0.0.1" {
+		t.Errorf("Expected the live report of the scan, got %#v", live())
+	}
+	report, ok := result.(ScanReport)
+	if !ok {
+		t.Fatalf("Expected a ScanReport, got %T", result)
+	}
+	if report.Target != "127.0.0.1" || len(report.Resolution.IPs) != 1 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestScanJob_InvalidPorts(t *testing.T) {
+	if _, err := scanJob(context.Background(), server.ScanRequest{Target: "127.0.0.1", Ports: "0-10"}, func(interface{}) {}, func(func() interface{}) {}); err == nil {
+		t.Errorf("Expected an error for an invalid port specification")
+	}
+}
+
+func TestServe_StreamsScanEvents(t *testing.T) {
+	var ports []string
+	for i := 0; i < 2; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen failed: %s", err)
+		}
+		defer listener.Close()
+		_, port, _ := net.SplitHostPort(listener.Addr().String())
+		ports = append(ports, port)
+	}
+	srv := httptest.NewServer(server.NewHandler(context.Background(), scanJob))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/scan", "application/json",
+		strings.NewReader(`{"target":"127.0.0.1","ports":"`+strings.Join(ports, ",")+`"}`))
+	if err != nil {
+		t.Fatalf("POST /scan failed: %s", err)
+	}
+	var job server.Job
+	json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+
+	stream, err := http.Get(srv.URL + "/scan/" + job.ID + "/stream")
+	if err != nil {
+		t.Fatalf("GET stream failed: %s", err)
+	}
+	defer stream.Body.Close()
+	open := map[int]bool{}
+	icmp := 0
+	lines := bufio.NewScanner(stream.Body)
+	for lines.Scan() {
+		data := strings.TrimPrefix(lines.Text(), "data: ")
+		if data == lines.Text() {
+			continue
+		}
+		var event ScanEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		switch {
+		case event.Type == EventPort && event.Port.Protocol == "TCP" && event.Port.State == "Open":
+			open[event.Port.Port] = true
+		case event.Type == EventICMP:
+			icmp++
+		}
+	}
+	if len(open) != len(ports) {
+		t.Errorf("Expected an event for each of the %d open ports, got %v", len(ports), open)
+	}
+	if icmp != 1 {
+		t.Errorf("Expected one ICMP event, got %d", icmp)
+	}
+}
@@ -0,0 +1,381 @@
+// Package server exposes port scans over HTTP as asynchronous jobs.
+//
+//	POST /scan             {"target":"example.com","ports":"1-1024"}  -> {"id":"1","status":"queued"}
+//	GET  /scan/{id}                                                   -> the job, with its report so far while running
+//	GET  /scan/{id}/stream                                            -> the job's events as server-sent events
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job states reported by the server.
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Defaults of the Handler limits set by NewHandler.
+const (
+	DefaultMaxRunning  = 4
+	DefaultMaxQueued   = 100
+	DefaultMaxFinished = 1000
+	DefaultFinishedTTL = time.Hour
+)
+
+// ScanRequest is the body of a POST /scan request.
+//
+// Fields:
+// - Target: The domain or IP address to scan.
+// - Ports: The ports to scan, e.g. "1-1024" (empty scans the scanner's defaults).
+type ScanRequest struct {
+	Target string `json:"target"`
+	Ports  string `json:"ports"`
+}
+
+// RunFunc performs a scan and returns its report. The report is encoded as
+// JSON, so any JSON-serializable value (typically a ScanReport) works.
+// Results found while scanning are handed to publish, typically as
+// ScanEvents, and forwarded to the clients streaming the job. A scan can
+// hand snapshot a function returning its report so far, which the job then
+// reports while it runs.
+type RunFunc func(ctx context.Context, req ScanRequest, publish func(event interface{}), snapshot func(report func() interface{})) (interface{}, error)
+
+// Job is the state of a submitted scan.
+//
+// Fields:
+// - ID: The job identifier.
+// - Status: One of queued, running, done or failed.
+// - Request: The submitted scan request.
+// - Report: The scan report once the job is done, or the report so far while it runs if the scan exposes one.
+// - Error: The failure message if the job failed.
+type Job struct {
+	ID      string      `json:"id"`
+	Status  string      `json:"status"`
+	Request ScanRequest `json:"request"`
+	Report  interface{} `json:"report,omitempty"`
+	Error   string      `json:"error,omitempty"`
+
+	snapshot func() interface{}
+}
+
+// Handler serves scan jobs over HTTP. Submitted jobs wait in a queue and
+// run asynchronously, at most MaxRunning at once, until the handler's
+// context is cancelled. Finished jobs are forgotten after FinishedTTL or
+// once more than MaxFinished have finished since, so a long-running server
+// does not grow without bound. The limits must be set before serving.
+//
+// Fields:
+// - MaxRunning: The number of jobs run at once (0 means no limit).
+// - MaxQueued: The number of jobs waiting to run; a submission beyond it is refused with 503 (0 means no limit).
+// - MaxFinished: The number of finished jobs kept, the oldest being forgotten first (0 means no limit).
+// - FinishedTTL: How long a finished job is kept (0 means forever).
+//
+// Example:
+//
+//	handler := server.NewHandler(ctx, run)
+//	handler.MaxRunning = 2
+//	http.ListenAndServe(":8080", handler)
+type Handler struct {
+	MaxRunning  int
+	MaxQueued   int
+	MaxFinished int
+	FinishedTTL time.Duration
+
+	ctx      context.Context
+	run      RunFunc
+	mu       sync.Mutex
+	nextID   int
+	jobs     map[string]*Job
+	streams  map[string]*eventStream
+	queue    []*Job
+	running  int
+	finished []finishedJob
+}
+
+// finishedJob records when a job finished, for eviction.
+type finishedJob struct {
+	id string
+	at time.Time
+}
+
+// NewHandler creates a Handler that runs scans with run.
+//
+// Parameters:
+// - ctx: The context bounding every job's lifetime.
+// - run: The function performing a scan.
+//
+// Returns:
+// - A pointer to a newly created Handler with the default limits.
+func NewHandler(ctx context.Context, run RunFunc) *Handler {
+	return &Handler{
+		MaxRunning:  DefaultMaxRunning,
+		MaxQueued:   DefaultMaxQueued,
+		MaxFinished: DefaultMaxFinished,
+		FinishedTTL: DefaultFinishedTTL,
+		ctx:         ctx,
+		run:         run,
+		jobs:        make(map[string]*Job),
+		streams:     make(map[string]*eventStream),
+	}
+}
+
+// ServeHTTP routes POST /scan, GET /scan/{id} and GET /scan/{id}/stream.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/scan":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.submit(w, r)
+	case strings.HasPrefix(path, "/scan/"):
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := strings.TrimPrefix(path, "/scan/")
+		if strings.HasSuffix(id, "/stream") {
+			h.stream(w, r, strings.TrimSuffix(id, "/stream"))
+			return
+		}
+		h.get(w, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// submit queues a new job and responds with its ID, or with 503 if the
+// queue is full.
+func (h *Handler) submit(w http.ResponseWriter, r *http.Request) {
+	var req ScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Target == "" {
+		http.Error(w, "target is required", http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	h.evict(time.Now())
+	if h.MaxQueued > 0 && len(h.queue) >= h.MaxQueued {
+		h.mu.Unlock()
+		http.Error(w, "too many queued scans", http.StatusServiceUnavailable)
+		return
+	}
+	h.nextID++
+	job := &Job{ID: strconv.Itoa(h.nextID), Status: StatusQueued, Request: req}
+	h.jobs[job.ID] = job
+	h.streams[job.ID] = newEventStream()
+	h.queue = append(h.queue, job)
+	snapshot := *job
+	h.dispatch()
+	h.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// dispatch starts the queued jobs, oldest first, while fewer than
+// MaxRunning run. h.mu must be held.
+func (h *Handler) dispatch() {
+	for len(h.queue) > 0 && (h.MaxRunning <= 0 || h.running < h.MaxRunning) {
+		job := h.queue[0]
+		h.queue[0] = nil
+		h.queue = h.queue[1:]
+		h.running++
+		job.Status = StatusRunning
+		go h.execute(job, h.streams[job.ID])
+	}
+}
+
+// execute runs a job, publishing its events, records its outcome and
+// starts the next queued job.
+func (h *Handler) execute(job *Job, events *eventStream) {
+	snapshot := func(report func() interface{}) {
+		h.mu.Lock()
+		job.snapshot = report
+		h.mu.Unlock()
+	}
+	report, err := h.run(h.ctx, job.Request, events.publish, snapshot)
+
+	h.mu.Lock()
+	job.snapshot = nil
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusDone
+		job.Report = report
+	}
+	status := job.Status
+	h.running--
+	now := time.Now()
+	h.finished = append(h.finished, finishedJob{id: job.ID, at: now})
+	h.evict(now)
+	h.dispatch()
+	h.mu.Unlock()
+	events.close(status)
+}
+
+// evict forgets the finished jobs older than FinishedTTL and the oldest
+// ones beyond MaxFinished. Clients already streaming such a job keep its
+// events. h.mu must be held.
+func (h *Handler) evict(now time.Time) {
+	drop := 0
+	for ; drop < len(h.finished); drop++ {
+		oldest := h.finished[drop]
+		expired := h.FinishedTTL > 0 && now.Sub(oldest.at) >= h.FinishedTTL
+		over := h.MaxFinished > 0 && len(h.finished)-drop > h.MaxFinished
+		if !expired && !over {
+			break
+		}
+		delete(h.jobs, oldest.id)
+		delete(h.streams, oldest.id)
+	}
+	if drop > 0 {
+		h.finished = append([]finishedJob(nil), h.finished[drop:]...)
+	}
+}
+
+// get responds with the current state of a job, with the report so far
+// while it runs.
+func (h *Handler) get(w http.ResponseWriter, id string) {
+	h.mu.Lock()
+	h.evict(time.Now())
+	job, ok := h.jobs[id]
+	var snapshot Job
+	if ok {
+		snapshot = *job
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if snapshot.snapshot != nil {
+		snapshot.Report = snapshot.snapshot()
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// stream sends the events of a job as server-sent events, first those
+// published so far and then each new one as it arrives. Every event is a
+// "result" event carrying the JSON encoded value; once the job completes an
+// "end" event carrying the job's status closes the stream.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, id string) {
+	h.mu.Lock()
+	events, ok := h.streams[id]
+	h.mu.Unlock()
+	if !ok {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	next := 0
+	for {
+		pending, done, wait := events.since(next)
+		for _, data := range pending {
+			if _, err := fmt.Fprintf(w, "event: result\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		next += len(pending)
+		flusher.Flush()
+		if done {
+			break
+		}
+		select {
+		case <-wait:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	fmt.Fprintf(w, "event: end\ndata: {\"status\":%q}\n\n", events.finalStatus())
+	flusher.Flush()
+}
+
+// eventStream keeps the events published by a job so clients can follow it
+// from the start, whenever they connect. It is safe for concurrent use.
+type eventStream struct {
+	mu     sync.Mutex
+	events [][]byte
+	done   bool
+	status string
+	wait   chan struct{}
+}
+
+// newEventStream creates an empty eventStream.
+func newEventStream() *eventStream {
+	return &eventStream{wait: make(chan struct{})}
+}
+
+// publish encodes and keeps an event, waking the waiting clients. Events
+// that cannot be encoded are dropped.
+func (s *eventStream) publish(event interface{}) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, data)
+	close(s.wait)
+	s.wait = make(chan struct{})
+}
+
+// close marks the stream complete with the job's final status, waking the
+// waiting clients.
+func (s *eventStream) close(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+	s.status = status
+	close(s.wait)
+	s.wait = make(chan struct{})
+}
+
+// finalStatus returns the status the stream was closed with.
+func (s *eventStream) finalStatus() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// since returns the events from index next on, whether the stream is
+// complete, and a channel closed when more events arrive.
+func (s *eventStream) since(next int) ([][]byte, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events[next:], s.done, s.wait
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
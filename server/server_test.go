@@ -0,0 +1,319 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func postScan(t *testing.T, url, body string) Job {
+	t.Helper()
+	resp, err := http.Post(url+"/scan", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /scan failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+	var job Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("Decoding job failed: %s", err)
+	}
+	return job
+}
+
+func getJob(t *testing.T, url, id string) Job {
+	t.Helper()
+	resp, err := http.Get(url + "/scan/" + id)
+	if err != nil {
+		t.Fatalf("GET /scan/%s failed: %s", id, err)
+	}
+	defer resp.Body.Close()
+	var job Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatalf("Decoding job failed: %s", err)
+	}
+	return job
+}
+
+func waitForJob(t *testing.T, url, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job := getJob(t, url, id)
+		if job.Status == StatusDone || job.Status == StatusFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return Job{}
+}
+
+func TestHandler_ScanLifecycle(t *testing.T) {
+	release := make(chan struct{})
+	run := func(ctx context.Context, req ScanRequest, publish func(interface{}), snapshot func(func() interface{})) (interface{}, error) {
+		<-release
+		if req.Target == "bad.example" {
+			return nil, errors.New("no such host")
+		}
+		return map[string]interface{}{"target": req.Target, "ports": req.Ports}, nil
+	}
+	srv := httptest.NewServer(NewHandler(context.Background(), run))
+	defer srv.Close()
+
+	job := postScan(t, srv.URL, `{"target":"example.com","ports":"1-1024"}`)
+	if job.ID == "" || job.Status != StatusQueued {
+		t.Fatalf("Expected a queued job with an ID, got %+v", job)
+	}
+	failed := postScan(t, srv.URL, `{"target":"bad.example"}`)
+	close(release)
+
+	done := waitForJob(t, srv.URL, job.ID)
+	if done.Status != StatusDone {
+		t.Fatalf("Expected status done, got %+v", done)
+	}
+	report, ok := done.Report.(map[string]interface{})
+	if !ok || report["target"] != "example.com" || report["ports"] != "1-1024" {
+		t.Errorf("Unexpected report %#v", done.Report)
+	}
+
+	if done := waitForJob(t, srv.URL, failed.ID); done.Status != StatusFailed || done.Error != "no such host" {
+		t.Errorf("Expected a failed job with its error, got %+v", done)
+	}
+}
+
+func TestHandler_ReportWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	run := func(ctx context.Context, req ScanRequest, publish func(interface{}), snapshot func(func() interface{})) (interface{}, error) {
+		snapshot(func() interface{} { return map[string]interface{}{"open": 1} })
+		<-release
+		return map[string]interface{}{"open": 2}, nil
+	}
+	srv := httptest.NewServer(NewHandler(context.Background(), run))
+	defer srv.Close()
+
+	job := postScan(t, srv.URL, `{"target":"example.com"}`)
+	deadline := time.Now().Add(5 * time.Second)
+	var running Job
+	for time.Now().Before(deadline) {
+		running = getJob(t, srv.URL, job.ID)
+		if running.Report != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	report, ok := running.Report.(map[string]interface{})
+	if running.Status != StatusRunning || !ok || report["open"] != 1.0 {
+		t.Errorf("Expected the report so far of the running job, got %+v", running)
+	}
+
+	close(release)
+	done := waitForJob(t, srv.URL, job.ID)
+	if report, ok := done.Report.(map[string]interface{}); !ok || report["open"] != 2.0 {
+		t.Errorf("Expected the final report once done, got %+v", done)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	run := func(ctx context.Context, req ScanRequest, publish func(interface{}), snapshot func(func() interface{})) (interface{}, error) {
+		return nil, nil
+	}
+	srv := httptest.NewServer(NewHandler(context.Background(), run))
+	defer srv.Close()
+
+	tests := []struct {
+		method, path, body string
+		expected           int
+	}{
+		{http.MethodPost, "/scan", `not json`, http.StatusBadRequest},
+		{http.MethodPost, "/scan", `{"ports":"80"}`, http.StatusBadRequest},
+		{http.MethodGet, "/scan", ``, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/scan/42", ``, http.StatusNotFound},
+		{http.MethodGet, "/scan/42/stream", ``, http.StatusNotFound},
+		{http.MethodGet, "/other", ``, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, srv.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatalf("NewRequest failed: %s", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %s", test.method, test.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.expected {
+			t.Errorf("%s %s: Expected status %d, got %d", test.method, test.path, test.expected, resp.StatusCode)
+		}
+	}
+}
+
+// readEvents reads server-sent events until the stream ends, returning the
+// data of each "result" event and the data of the "end" event.
+func readEvents(t *testing.T, url string) ([]string, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %s", url, err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+	var results []string
+	var end, event string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "result":
+			results = append(results, strings.TrimPrefix(line, "data: "))
+		case strings.HasPrefix(line, "data: ") && event == "end":
+			end = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return results, end
+}
+
+func TestHandler_Stream(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	run := func(ctx context.Context, req ScanRequest, publish func(interface{}), snapshot func(func() interface{})) (interface{}, error) {
+		publish(map[string]int{"port": 1})
+		close(started)
+		<-release
+		publish(map[string]int{"port": 2})
+		publish(map[string]int{"port": 3})
+		return "report", nil
+	}
+	srv := httptest.NewServer(NewHandler(context.Background(), run))
+	defer srv.Close()
+
+	job := postScan(t, srv.URL, `{"target":"example.com"}`)
+	<-started
+	go func() {
+		// Publish the rest while the client is connected
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	results, end := readEvents(t, srv.URL+"/scan/"+job.ID+"/stream")
+
+	expected := []string{`{"port":1}`, `{"port":2}`, `{"port":3}`}
+	if strings.Join(results, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected events %v, got %v", expected, results)
+	}
+	if end != `{"status":"done"}` {
+		t.Errorf("Expected the stream to end with the job status, got %q", end)
+	}
+
+	// A client connecting after the job completed gets every event too
+	if results, _ := readEvents(t, srv.URL+"/scan/"+job.ID+"/stream"); len(results) != 3 {
+		t.Errorf("Expected 3 replayed events, got %v", results)
+	}
+}
+
+// getStatus returns the HTTP status of GET /scan/{id}.
+func getStatus(t *testing.T, url, id string) int {
+	t.Helper()
+	resp, err := http.Get(url + "/scan/" + id)
+	if err != nil {
+		t.Fatalf("GET /scan/%s failed: %s", id, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestHandler_Queue(t *testing.T) {
+	release := make(chan struct{})
+	var running, maxRunning int64
+	run := func(ctx context.Context, req ScanRequest, publish func(interface{}), snapshot func(func() interface{})) (interface{}, error) {
+		now := atomic.AddInt64(&running, 1)
+		for {
+			seen := atomic.LoadInt64(&maxRunning)
+			if now <= seen || atomic.CompareAndSwapInt64(&maxRunning, seen, now) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt64(&running, -1)
+		return req.Target, nil
+	}
+	handler := NewHandler(context.Background(), run)
+	handler.MaxRunning = 1
+	handler.MaxQueued = 1
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	first := postScan(t, srv.URL, `{"target":"one.example"}`)
+	second := postScan(t, srv.URL, `{"target":"two.example"}`)
+
+	resp, err := http.Post(srv.URL+"/scan", "application/json", strings.NewReader(`{"target":"three.example"}`))
+	if err != nil {
+		t.Fatalf("POST /scan failed: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d with a full queue, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	var job Job
+	resp, err = http.Get(srv.URL + "/scan/" + second.ID)
+	if err != nil {
+		t.Fatalf("GET /scan/%s failed: %s", second.ID, err)
+	}
+	json.NewDecoder(resp.Body).Decode(&job)
+	resp.Body.Close()
+	if job.Status != StatusQueued {
+		t.Errorf("Expected the second job to wait while the first runs, got %s", job.Status)
+	}
+
+	close(release)
+	for _, id := range []string{first.ID, second.ID} {
+		if done := waitForJob(t, srv.URL, id); done.Status != StatusDone {
+			t.Errorf("Expected job %s to be done, got %+v", id, done)
+		}
+	}
+	if maxRunning != 1 {
+		t.Errorf("Expected at most 1 job running at once, got %d", maxRunning)
+	}
+}
+
+func TestHandler_EvictsFinishedJobs(t *testing.T) {
+	run := func(ctx context.Context, req ScanRequest, publish func(interface{}), snapshot func(func() interface{})) (interface{}, error) {
+		return req.Target, nil
+	}
+	handler := NewHandler(context.Background(), run)
+	handler.MaxFinished = 1
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	first := postScan(t, srv.URL, `{"target":"one.example"}`)
+	waitForJob(t, srv.URL, first.ID)
+	second := postScan(t, srv.URL, `{"target":"two.example"}`)
+	waitForJob(t, srv.URL, second.ID)
+	if status := getStatus(t, srv.URL, first.ID); status != http.StatusNotFound {
+		t.Errorf("Expected the oldest finished job to be forgotten beyond MaxFinished, got status %d", status)
+	}
+	if status := getStatus(t, srv.URL, second.ID); status != http.StatusOK {
+		t.Errorf("Expected the latest finished job to be kept, got status %d", status)
+	}
+
+	handler.mu.Lock()
+	handler.FinishedTTL = 10 * time.Millisecond
+	handler.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	if status := getStatus(t, srv.URL, second.ID); status != http.StatusNotFound {
+		t.Errorf("Expected the finished job to be forgotten after FinishedTTL, got status %d", status)
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+// ProtocolServices maps a protocol, "TCP" or "UDP", to the known services
+// by port for that protocol, for ports whose service depends on it: 53 is
+// DNS on both, but 500 is IKE on UDP only.
+//
+// Example:
+//
+//	services := ProtocolServices{
+//	    "TCP": {514: "shell"},
+//	    "UDP": {514: "syslog", 500: "isakmp"},
+//	}
+type ProtocolServices map[string]map[int]string
+
+// FromFlat migrates a flat service map, which names the same service for
+// every protocol, to a ProtocolServices with a copy of it for TCP and UDP.
+// The flat map is not modified.
+//
+// Parameters:
+// - services: The flat map, e.g. Services.
+//
+// Returns:
+// - The services keyed by protocol.
+//
+// Example:
+//
+//	services := FromFlat(map[int]string{9000: "internal-app"})
+func FromFlat(services map[int]string) ProtocolServices {
+	migrated := make(ProtocolServices, 2)
+	for _, protocol := range []string{"TCP", "UDP"} {
+		copied := make(map[int]string, len(services))
+		for port, name := range services {
+			copied[port] = name
+		}
+		migrated[protocol] = copied
+	}
+	return migrated
+}
+
+// udpOnlyServices are the ports of Services whose service only runs over
+// UDP, so the same port on TCP is something else.
+var udpOnlyServices = []int{
+	69,   // tftp
+	138,  // netbios-dgm
+	500,  // isakmp (IKE)
+	1900, // ssdp
+	4500, // ipsec-nat-t
+	5353, // mdns
+}
+
+// udpServices name the ports whose UDP service differs from the TCP one
+// in Services.
+var udpServices = map[int]string{
+	67:  "dhcps",
+	68:  "dhcpc",
+	514: "syslog",
+	520: "route",
+	623: "asf-rmcp",
+}
+
+// ServicesByProtocol are Services migrated with FromFlat, then corrected for
+// the ports whose service differs by protocol.
+var ServicesByProtocol = servicesByProtocol()
+
+// servicesByProtocol builds ServicesByProtocol.
+func servicesByProtocol() ProtocolServices {
+	services := FromFlat(Services)
+	for _, port := range udpOnlyServices {
+		delete(services["TCP"], port)
+	}
+	for port, name := range udpServices {
+		services["UDP"][port] = name
+	}
+	return services
+}
+
+// DetectServiceProtocol identifies the service running on a port from the
+// services of the protocol it was scanned over.
+//
+// Parameters:
+// - port: The port number to check for a running service.
+// - protocol: The protocol the port was scanned over, "TCP" or "UDP".
+// - services: The known services by protocol, e.g. ServicesByProtocol. A nil
+// map, or one without the protocol, is allowed and detects no services.
+//
+// Returns:
+// - A ServiceVersion struct with the protocol and the detected service, with
+// Service "Unknown" and Response "Service Not Detected" when the port is not
+// known for the protocol.
+//
+// Example:
+//
+//	svc := DetectServiceProtocol(500, "UDP", ServicesByProtocol) // isakmp
+func DetectServiceProtocol(port int, protocol string, services ProtocolServices) ServiceVersion {
+	svc := DetectService(port, services[protocol])
+	svc.Protocol = protocol
+	return svc
+}
@@ -0,0 +1,47 @@
+package service
+
+import "testing"
+
+func TestDetectServiceProtocol(t *testing.T) {
+	tests := []struct {
+		port     int
+		protocol string
+		expected string
+	}{
+		{53, "TCP", "domain"},
+		{53, "UDP", "domain"},
+		{500, "UDP", "isakmp"},
+		{500, "TCP", "Unknown"},
+		{514, "TCP", "shell"},
+		{514, "UDP", "syslog"},
+		{5353, "TCP", "Unknown"},
+		{5353, "UDP", "mdns"},
+		{80, "SCTP", "Unknown"},
+	}
+	for _, test := range tests {
+		svc := DetectServiceProtocol(test.port, test.protocol, ServicesByProtocol)
+		if svc.Service != test.expected {
+			t.Errorf("Port %d/%s: Expected service %s, got %s", test.port, test.protocol, test.expected, svc.Service)
+		}
+		if svc.Protocol != test.protocol {
+			t.Errorf("Port %d/%s: Expected protocol %s, got %s", test.port, test.protocol, test.protocol, svc.Protocol)
+		}
+	}
+	if Services[500] != "isakmp" || Services[514] != "shell" {
+		t.Errorf("Expected the flat Services to be left unchanged")
+	}
+}
+
+func TestFromFlat(t *testing.T) {
+	flat := map[int]string{22: "ssh", 9000: "internal-app"}
+	migrated := FromFlat(flat)
+	for _, protocol := range []string{"TCP", "UDP"} {
+		if svc := DetectServiceProtocol(9000, protocol, migrated); svc.Service != "internal-app" {
+			t.Errorf("%s: Expected the flat entry for both protocols, got %s", protocol, svc.Service)
+		}
+	}
+	migrated["UDP"][22] = "changed"
+	if flat[22] != "ssh" || migrated["TCP"][22] != "ssh" {
+		t.Errorf("Expected each protocol to get its own copy of the flat map")
+	}
+}
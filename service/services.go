@@ -1,12 +1,24 @@
 package service
 
+import "time"
+
 // ServiceVersion holds information about a detected service.
 //
 // Fields:
+// - IP: The IP address the port was scanned on (empty when not scanned).
 // - Port: The port number where the service is detected.
 // - Protocol: The protocol used by the service (default is "Unknown").
 // - Service: The name of the detected service.
 // - Response: The response message indicating whether a service was detected.
+// - State: The scanned state of the port, e.g. "Open" or "Filtered" (empty until scanned).
+// - Reason: The signal the state was derived from, e.g. "timeout" or "connection refused".
+// - Banner: What the service said about itself while being probed, e.g. an SNMP sysDescr.
+// - Backends: The distinct banners or TLS certificate fingerprints seen over repeated connections, when fingerprinted.
+// - MultipleBackends: Whether the repeated connections reached differing backends, e.g. behind a load balancer.
+// - Severity: How dangerous the exposure of an open port is, e.g. "High" for Telnet (empty unless open).
+// - Vuln: A note on a known vulnerability of the version in the banner, when flagged.
+// - Timestamp: When the probe of the port completed (zero until scanned).
+// - HTTP: Details of the web server on the port, if it was probed over HTTP.
 //
 // Example:
 //
@@ -17,10 +29,63 @@ package service
 //	    Response: "Service Detected",
 //	}
 type ServiceVersion struct {
-	Port     int    // The port number where the service is detected.
-	Protocol string // The protocol used by the service (default is "Unknown").
-	Service  string // The name of the detected service.
-	Response string // The response message indicating whether a service was detected.
+	IP               string    `json:"ip,omitempty"`                // The IP address the port was scanned on.
+	Port             int       `json:"port"`                        // The port number where the service is detected.
+	Protocol         string    `json:"protocol"`                    // The protocol used by the service (default is "Unknown").
+	Service          string    `json:"service"`                     // The name of the detected service.
+	Response         string    `json:"response"`                    // The response message indicating whether a service was detected.
+	State            string    `json:"state"`                       // The scanned state of the port, e.g. "Open" or "Filtered".
+	Reason           string    `json:"reason,omitempty"`            // The signal the state was derived from.
+	Banner           string    `json:"banner,omitempty"`            // What the service said about itself while being probed.
+	Backends         []string  `json:"backends,omitempty"`          // The distinct backend fingerprints seen.
+	MultipleBackends bool      `json:"multiple_backends,omitempty"` // Whether differing backends answered.
+	Severity         Severity  `json:"severity,omitempty"`          // How dangerous the exposure of an open port is.
+	Vuln             string    `json:"vuln,omitempty"`              // A known vulnerability of the version in the banner.
+	Timestamp        time.Time `json:"timestamp"`                   // When the probe of the port completed.
+	HTTP             *HTTPInfo `json:"http,omitempty"`              // Details of the web server on the port, if probed.
+}
+
+// HTTPInfo holds what an HTTP probe learned about a web server.
+//
+// Fields:
+// - TLS: Whether the server was reached over HTTPS.
+// - StatusCode: The HTTP status code of the response.
+// - Server: The Server response header.
+// - Title: The contents of the page's <title> element.
+// - Headers: All response headers.
+// - VHosts: The Host header values that got a different response than the default one, mapped to that response.
+//
+// Example:
+//
+//	info := HTTPInfo{StatusCode: 200, Server: "nginx", Title: "Welcome"}
+type HTTPInfo struct {
+	TLS        bool                 `json:"tls"`
+	StatusCode int                  `json:"status_code"`
+	Server     string               `json:"server,omitempty"`
+	Title      string               `json:"title,omitempty"`
+	Headers    map[string][]string  `json:"headers,omitempty"`
+	VHosts     map[string]*HTTPInfo `json:"vhosts,omitempty"`
+}
+
+// Equal reports whether two ServiceVersion values describe the same service.
+//
+// Only Port, Protocol and Service participate in equality. Response, and any
+// volatile field added later such as a live banner, is ignored so that
+// comparisons stay stable as the struct grows.
+//
+// Parameters:
+// - other: The ServiceVersion to compare against.
+//
+// Returns:
+// - true if both values have the same Port, Protocol and Service.
+//
+// Example:
+//
+//	if !got.Equal(expected) {
+//	    t.Errorf("Expected %v, got %v", expected, got)
+//	}
+func (s ServiceVersion) Equal(other ServiceVersion) bool {
+	return s.Port == other.Port && s.Protocol == other.Protocol && s.Service == other.Service
 }
 
 // DetectService identifies the service running on a given port.
@@ -28,20 +93,50 @@ type ServiceVersion struct {
 // Parameters:
 // - port: The port number to check for a running service.
 // - services: A map of known services where the key is the port number and the value is the service name.
+// A nil or empty map is allowed and detects no services.
 //
 // Returns:
-// - A ServiceVersion struct containing information about the detected service.
+// - A ServiceVersion struct containing information about the detected service,
+// with Service "Unknown" and Response "Service Not Detected" when the port is
+// not in the map.
 //
 // Example:
 //
 //	svc := DetectService(80, knownServices)
 func DetectService(port int, Services map[int]string) ServiceVersion {
+	if len(Services) == 0 {
+		return ServiceVersion{Port: port, Protocol: "Unknown", Service: "Unknown", Response: "Service Not Detected"}
+	}
 	if svc, ok := Services[port]; ok {
 		return ServiceVersion{Port: port, Protocol: "Unknown", Service: svc, Response: "Service Detected"}
 	}
 	return ServiceVersion{Port: port, Protocol: "Unknown", Service: "Unknown", Response: "Service Not Detected"}
 }
 
+// MergeServices combines a service map with user overrides. Neither map is
+// modified.
+//
+// Parameters:
+// - base: The map of known services, e.g. Services.
+// - overrides: Extra or replacement entries, which win over base.
+//
+// Returns:
+// - A new map holding every entry of base and overrides.
+//
+// Example:
+//
+//	services := MergeServices(Services, map[int]string{9000: "internal-app"})
+func MergeServices(base, overrides map[int]string) map[int]string {
+	merged := make(map[int]string, len(base)+len(overrides))
+	for port, name := range base {
+		merged[port] = name
+	}
+	for port, name := range overrides {
+		merged[port] = name
+	}
+	return merged
+}
+
 // Servicess is a map of well-known services where the key is the port number and the value is the service name.
 //
 // Example:
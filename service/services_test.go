@@ -32,3 +32,58 @@ func TestDetectService(t *testing.T) {
 		}
 	}
 }
+
+func TestServiceVersionEqual(t *testing.T) {
+	base := ServiceVersion{Port: 53, Protocol: "UDP", Service: "domain", Response: "Service Detected"}
+
+	tests := []struct {
+		other    ServiceVersion
+		expected bool
+	}{
+		{ServiceVersion{Port: 53, Protocol: "UDP", Service: "domain", Response: "Service Detected"}, true},
+		{ServiceVersion{Port: 53, Protocol: "UDP", Service: "domain", Response: "banner changed"}, true},
+		{ServiceVersion{Port: 54, Protocol: "UDP", Service: "domain"}, false},
+		{ServiceVersion{Port: 53, Protocol: "TCP", Service: "domain"}, false},
+		{ServiceVersion{Port: 53, Protocol: "UDP", Service: "Unknown"}, false},
+	}
+
+	for _, test := range tests {
+		if got := base.Equal(test.other); got != test.expected {
+			t.Errorf("Equal(%+v): Expected %v, got %v", test.other, test.expected, got)
+		}
+	}
+}
+
+func TestDetectServiceNilMap(t *testing.T) {
+	for _, services := range []map[int]string{nil, {}} {
+		svcVersion := DetectService(80, services)
+		if svcVersion.Service != "Unknown" {
+			t.Errorf("Expected service Unknown, got %s", svcVersion.Service)
+		}
+		if svcVersion.Response != "Service Not Detected" {
+			t.Errorf("Expected response Service Not Detected, got %s", svcVersion.Response)
+		}
+		if svcVersion.Port != 80 {
+			t.Errorf("Expected port 80, got %d", svcVersion.Port)
+		}
+	}
+}
+
+func TestMergeServices(t *testing.T) {
+	base := map[int]string{22: "ssh", 80: "http"}
+	overrides := map[int]string{80: "intranet", 9000: "internal-app"}
+
+	merged := MergeServices(base, overrides)
+	expected := map[int]string{22: "ssh", 80: "intranet", 9000: "internal-app"}
+	if len(merged) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, merged)
+	}
+	for port, name := range expected {
+		if merged[port] != name {
+			t.Errorf("Port %d: Expected service %s, got %s", port, name, merged[port])
+		}
+	}
+	if base[80] != "http" || len(base) != 2 {
+		t.Errorf("Expected base to be left unchanged, got %v", base)
+	}
+}
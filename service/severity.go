@@ -0,0 +1,87 @@
+package service
+
+// Severity rates how dangerous an exposed service is, for triage.
+type Severity string
+
+// Severities, from least to most dangerous.
+const (
+	SeverityInfo   Severity = "Info"
+	SeverityLow    Severity = "Low"
+	SeverityMedium Severity = "Medium"
+	SeverityHigh   Severity = "High"
+)
+
+// severityRanks orders the severities for sorting.
+var severityRanks = map[Severity]int{SeverityInfo: 1, SeverityLow: 2, SeverityMedium: 3, SeverityHigh: 4}
+
+// Rank orders severities from least to most dangerous.
+//
+// Returns:
+// - 1 for SeverityInfo up to 4 for SeverityHigh, 0 for an unknown severity.
+//
+// Example:
+//
+//	if a.Rank() > b.Rank() {
+//	    fmt.Println(a, "is worse")
+//	}
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// RiskyPorts is the built-in table of ports whose exposure is dangerous,
+// mostly remote access without encryption, file sharing and data stores
+// that often run without authentication. Ports not listed are SeverityInfo.
+//
+// Example:
+//
+//	severity := SeverityFor(3389, RiskyPorts) // SeverityHigh
+var RiskyPorts = map[int]Severity{
+	23:    SeverityHigh, // Telnet
+	139:   SeverityHigh, // NetBIOS session
+	445:   SeverityHigh, // SMB
+	512:   SeverityHigh, // rexec
+	513:   SeverityHigh, // rlogin
+	514:   SeverityHigh, // rsh
+	2375:  SeverityHigh, // Docker API without TLS
+	3389:  SeverityHigh, // RDP
+	5900:  SeverityHigh, // VNC
+	6379:  SeverityHigh, // Redis, often without auth
+	9200:  SeverityHigh, // Elasticsearch
+	11211: SeverityHigh, // Memcached
+	27017: SeverityHigh, // MongoDB
+
+	21:   SeverityMedium, // FTP
+	69:   SeverityMedium, // TFTP
+	111:  SeverityMedium, // rpcbind
+	135:  SeverityMedium, // MS RPC
+	161:  SeverityMedium, // SNMP
+	1433: SeverityMedium, // MS SQL
+	1521: SeverityMedium, // Oracle
+	3306: SeverityMedium, // MySQL
+	5432: SeverityMedium, // PostgreSQL
+
+	22:  SeverityLow, // SSH
+	25:  SeverityLow, // SMTP
+	53:  SeverityLow, // DNS
+	110: SeverityLow, // POP3
+	143: SeverityLow, // IMAP
+}
+
+// SeverityFor rates an exposed port.
+//
+// Parameters:
+// - port: The open port.
+// - table: The severity of risky ports, e.g. RiskyPorts.
+//
+// Returns:
+// - The port's severity from table, or SeverityInfo if it is not listed.
+//
+// Example:
+//
+//	severity := SeverityFor(80, RiskyPorts) // SeverityInfo
+func SeverityFor(port int, table map[int]Severity) Severity {
+	if severity, ok := table[port]; ok {
+		return severity
+	}
+	return SeverityInfo
+}
@@ -0,0 +1,26 @@
+package service
+
+import "testing"
+
+func TestSeverityFor(t *testing.T) {
+	tests := []struct {
+		port     int
+		expected Severity
+	}{
+		{23, SeverityHigh},
+		{3389, SeverityHigh},
+		{445, SeverityHigh},
+		{3306, SeverityMedium},
+		{22, SeverityLow},
+		{80, SeverityInfo},
+		{443, SeverityInfo},
+	}
+	for _, test := range tests {
+		if got := SeverityFor(test.port, RiskyPorts); got != test.expected {
+			t.Errorf("Port %d: Expected %s, got %s", test.port, test.expected, got)
+		}
+	}
+	if got := SeverityFor(23, map[int]Severity{}); got != SeverityInfo {
+		t.Errorf("Expected %s for a port missing from the table, got %s", SeverityInfo, got)
+	}
+}
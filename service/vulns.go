@@ -0,0 +1,70 @@
+package service
+
+import "strings"
+
+// KnownVulns maps normalized banner fragments of versions with well-known
+// vulnerabilities to a note about them. It is a short list of notorious
+// versions for triage, not a CVE database; a banner matches when it
+// contains a fragment after NormalizeBanner.
+//
+// Example:
+//
+//	note := VulnFor("SSH-2.0-OpenSSH_7.2p2 Ubuntu-4ubuntu2.8", KnownVulns)
+var KnownVulns = map[string]string{
+	"openssh_7.2p2":     "CVE-2016-6210: username enumeration",
+	"openssh_7.7":       "CVE-2018-15473: username enumeration",
+	"vsftpd 2.3.4":      "CVE-2011-2523: backdoored release",
+	"proftpd 1.3.5":     "CVE-2015-3306: unauthenticated file copy with mod_copy",
+	"apache/2.4.49":     "CVE-2021-41773: path traversal and remote code execution",
+	"apache/2.4.50":     "CVE-2021-42013: path traversal and remote code execution",
+	"microsoft-iis/6.0": "CVE-2017-7269: WebDAV buffer overflow",
+	"openssl/1.0.1f":    "CVE-2014-0160: Heartbleed",
+}
+
+// NormalizeBanner lowercases a banner and collapses its whitespace, so
+// table fragments match regardless of case, line endings or padding.
+//
+// Parameters:
+// - banner: The banner as captured.
+//
+// Returns:
+// - The normalized banner.
+//
+// Example:
+//
+//	NormalizeBanner("SSH-2.0-OpenSSH_7.7\r\n") // "ssh-2.0-openssh_7.7"
+func NormalizeBanner(banner string) string {
+	return strings.Join(strings.Fields(strings.ToLower(banner)), " ")
+}
+
+// VulnFor looks a banner up in a table of known vulnerable versions.
+//
+// Parameters:
+// - banner: The banner the service sent.
+// - table: Normalized banner fragments and their notes, e.g. KnownVulns.
+//
+// Returns:
+// - The note of the longest fragment the banner contains, or "" if none.
+//
+// Example:
+//
+//	note := VulnFor("220 (vsFTPd 2.3.4)", KnownVulns)
+func VulnFor(banner string, table map[string]string) string {
+	normalized := NormalizeBanner(banner)
+	if normalized == "" {
+		return ""
+	}
+	var match, note string
+	for fragment, fragmentNote := range table {
+		fragment = NormalizeBanner(fragment)
+		if fragment == "" || !strings.Contains(normalized, fragment) {
+			continue
+		}
+		// The longest fragment is the most specific; break ties by name so
+		// the result does not depend on map order
+		if len(fragment) > len(match) || (len(fragment) == len(match) && fragment < match) {
+			match, note = fragment, fragmentNote
+		}
+	}
+	return note
+}
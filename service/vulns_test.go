@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestVulnFor(t *testing.T) {
+	tests := []struct {
+		banner   string
+		expected string
+	}{
+		{"SSH-2.0-OpenSSH_7.2p2 Ubuntu-4ubuntu2.8\r\n", KnownVulns["openssh_7.2p2"]},
+		{"220 (vsFTPd 2.3.4)\r\n", KnownVulns["vsftpd 2.3.4"]},
+		{"Apache/2.4.49  (Unix)", KnownVulns["apache/2.4.49"]},
+		{"SSH-2.0-OpenSSH_9.6\r\n", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := VulnFor(tt.banner, KnownVulns); got != tt.expected {
+			t.Errorf("%q: Expected %q, got %q", tt.banner, tt.expected, got)
+		}
+	}
+
+	table := map[string]string{"OpenSSH": "any OpenSSH", "OpenSSH_7.7": "OpenSSH 7.7"}
+	if got := VulnFor("SSH-2.0-OpenSSH_7.7", table); got != "OpenSSH 7.7" {
+		t.Errorf("Expected the most specific fragment to match, got %q", got)
+	}
+}
+
+func TestNormalizeBanner(t *testing.T) {
+	if got := NormalizeBanner("  220 ProFTPD   1.3.5\r\nServer\t"); got != "220 proftpd 1.3.5 server" {
+		t.Errorf("Expected the banner lowercased with its whitespace collapsed, got %q", got)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"sort"
+
+	"det/service"
+)
+
+// severity rates an open port with RiskyPorts, or service.RiskyPorts when
+// none is configured.
+func (t *PortScanner) severity(port int) service.Severity {
+	table := t.RiskyPorts
+	if table == nil {
+		table = service.RiskyPorts
+	}
+	return service.SeverityFor(port, table)
+}
+
+// SortBySeverity orders the TCP and UDP ports of the report from the most to
+// the least dangerous, then by IP and port.
+//
+// Example:
+//
+//	report := scanner.Report()
+//	report.SortBySeverity()
+//	fmt.Println(report.TCP[0].Severity)
+func (r *ScanReport) SortBySeverity() {
+	bySeverity := func(services []service.ServiceVersion) {
+		sort.SliceStable(services, func(i, j int) bool {
+			a, b := services[i], services[j]
+			if a.Severity.Rank() != b.Severity.Rank() {
+				return a.Severity.Rank() > b.Severity.Rank()
+			}
+			return serviceLess(a, b)
+		})
+	}
+	bySeverity(r.TCP)
+	bySeverity(r.UDP)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"det/service"
+)
+
+func TestScan_TagsSeverity(t *testing.T) {
+	open := ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateOpen, nil })
+	scanner := &PortScanner{Quiet: true, TCPProber: open}
+	runTCPWorker(scanner, "192.0.2.1", []int{80, 3389, 22, 23})
+
+	report := ScanReport{}
+	for svc := range scanner.OpenPorts {
+		report.TCP = append(report.TCP, svc)
+	}
+	report.SortBySeverity()
+
+	expected := []struct {
+		port     int
+		severity service.Severity
+	}{
+		{23, service.SeverityHigh},
+		{3389, service.SeverityHigh},
+		{22, service.SeverityLow},
+		{80, service.SeverityInfo},
+	}
+	if len(report.TCP) != len(expected) {
+		t.Fatalf("Expected %d open ports, got %d", len(expected), len(report.TCP))
+	}
+	for i, e := range expected {
+		if got := report.TCP[i]; got.Port != e.port || got.Severity != e.severity {
+			t.Errorf("Position %d: Expected port %d (%s), got port %d (%s)", i, e.port, e.severity, got.Port, got.Severity)
+		}
+	}
+}
+
+func TestScan_RiskyPortsOverride(t *testing.T) {
+	open := ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateOpen, nil })
+	scanner := &PortScanner{Quiet: true, TCPProber: open, RiskyPorts: map[int]service.Severity{80: service.SeverityMedium}}
+	runTCPWorker(scanner, "192.0.2.1", []int{80, 23})
+
+	for svc := range scanner.OpenPorts {
+		expected := map[int]service.Severity{80: service.SeverityMedium, 23: service.SeverityInfo}[svc.Port]
+		if svc.Severity != expected {
+			t.Errorf("Port %d: Expected %s from the override table, got %s", svc.Port, expected, svc.Severity)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// snmpPort is the UDP port probed with SNMP GET requests.
+const snmpPort = 161
+
+// DefaultSNMPCommunities are the community strings tried by the SNMP probe.
+var DefaultSNMPCommunities = []string{"public", "private"}
+
+// ASN.1 BER tags used by SNMPv1 messages.
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berNull        = 0x05
+	berOID         = 0x06
+	berSequence    = 0x30
+	snmpGetRequest = 0xa0
+	snmpGetReply   = 0xa2
+)
+
+// oidSysDescr is the encoded OID 1.3.6.1.2.1.1.1.0 (sysDescr.0).
+var oidSysDescr = []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00}
+
+// errSNMPMalformed is returned for a message that is not valid SNMPv1.
+var errSNMPMalformed = errors.New("snmp: malformed message")
+
+// SNMPResponse is a decoded SNMPv1 GetResponse for sysDescr.0.
+//
+// Fields:
+// - Community: The community string of the response.
+// - RequestID: The request ID being answered.
+// - SysDescr: The system description.
+type SNMPResponse struct {
+	Community string
+	RequestID uint32
+	SysDescr  string
+}
+
+// berTLV encodes a BER tag, length and value.
+func berTLV(tag byte, value []byte) []byte {
+	b := []byte{tag}
+	switch n := len(value); {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	default:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	}
+	return append(b, value...)
+}
+
+// berRead decodes the BER element at the start of b.
+//
+// Returns:
+// - The element's tag and value, and the bytes following it.
+// - errSNMPMalformed if the element is truncated.
+func berRead(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errSNMPMalformed
+	}
+	tag, length, b := b[0], int(b[1]), b[2:]
+	if length&0x80 != 0 {
+		size := length & 0x7f
+		if size == 0 || size > 2 || len(b) < size {
+			return 0, nil, nil, errSNMPMalformed
+		}
+		length = 0
+		for _, c := range b[:size] {
+			length = length<<8 | int(c)
+		}
+		b = b[size:]
+	}
+	if len(b) < length {
+		return 0, nil, nil, errSNMPMalformed
+	}
+	return tag, b[:length], b[length:], nil
+}
+
+// berExpect decodes the BER element at the start of b and checks its tag.
+func berExpect(b []byte, tag byte) ([]byte, []byte, error) {
+	got, value, rest, err := berRead(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if got != tag {
+		return nil, nil, errSNMPMalformed
+	}
+	return value, rest, nil
+}
+
+// berUint decodes a non-negative BER integer of up to 4 bytes.
+func berUint(value []byte) (uint32, error) {
+	if len(value) == 0 || len(value) > 5 || (len(value) == 5 && value[0] != 0) {
+		return 0, errSNMPMalformed
+	}
+	var n uint32
+	for _, c := range value {
+		n = n<<8 | uint32(c)
+	}
+	return n, nil
+}
+
+// MarshalSNMPGet builds an SNMPv1 GetRequest for sysDescr.0.
+//
+// Parameters:
+// - community: The community string.
+// - requestID: The request ID echoed by the agent.
+//
+// Returns:
+// - The encoded message.
+//
+// Example:
+//
+//	packet := MarshalSNMPGet("public", 1)
+func MarshalSNMPGet(community string, requestID uint32) []byte {
+	id := make([]byte, 4)
+	binary.BigEndian.PutUint32(id, requestID)
+	varbind := berTLV(berSequence, append(berTLV(berOID, oidSysDescr), berTLV(berNull, nil)...))
+	var pdu []byte
+	pdu = append(pdu, berTLV(berInteger, id)...)
+	pdu = append(pdu, berTLV(berInteger, []byte{0})...) // error-status
+	pdu = append(pdu, berTLV(berInteger, []byte{0})...) // error-index
+	pdu = append(pdu, berTLV(berSequence, varbind)...)
+
+	var msg []byte
+	msg = append(msg, berTLV(berInteger, []byte{0})...) // version-1
+	msg = append(msg, berTLV(berOctetString, []byte(community))...)
+	msg = append(msg, berTLV(snmpGetRequest, pdu)...)
+	return berTLV(berSequence, msg)
+}
+
+// ParseSNMPResponse decodes an SNMPv1 GetResponse for sysDescr.0.
+//
+// Parameters:
+// - b: The received message.
+//
+// Returns:
+// - The decoded SNMPResponse.
+// - An error if the message is malformed, reports an error status or does
+// not carry sysDescr.0.
+func ParseSNMPResponse(b []byte) (SNMPResponse, error) {
+	var resp SNMPResponse
+	msg, _, err := berExpect(b, berSequence)
+	if err != nil {
+		return resp, err
+	}
+	if _, msg, err = berExpect(msg, berInteger); err != nil {
+		return resp, err
+	}
+	community, msg, err := berExpect(msg, berOctetString)
+	if err != nil {
+		return resp, err
+	}
+	resp.Community = string(community)
+	pdu, _, err := berExpect(msg, snmpGetReply)
+	if err != nil {
+		return resp, err
+	}
+
+	id, pdu, err := berExpect(pdu, berInteger)
+	if err != nil {
+		return resp, err
+	}
+	if resp.RequestID, err = berUint(id); err != nil {
+		return resp, err
+	}
+	status, pdu, err := berExpect(pdu, berInteger)
+	if err != nil {
+		return resp, err
+	}
+	if code, err := berUint(status); err != nil || code != 0 {
+		return resp, fmt.Errorf("snmp: error status %x", status)
+	}
+	if _, pdu, err = berExpect(pdu, berInteger); err != nil {
+		return resp, err
+	}
+
+	varbinds, _, err := berExpect(pdu, berSequence)
+	if err != nil {
+		return resp, err
+	}
+	varbind, _, err := berExpect(varbinds, berSequence)
+	if err != nil {
+		return resp, err
+	}
+	oid, varbind, err := berExpect(varbind, berOID)
+	if err != nil {
+		return resp, err
+	}
+	if !bytes.Equal(oid, oidSysDescr) {
+		return resp, errors.New("snmp: response is not for sysDescr.0")
+	}
+	descr, _, err := berExpect(varbind, berOctetString)
+	if err != nil {
+		return resp, err
+	}
+	resp.SysDescr = string(descr)
+	return resp, nil
+}
+
+// probeSNMP sends an SNMP GET for sysDescr.0 with each community string in
+// turn until an agent answers. Agents ignore wrong communities, so each
+// unanswered community costs a full UDP timeout.
+//
+// Parameters:
+// - dial: The dial function.
+// - ip: The IP address to probe.
+// - port: The UDP port to probe.
+// - communities: The community strings to try.
+// - writeTimeout: The timeout for dialing and sending each request.
+// - readTimeout: How long to wait for each response.
+//
+// Returns:
+// - The first valid response.
+// - The last error if no community was answered.
+func probeSNMP(dial DialFunc, ip string, port int, communities []string, writeTimeout, readTimeout time.Duration) (SNMPResponse, error) {
+	err := errors.New("snmp: no community strings")
+	for i, community := range communities {
+		var resp SNMPResponse
+		resp, err = snmpGet(dial, ip, port, community, uint32(i+1), writeTimeout, readTimeout)
+		if err == nil {
+			return resp, nil
+		}
+	}
+	return SNMPResponse{}, err
+}
+
+// snmpGet sends one SNMP GET for sysDescr.0 and waits for its response.
+func snmpGet(dial DialFunc, ip string, port int, community string, requestID uint32, writeTimeout, readTimeout time.Duration) (SNMPResponse, error) {
+	start := time.Now()
+	conn, err := dial("udp", net.JoinHostPort(ip, strconv.Itoa(port)), writeTimeout)
+	if err != nil {
+		return SNMPResponse{}, err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(start.Add(writeTimeout))
+	if _, err := conn.Write(MarshalSNMPGet(community, requestID)); err != nil {
+		return SNMPResponse{}, err
+	}
+	conn.SetReadDeadline(start.Add(writeTimeout + readTimeout))
+	buf := make([]byte, 65535)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return SNMPResponse{}, err
+		}
+		resp, err := ParseSNMPResponse(buf[:n])
+		if err == nil && resp.RequestID == requestID {
+			return resp, nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+// snmpResponder is a local SNMP agent stub answering GET requests for
+// sysDescr.0 made with its community string and ignoring all others.
+type snmpResponder struct {
+	conn      net.PacketConn
+	community string
+	sysDescr  string
+}
+
+func newSNMPResponder(t *testing.T, community, sysDescr string) *snmpResponder {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %s", err)
+	}
+	r := &snmpResponder{conn: conn, community: community, sysDescr: sysDescr}
+	go r.serve()
+	t.Cleanup(func() { conn.Close() })
+	return r
+}
+
+func (r *snmpResponder) serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		community, id, ok := parseSNMPGet(buf[:n])
+		if !ok || community != r.community {
+			continue
+		}
+		r.conn.WriteTo(marshalSNMPResponse(community, id, r.sysDescr), addr)
+	}
+}
+
+// parseSNMPGet extracts the community and request ID of a GetRequest.
+func parseSNMPGet(b []byte) (string, []byte, bool) {
+	msg, _, err := berExpect(b, berSequence)
+	if err != nil {
+		return "", nil, false
+	}
+	if _, msg, err = berExpect(msg, berInteger); err != nil {
+		return "", nil, false
+	}
+	community, msg, err := berExpect(msg, berOctetString)
+	if err != nil {
+		return "", nil, false
+	}
+	pdu, _, err := berExpect(msg, snmpGetRequest)
+	if err != nil {
+		return "", nil, false
+	}
+	id, _, err := berExpect(pdu, berInteger)
+	if err != nil {
+		return "", nil, false
+	}
+	return string(community), id, true
+}
+
+// marshalSNMPResponse builds a GetResponse carrying sysDescr.0.
+func marshalSNMPResponse(community string, id []byte, sysDescr string) []byte {
+	varbind := berTLV(berSequence, append(berTLV(berOID, oidSysDescr), berTLV(berOctetString, []byte(sysDescr))...))
+	var pdu []byte
+	pdu = append(pdu, berTLV(berInteger, id)...)
+	pdu = append(pdu, berTLV(berInteger, []byte{0})...)
+	pdu = append(pdu, berTLV(berInteger, []byte{0})...)
+	pdu = append(pdu, berTLV(berSequence, varbind)...)
+	var msg []byte
+	msg = append(msg, berTLV(berInteger, []byte{0})...)
+	msg = append(msg, berTLV(berOctetString, []byte(community))...)
+	msg = append(msg, berTLV(snmpGetReply, pdu)...)
+	return berTLV(berSequence, msg)
+}
+
+func TestMarshalSNMPGet_MatchesPayloadTable(t *testing.T) {
+	if got := MarshalSNMPGet("public", 1); string(got) != string(UDPPayload(snmpPort)) {
+		t.Errorf("Expected the GET for public to match the port 161 payload %x, got %x", UDPPayload(snmpPort), got)
+	}
+}
+
+func TestParseSNMPResponse(t *testing.T) {
+	resp, err := ParseSNMPResponse(marshalSNMPResponse("private", []byte{0, 0, 0, 2}, "Linux router 5.10"))
+	if err != nil {
+		t.Fatalf("ParseSNMPResponse failed: %s", err)
+	}
+	if resp.Community != "private" || resp.RequestID != 2 || resp.SysDescr != "Linux router 5.10" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if _, err := ParseSNMPResponse(MarshalSNMPGet("public", 1)); err == nil {
+		t.Errorf("Expected an error for a GetRequest")
+	}
+	if _, err := ParseSNMPResponse([]byte{0x30, 0x10, 0x02}); err == nil {
+		t.Errorf("Expected an error for a truncated message")
+	}
+}
+
+func TestDatagramProber_SNMP(t *testing.T) {
+	responder := newSNMPResponder(t, "private", "Linux router 5.10")
+	stub := responder.conn.LocalAddr().String()
+	scanner := &PortScanner{
+		SNMPCommunities: DefaultSNMPCommunities,
+		UDPWriteTimeout: 100 * time.Millisecond,
+		UDPReadTimeout:  200 * time.Millisecond,
+		// Send port 161 probes to the stub
+		Dial: func(network, address string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout(network, stub, timeout)
+		},
+		Quiet: true,
+	}
+
+	ports := make(chan Endpoint, 1)
+	ports <- Endpoint{IP: "127.0.0.1", Port: snmpPort}
+	close(ports)
+	scanner.OpenPortsUDP = make(chan service.ServiceVersion, 1)
+	scanner.WorkerUDP(context.Background(), ports, scanner.OpenPortsUDP, make(chan bool, 1))
+	close(scanner.OpenPortsUDP)
+
+	svc, ok := <-scanner.OpenPortsUDP
+	if !ok {
+		t.Fatalf("Expected port 161 to be reported open")
+	}
+	if svc.State != "Open" || svc.Banner != "Linux router 5.10" {
+		t.Errorf("Expected port 161 open with the sysDescr banner, got %s with banner %q", svc.State, svc.Banner)
+	}
+
+	expected := `Port 161 (UDP) is Open, Service: snmp, Banner: "Linux router 5.10", Severity: Medium`
+	svc.Service = "snmp"
+	if line := serviceLine("UDP", svc); line != expected {
+		t.Errorf("Expected report line %q, got %q", expected, line)
+	}
+}
+
+func TestDatagramProber_SNMPUnanswered(t *testing.T) {
+	responder := newSNMPResponder(t, "secret", "hidden")
+	stub := responder.conn.LocalAddr().String()
+	scanner := &PortScanner{
+		SNMPCommunities: DefaultSNMPCommunities,
+		UDPWriteTimeout: 50 * time.Millisecond,
+		UDPReadTimeout:  50 * time.Millisecond,
+		Dial: func(network, address string, timeout time.Duration) (net.Conn, error) {
+			return net.DialTimeout(network, stub, timeout)
+		},
+	}
+	state, banner, err := DatagramProber{Scanner: scanner}.ProbeBanner(context.Background(), "127.0.0.1", snmpPort)
+	if err != nil || state != StateClosed || banner != "" {
+		t.Errorf("Expected Closed without a banner for unknown communities, got %s %q (%v)", state, banner, err)
+	}
+}
+
+// FuzzParseSNMPResponse checks that no datagram makes the SNMP parser, which
+// extracts the sysDescr banner from untrusted responses, panic. Run it with:
+//
+//	go test -run '^$' -fuzz FuzzParseSNMPResponse -fuzztime 30s
+func FuzzParseSNMPResponse(f *testing.F) {
+	f.Add(marshalSNMPResponse("public", []byte{0, 0, 0, 1}, "Linux router 5.10"))
+	f.Add(marshalSNMPResponse("", []byte{1}, ""))
+	f.Add(MarshalSNMPGet("public", 1))
+	f.Add([]byte{0x30, 0x10, 0x02})
+	f.Add([]byte{0x30, 0x84, 0xff, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, b []byte) {
+		resp, err := ParseSNMPResponse(b)
+		if err == nil && len(resp.SysDescr) > len(b) {
+			t.Fatalf("sysDescr longer than the datagram: %q", resp.SysDescr)
+		}
+	})
+}
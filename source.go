@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+// BindSource validates SourceIP or Interface and binds the scanner's dialer
+// and ICMP socket to that local address, so scans leave through a specific
+// interface (e.g. a VPN tunnel) instead of the default route. It must be
+// called before Scan; it does nothing when neither field is set.
+//
+// Returns:
+// - An error if the source address is invalid or does not belong to a local interface.
+//
+// Example:
+//
+//	scanner.Interface = "tun0"
+//	if err := scanner.BindSource(); err != nil {
+//	    log.Fatal(err)
+//	}
+func (t *PortScanner) BindSource() error {
+	var src net.IP
+	switch {
+	case t.SourceIP != "":
+		src = net.ParseIP(t.SourceIP)
+		if src == nil {
+			return fmt.Errorf("invalid source IP %q", t.SourceIP)
+		}
+		addrs, err := net.InterfaceAddrs()
+		if err != nil {
+			return fmt.Errorf("error listing interface addresses: %s", err)
+		}
+		if !containsIP(addrs, src) {
+			return fmt.Errorf("source IP %s does not belong to a local interface", src)
+		}
+	case t.Interface != "":
+		iface, err := net.InterfaceByName(t.Interface)
+		if err != nil {
+			return fmt.Errorf("invalid interface %q: %s", t.Interface, err)
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return fmt.Errorf("error listing addresses of %s: %s", t.Interface, err)
+		}
+		src = firstIP(addrs)
+		if src == nil {
+			return fmt.Errorf("interface %s has no IP address", t.Interface)
+		}
+	default:
+		return nil
+	}
+
+	if t.Dialer == nil {
+		t.Dialer = &net.Dialer{}
+	}
+	t.Dialer.LocalAddr = &net.TCPAddr{IP: src}
+	t.source = src
+	return nil
+}
+
+// udpLocalDialer returns the dialer to use for UDP: a copy of dialer with a
+// TCP LocalAddr turned into the same UDP address, since net.Dialer rejects a
+// local address of another network. Other dialers are returned as is.
+//
+// Parameters:
+// - dialer: The scanner's dialer.
+//
+// Returns:
+// - The dialer for UDP.
+func udpLocalDialer(dialer *net.Dialer) *net.Dialer {
+	local, ok := dialer.LocalAddr.(*net.TCPAddr)
+	if !ok {
+		return dialer
+	}
+	udp := *dialer
+	udp.LocalAddr = &net.UDPAddr{IP: local.IP, Zone: local.Zone}
+	return &udp
+}
+
+// containsIP reports whether ip is one of the interface addresses.
+func containsIP(addrs []net.Addr, ip net.IP) bool {
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstIP returns the first IPv4 interface address, or the first address of
+// any family if the interface has no IPv4 address.
+func firstIP(addrs []net.Addr) net.IP {
+	var first net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP
+		}
+		if first == nil {
+			first = ipNet.IP
+		}
+	}
+	return first
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBindSource_SourceIP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Addr, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- conn.RemoteAddr()
+		conn.Close()
+	}()
+
+	scanner := &PortScanner{Timeout: time.Second, Dialer: &net.Dialer{KeepAlive: -1}, SourceIP: "127.0.0.1"}
+	if err := scanner.BindSource(); err != nil {
+		t.Fatalf("BindSource failed: %s", err)
+	}
+	local, ok := scanner.Dialer.LocalAddr.(*net.TCPAddr)
+	if !ok || !local.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Fatalf("Expected dialer LocalAddr 127.0.0.1, got %v", scanner.Dialer.LocalAddr)
+	}
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	if state := scanner.scanPortTCP("127.0.0.1", port); state != "Open" {
+		t.Fatalf("Expected Open, got %s", state)
+	}
+	if remote := <-accepted; !remote.(*net.TCPAddr).IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("Expected the connection to originate from 127.0.0.1, got %s", remote)
+	}
+}
+
+func TestBindSource_Invalid(t *testing.T) {
+	tests := []*PortScanner{
+		{SourceIP: "not-an-ip"},
+		{SourceIP: "203.0.113.250"},
+		{Interface: "no-such-interface0"},
+	}
+	for _, scanner := range tests {
+		if err := scanner.BindSource(); err == nil {
+			t.Errorf("Expected an error for source %q interface %q", scanner.SourceIP, scanner.Interface)
+		}
+	}
+}
+
+func TestBindSource_Unset(t *testing.T) {
+	scanner := &PortScanner{}
+	if err := scanner.BindSource(); err != nil {
+		t.Fatalf("BindSource failed: %s", err)
+	}
+	if scanner.Dialer != nil {
+		t.Errorf("Expected no dialer to be configured without a source")
+	}
+}
+
+func TestBindSource_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %s", err)
+	}
+	defer conn.Close()
+	received := make(chan net.Addr, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		_, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		received <- addr
+		conn.WriteTo([]byte("pong"), addr)
+	}()
+
+	scanner := &PortScanner{SourceIP: "127.0.0.1", UDPReadTimeout: time.Second}
+	if err := scanner.BindSource(); err != nil {
+		t.Fatalf("BindSource failed: %s", err)
+	}
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+	if err := scanner.scanUDP(context.Background(), port, "127.0.0.1"); err != nil {
+		t.Fatalf("Expected the bound UDP probe to get an answer, got %s", err)
+	}
+	if remote := <-received; !remote.(*net.UDPAddr).IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("Expected the probe to originate from 127.0.0.1, got %s", remote)
+	}
+}
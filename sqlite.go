@@ -0,0 +1,160 @@
+//go:build sqlite
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"det/service"
+
+	// Registers the pure-Go "sqlite" driver, so no cgo is needed
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the export tables. A run holds the ports scanned on
+// its hosts; hosts and services are shared by every run and updated as new
+// runs come in.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	target    TEXT NOT NULL,
+	ports     TEXT NOT NULL,
+	workers   INTEGER NOT NULL,
+	mode      TEXT NOT NULL,
+	started   TEXT,
+	finished  TEXT,
+	version   TEXT NOT NULL,
+	truncated INTEGER NOT NULL,
+	cancelled INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS hosts (
+	ip         TEXT PRIMARY KEY,
+	first_seen INTEGER NOT NULL REFERENCES runs(id),
+	last_seen  INTEGER NOT NULL REFERENCES runs(id),
+	reachable  INTEGER,
+	os_guess   TEXT
+);
+CREATE TABLE IF NOT EXISTS services (
+	port     INTEGER NOT NULL,
+	protocol TEXT NOT NULL,
+	name     TEXT NOT NULL,
+	PRIMARY KEY (port, protocol)
+);
+CREATE TABLE IF NOT EXISTS ports (
+	run_id     INTEGER NOT NULL REFERENCES runs(id),
+	ip         TEXT NOT NULL REFERENCES hosts(ip),
+	port       INTEGER NOT NULL,
+	protocol   TEXT NOT NULL,
+	state      TEXT NOT NULL,
+	reason     TEXT,
+	banner     TEXT,
+	severity   TEXT,
+	vuln       TEXT,
+	scanned_at TEXT,
+	PRIMARY KEY (run_id, ip, port, protocol)
+);
+`
+
+// ExportSQLite inserts a scan report as a new run into a SQLite database,
+// creating the tables on first use, so the runs of scheduled scans can be
+// queried with SQL. The ports of the run are in the ports table, the hosts
+// and services tables are updated with what the run found.
+//
+// Parameters:
+// - path: The path of the database, created if it does not exist.
+// - report: The report of the run.
+//
+// Returns:
+// - An error if the database cannot be opened or written; a failed run is not inserted at all.
+//
+// Example:
+//
+//	scanner.Scan()
+//	err := ExportSQLite("scans.db", scanner.Report())
+//	// sqlite3 scans.db "SELECT ip, port FROM ports WHERE state = 'Open'"
+func ExportSQLite(path string, report ScanReport) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("error opening database: %s", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("error creating tables: %s", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %s", err)
+	}
+	if err := insertRun(tx, report); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing run: %s", err)
+	}
+	return nil
+}
+
+// insertRun inserts the run, its hosts, services and ports within tx.
+func insertRun(tx *sql.Tx, report ScanReport) error {
+	meta := report.Meta
+	result, err := tx.Exec(`INSERT INTO runs (target, ports, workers, mode, started, finished, version, truncated, cancelled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		report.Target, meta.Ports, meta.Workers, meta.Mode, sqliteTime(meta.Start), sqliteTime(meta.End),
+		meta.Version, report.Truncated, report.Cancelled)
+	if err != nil {
+		return fmt.Errorf("error inserting run: %s", err)
+	}
+	runID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error inserting run: %s", err)
+	}
+
+	// Every scanned IP is a host, whether or not it answered
+	upsertHost := `INSERT INTO hosts (ip, first_seen, last_seen) VALUES (?, ?, ?)
+		ON CONFLICT (ip) DO UPDATE SET last_seen = excluded.last_seen`
+	for _, ip := range report.Resolution.IPs {
+		if _, err := tx.Exec(upsertHost, ip, runID, runID); err != nil {
+			return fmt.Errorf("error inserting host: %s", err)
+		}
+	}
+	for _, icmp := range report.ICMP {
+		if _, err := tx.Exec(upsertHost, icmp.IP, runID, runID); err != nil {
+			return fmt.Errorf("error inserting host: %s", err)
+		}
+		if _, err := tx.Exec(`UPDATE hosts SET reachable = ?, os_guess = ? WHERE ip = ?`,
+			icmp.Reachable, icmp.OSGuess, icmp.IP); err != nil {
+			return fmt.Errorf("error inserting host: %s", err)
+		}
+	}
+
+	for _, svc := range append(append([]service.ServiceVersion{}, report.TCP...), report.UDP...) {
+		if _, err := tx.Exec(upsertHost, svc.IP, runID, runID); err != nil {
+			return fmt.Errorf("error inserting host: %s", err)
+		}
+		if _, err := tx.Exec(`INSERT INTO services (port, protocol, name) VALUES (?, ?, ?)
+			ON CONFLICT (port, protocol) DO UPDATE SET name = excluded.name`,
+			svc.Port, svc.Protocol, svc.Service); err != nil {
+			return fmt.Errorf("error inserting service: %s", err)
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO ports (run_id, ip, port, protocol, state, reason, banner, severity, vuln, scanned_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, svc.IP, svc.Port, svc.Protocol, svc.State, svc.Reason, svc.Banner, string(svc.Severity),
+			svc.Vuln, sqliteTime(svc.Timestamp)); err != nil {
+			return fmt.Errorf("error inserting port: %s", err)
+		}
+	}
+	return nil
+}
+
+// sqliteTime formats t as RFC 3339, which sorts and compares correctly in
+// SQL, or returns nil for the zero time.
+func sqliteTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,14 @@
+//go:build !sqlite
+
+package main
+
+import "errors"
+
+// ExportSQLite inserts a scan report into a SQLite database. This build has
+// no SQLite driver: rebuild with -tags sqlite to enable the export.
+//
+// Returns:
+// - An error saying SQLite support is not built in.
+func ExportSQLite(path string, report ScanReport) error {
+	return errors.New("error exporting to SQLite: built without SQLite support, rebuild with -tags sqlite")
+}
@@ -0,0 +1,16 @@
+//go:build !sqlite
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportSQLite_NotBuiltIn(t *testing.T) {
+	err := ExportSQLite(filepath.Join(t.TempDir(), "scans.db"), ScanReport{})
+	if err == nil || !strings.Contains(err.Error(), "-tags sqlite") {
+		t.Errorf("Expected an error pointing at the sqlite build tag, got %v", err)
+	}
+}
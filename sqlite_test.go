@@ -0,0 +1,69 @@
+//go:build sqlite
+
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestExportSQLite_QueryBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scans.db")
+	report := ScanReport{
+		Meta:       ScanMeta{Target: "example.com", Ports: "22,80", Workers: 10, Mode: "connect", Start: time.Now(), Version: "dev"},
+		Target:     "example.com",
+		Resolution: Resolution{Host: "example.com", IPs: []string{"192.0.2.1"}},
+		TCP: []service.ServiceVersion{
+			{IP: "192.0.2.1", Port: 22, Protocol: "TCP", Service: "SSH", State: "Open", Banner: "SSH-2.0-OpenSSH_7.7", Severity: service.SeverityLow},
+			{IP: "192.0.2.1", Port: 80, Protocol: "TCP", Service: "HTTP", State: "Closed", Reason: ReasonRefused},
+		},
+		ICMP: []ICMPResult{{IP: "192.0.2.1", Reachable: true, State: ICMPReachable, OSGuess: "Linux/Unix"}},
+	}
+	for run := 0; run < 2; run++ {
+		if err := ExportSQLite(path, report); err != nil {
+			t.Fatalf("ExportSQLite failed: %s", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Opening the database failed: %s", err)
+	}
+	defer db.Close()
+
+	var runs, hosts, lastSeen int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM runs`).Scan(&runs); err != nil || runs != 2 {
+		t.Errorf("Expected 2 runs, got %d (%v)", runs, err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*), MAX(last_seen) FROM hosts`).Scan(&hosts, &lastSeen); err != nil || hosts != 1 || lastSeen != 2 {
+		t.Errorf("Expected 1 host last seen in run 2, got %d hosts last seen in run %d (%v)", hosts, lastSeen, err)
+	}
+
+	rows, err := db.Query(`SELECT r.id, p.ip, p.port, s.name, p.banner FROM ports p
+		JOIN runs r ON r.id = p.run_id
+		JOIN services s ON s.port = p.port AND s.protocol = p.protocol
+		WHERE p.state = 'Open' ORDER BY r.id`)
+	if err != nil {
+		t.Fatalf("Querying the open ports failed: %s", err)
+	}
+	defer rows.Close()
+	var open int
+	for rows.Next() {
+		var runID, port int
+		var ip, name, banner string
+		if err := rows.Scan(&runID, &ip, &port, &name, &banner); err != nil {
+			t.Fatalf("Scanning a row failed: %s", err)
+		}
+		open++
+		if runID != open || ip != "192.0.2.1" || port != 22 || name != "SSH" || banner != "SSH-2.0-OpenSSH_7.7" {
+			t.Errorf("Expected open SSH on 192.0.2.1:22 in run %d, got run %d %s:%d %s %q", open, runID, ip, port, name, banner)
+		}
+	}
+	if open != 2 {
+		t.Errorf("Expected the open port once per run, got %d", open)
+	}
+}
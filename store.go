@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// AppendRun persists a scan report to a results store, a file holding one
+// JSON encoded ScanReport per line, so scheduled scans of the same targets
+// can be compared over time (see LoadRuns and DiffReports).
+//
+// Parameters:
+// - store: The path of the store, created if it does not exist.
+// - report: The report of the run.
+//
+// Returns:
+// - An error if the store cannot be written.
+//
+// Example:
+//
+//	scanner.Scan()
+//	err := AppendRun("runs.ndjson", scanner.Report())
+func AppendRun(store string, report ScanReport) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("error encoding run: %s", err)
+	}
+	file, err := os.OpenFile(store, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening store: %s", err)
+	}
+	// Write the run with a single call so a crash cannot interleave it with another
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing to store: %s", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing to store: %s", err)
+	}
+	return nil
+}
+
+// LoadRuns reads every run persisted by AppendRun, oldest first. A store
+// that does not exist yet holds no runs.
+//
+// Parameters:
+// - store: The path of the store.
+//
+// Returns:
+// - The runs in the order they were appended.
+// - An error if the store cannot be read or a run is corrupt, e.g. cut short
+// by a crash; the runs before it are still returned.
+//
+// Example:
+//
+//	runs, err := LoadRuns("runs.ndjson")
+//	if len(runs) >= 2 {
+//	    diff := DiffReports(runs[len(runs)-2], runs[len(runs)-1])
+//	}
+func LoadRuns(store string) ([]ScanReport, error) {
+	file, err := os.Open(store)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening store: %s", err)
+	}
+	defer file.Close()
+
+	var runs []ScanReport
+	decoder := json.NewDecoder(file)
+	for {
+		var report ScanReport
+		err := decoder.Decode(&report)
+		if err == io.EOF {
+			return runs, nil
+		}
+		if err != nil {
+			return runs, fmt.Errorf("error reading run %d from store: %s", len(runs)+1, err)
+		}
+		runs = append(runs, report)
+	}
+}
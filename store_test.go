@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestAppendRun_LoadRuns(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "runs.ndjson")
+	observed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	runs := []ScanReport{
+		{
+			Target:     "example.com",
+			Resolution: Resolution{Host: "example.com", IPs: []string{"192.0.2.1"}},
+			TCP:        []service.ServiceVersion{{IP: "192.0.2.1", Port: 22, Protocol: "TCP", Service: "ssh", State: "Open", Timestamp: observed}},
+			ICMP:       []ICMPResult{{IP: "192.0.2.1", Reachable: true, State: ICMPReachable, RTT: 12 * time.Millisecond, TTL: 64}},
+		},
+		{
+			Target:     "example.com",
+			Resolution: Resolution{Host: "example.com", IPs: []string{"192.0.2.1"}},
+			TCP: []service.ServiceVersion{
+				{IP: "192.0.2.1", Port: 22, Protocol: "TCP", Service: "ssh", State: "Open", Timestamp: observed.Add(time.Hour)},
+				{IP: "192.0.2.1", Port: 443, Protocol: "TCP", Service: "https", State: "Open", Banner: "nginx", Timestamp: observed.Add(time.Hour)},
+			},
+			Geo:            map[string]GeoInfo{"192.0.2.1": {ASN: 64496, Org: "Example", Country: "US"}},
+			ProxySuspected: true,
+		},
+	}
+	for _, run := range runs {
+		if err := AppendRun(store, run); err != nil {
+			t.Fatalf("AppendRun failed: %s", err)
+		}
+	}
+
+	loaded, err := LoadRuns(store)
+	if err != nil {
+		t.Fatalf("LoadRuns failed: %s", err)
+	}
+	if !reflect.DeepEqual(loaded, runs) {
+		t.Errorf("Expected the runs back intact:\nexpected %+v\ngot      %+v", runs, loaded)
+	}
+	if diff := DiffReports(loaded[0], loaded[1]); len(diff.Opened) != 1 || diff.Opened[0].Port != 443 {
+		t.Errorf("Expected port 443 to be opened between the runs, got %+v", diff)
+	}
+}
+
+func TestLoadRuns_Missing(t *testing.T) {
+	runs, err := LoadRuns(filepath.Join(t.TempDir(), "missing.ndjson"))
+	if err != nil || runs != nil {
+		t.Errorf("Expected no runs and no error for a missing store, got %v, %v", runs, err)
+	}
+}
+
+func TestLoadRuns_Truncated(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "runs.ndjson")
+	if err := AppendRun(store, ScanReport{Target: "example.com"}); err != nil {
+		t.Fatalf("AppendRun failed: %s", err)
+	}
+	file, err := os.OpenFile(store, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Opening store failed: %s", err)
+	}
+	file.WriteString(`{"target":"exam`)
+	file.Close()
+
+	runs, err := LoadRuns(store)
+	if err == nil {
+		t.Errorf("Expected an error for a run cut short")
+	}
+	if len(runs) != 1 || runs[0].Target != "example.com" {
+		t.Errorf("Expected the complete run before the corrupt one, got %+v", runs)
+	}
+}
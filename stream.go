@@ -0,0 +1,73 @@
+package main
+
+import "net"
+
+// NewStreamingTarget creates a PortScanner in streaming mode. Its endpoint
+// channels hold a few endpoints per worker instead of one per probe,
+// endpoints are generated as they are enqueued, and TCP and UDP results are
+// delivered to onResult only, so a full 65535 port scan of many targets runs
+// in memory proportional to the workers. The report then holds no TCP or UDP results;
+// write them from onResult instead, e.g. with an NDJSONWriter.
+//
+// Parameters:
+// - domain: The target to scan, in any form NewTarget accepts.
+// - numWorkers: The number of worker goroutines to use for scanning.
+// - onResult: Called from the workers with every result as it arrives.
+//
+// Returns:
+// - A pointer to a newly created PortScanner instance.
+// - An error if numWorkers is below 1 or the domain cannot be resolved to an IP address.
+//
+// Example:
+//
+//	scanner, err := NewStreamingTarget("example.com", 100, NewNDJSONWriter(os.Stdout).Write)
+//	scanner.Scan()
+func NewStreamingTarget(domain string, numWorkers int, onResult func(ScanEvent)) (*PortScanner, error) {
+	// Unix sockets, CIDR and dash-form ranges are built as by NewTarget
+	t, err := NewTargetWithResolver(domain, numWorkers, net.DefaultResolver)
+	if err != nil {
+		return nil, err
+	}
+	t.Streaming = true
+	t.OnResult = onResult
+	if t.UnixSocket == "" {
+		t.TCPPortChannel = make(chan Endpoint, streamBuffer(numWorkers))
+		t.UDPPortChannel = make(chan Endpoint, streamBuffer(numWorkers))
+	}
+	return t, nil
+}
+
+// streamBuffer is the channel capacity used in streaming mode.
+func streamBuffer(numWorkers int) int {
+	if numWorkers < 1 {
+		return 1
+	}
+	return numWorkers
+}
+
+// eachEndpoint calls fn with every endpoint in the order of Interleave over
+// the ports as ordered by Order, shuffled when RandomizeOrder is set. In streaming mode the endpoints are
+// generated one by one unless they must be shuffled.
+//
+// Parameters:
+// - ips: The IP addresses to scan.
+// - ports: The ports to scan on each IP.
+// - fn: Called with each endpoint.
+func (t *PortScanner) eachEndpoint(ips []string, ports []int, fn func(Endpoint)) {
+	ports = t.orderedPorts(ports)
+	if t.Streaming && !t.RandomizeOrder {
+		for _, port := range ports {
+			for _, ip := range ips {
+				fn(Endpoint{IP: ip, Port: port})
+			}
+		}
+		return
+	}
+	endpoints := Interleave(ips, ports)
+	if t.RandomizeOrder {
+		t.shuffleEndpoints(endpoints)
+	}
+	for _, endpoint := range endpoints {
+		fn(endpoint)
+	}
+}
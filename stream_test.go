@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sparseDial accepts connections to every 1000th port and refuses the rest.
+func sparseDial(network, address string, timeout time.Duration) (net.Conn, error) {
+	_, port, _ := net.SplitHostPort(address)
+	if len(port) < 4 || port[len(port)-3:] != "000" {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+// newLoopbackTarget creates a scanner of every port on 127.0.0.1 that dials
+// with sparseDial and never pings.
+func newLoopbackTarget(streaming bool, onResult func(ScanEvent)) *PortScanner {
+	scanner := newTargetFromResolution("127.0.0.1", 64, Resolution{Host: "127.0.0.1", IPs: []string{"127.0.0.1"}}, streaming)
+	scanner.Dial = sparseDial
+	scanner.HTTPProbe = false
+	scanner.SNMPCommunities = nil
+	scanner.Quiet = true
+	scanner.OnResult = onResult
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	return scanner
+}
+
+func TestStreaming_DeliversThroughOnResult(t *testing.T) {
+	var mu sync.Mutex
+	ports := map[int]bool{}
+	scanner := newLoopbackTarget(true, func(event ScanEvent) {
+		if event.Type == EventPort && event.Port.Protocol == "TCP" {
+			mu.Lock()
+			ports[event.Port.Port] = true
+			mu.Unlock()
+		}
+	})
+	if cap(scanner.OpenPorts) > 64 || cap(scanner.TCPPortChannel) > 64 {
+		t.Errorf("Expected channels sized to the workers, got %d and %d", cap(scanner.OpenPorts), cap(scanner.TCPPortChannel))
+	}
+
+	scanner.Scan()
+
+	if len(ports) != 65 {
+		t.Errorf("Expected 65 open TCP ports through OnResult, got %d", len(ports))
+	}
+	if report := scanner.Report(); len(report.TCP) != 0 {
+		t.Errorf("Expected no buffered TCP results in streaming mode, got %d", len(report.TCP))
+	}
+}
+
+func TestNewStreamingTarget_Ranges(t *testing.T) {
+	for _, target := range []string{"192.0.2.0/30", "192.0.2.1-4"} {
+		scanner, err := NewStreamingTarget(target, 8, func(ScanEvent) {})
+		if err != nil {
+			t.Fatalf("NewStreamingTarget(%q) failed: %s", target, err)
+		}
+		if len(scanner.IPs) != 4 {
+			t.Errorf("Expected %q to expand to 4 IPs, got %v", target, scanner.IPs)
+		}
+		if !scanner.Streaming || cap(scanner.TCPPortChannel) > 8 {
+			t.Errorf("Expected %q to stream with channels sized to the workers, got capacity %d", target, cap(scanner.TCPPortChannel))
+		}
+	}
+
+	scanner, err := NewStreamingTarget("unix:///run/app.sock", 8, func(ScanEvent) {})
+	if err != nil {
+		t.Fatalf("NewStreamingTarget failed: %s", err)
+	}
+	if scanner.UnixSocket != "/run/app.sock" {
+		t.Errorf("Expected a Unix socket target, got %q", scanner.UnixSocket)
+	}
+}
+
+// BenchmarkScan_Memory scans every port buffered and streaming and reports
+// the peak heap. B/op counts the garbage of every probe, the same in both
+// modes; the peak is what streaming bounds to the workers.
+func BenchmarkScan_Memory(b *testing.B) {
+	for _, mode := range []struct {
+		name      string
+		streaming bool
+	}{{"Buffered", false}, {"Streaming", true}} {
+		b.Run(mode.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for i := 0; i < b.N; i++ {
+				scanner := newLoopbackTarget(mode.streaming, func(ScanEvent) {})
+				runtime.GC()
+				stop := make(chan struct{})
+				sampled := peakHeap(stop)
+				scanner.Scan()
+				close(stop)
+				if p := <-sampled; p > peak {
+					peak = p
+				}
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-MiB")
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ScanSummary counts the probed TCP and UDP ports by state, whether or not
+// ReportStates lets them into the report.
+//
+// Fields:
+// - Open: The ports found open.
+// - Closed: The ports found closed.
+// - Filtered: The ports that did not answer.
+// - Other: The ports in any other state, e.g. Error or Unscanned.
+//
+// Example:
+//
+//	fmt.Println(scanner.Summary()) // 3 open, 65530 closed, 2 filtered
+type ScanSummary struct {
+	Open     int `json:"open"`
+	Closed   int `json:"closed"`
+	Filtered int `json:"filtered"`
+	Other    int `json:"other,omitempty"`
+}
+
+// String formats the summary as "X open, Y closed, Z filtered", followed by
+// the other states if there were any.
+func (s ScanSummary) String() string {
+	line := fmt.Sprintf("%d open, %d closed, %d filtered", s.Open, s.Closed, s.Filtered)
+	if s.Other > 0 {
+		line += fmt.Sprintf(", %d other", s.Other)
+	}
+	return line
+}
+
+// stateCounts holds the counters behind ScanSummary. errors counts the
+// Error states within other, for Metrics.
+type stateCounts struct {
+	open, closed, filtered, other, errors int64
+}
+
+// countState counts a probed port for the summary. It is safe for
+// concurrent use.
+//
+// Parameters:
+// - state: The final state of the port.
+func (t *PortScanner) countState(state string) {
+	switch State(state) {
+	case StateOpen:
+		atomic.AddInt64(&t.states.open, 1)
+		t.signalAnswer()
+	case StateClosed:
+		atomic.AddInt64(&t.states.closed, 1)
+		t.signalAnswer()
+	case StateFiltered:
+		atomic.AddInt64(&t.states.filtered, 1)
+	case StateError:
+		atomic.AddInt64(&t.states.errors, 1)
+		atomic.AddInt64(&t.states.other, 1)
+	default:
+		atomic.AddInt64(&t.states.other, 1)
+	}
+}
+
+// Summary counts the TCP and UDP ports probed so far by state. It is safe
+// to call while the scan is running.
+//
+// Returns:
+// - The counts.
+//
+// Example:
+//
+//	scanner.Scan()
+//	fmt.Println(scanner.Summary())
+func (t *PortScanner) Summary() ScanSummary {
+	return ScanSummary{
+		Open:     int(atomic.LoadInt64(&t.states.open)),
+		Closed:   int(atomic.LoadInt64(&t.states.closed)),
+		Filtered: int(atomic.LoadInt64(&t.states.filtered)),
+		Other:    int(atomic.LoadInt64(&t.states.other)),
+	}
+}
+
+// logPort prints the progress line of a single port or IP, which
+// SummaryOnly suppresses along with the per-port lines of the output file.
+//
+// Parameters:
+// - format: The fmt.Printf format.
+// - args: The values to format.
+func (t *PortScanner) logPort(format string, args ...interface{}) {
+	if !t.SummaryOnly {
+		t.logf(format, args...)
+	}
+}
+
+// logsPorts reports whether logPort prints anything. The per-endpoint paths
+// check it first, so a quiet scan does not box their arguments for nothing.
+func (t *PortScanner) logsPorts() bool {
+	return !t.Quiet && !t.SummaryOnly
+}
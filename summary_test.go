@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// summaryTarget creates a scanner of ports 1 to 5 on 127.0.0.1 where TCP
+// port 1 is open, port 3 filtered and every other port closed.
+func summaryTarget(t *testing.T) *PortScanner {
+	scanner, err := NewTarget("127.0.0.1", 2)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{1, 2, 3, 4, 5}
+	scanner.TCPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) {
+		switch port {
+		case 1:
+			return StateOpen, nil
+		case 3:
+			return StateFiltered, nil
+		}
+		return StateClosed, nil
+	})
+	scanner.UDPProber = ProberFunc(func(ctx context.Context, ip string, port int) (State, error) { return StateClosed, nil })
+	scanner.pinger = func(ip string) ICMPResult { return ICMPResult{IP: ip, State: ICMPNoReply} }
+	return scanner
+}
+
+func TestSummary_CountsEveryProbedPort(t *testing.T) {
+	scanner := summaryTarget(t)
+	scanner.Quiet = true
+	scanner.Scan()
+
+	expected := ScanSummary{Open: 1, Closed: 8, Filtered: 1}
+	if summary := scanner.Summary(); summary != expected {
+		t.Errorf("Expected %+v, got %+v", expected, summary)
+	}
+	if report := scanner.Report(); report.Summary != expected {
+		t.Errorf("Expected the report summary %+v, got %+v", expected, report.Summary)
+	}
+	if line := expected.String(); line != "1 open, 8 closed, 1 filtered" {
+		t.Errorf("Expected %q, got %q", "1 open, 8 closed, 1 filtered", line)
+	}
+	if line := (ScanSummary{Open: 1, Other: 2}).String(); line != "1 open, 0 closed, 0 filtered, 2 other" {
+		t.Errorf("Expected the other states to be counted, got %q", line)
+	}
+}
+
+func TestSummaryOnly_WritesOnlyTheSummary(t *testing.T) {
+	scanner := summaryTarget(t)
+	scanner.SummaryOnly = true
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %s", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	scanner.Scan()
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+
+	file := filepath.Join(t.TempDir(), "output.txt")
+	if err := writeResultsToFile(scanner, file); err != nil {
+		t.Fatalf("writeResultsToFile failed: %s", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Reading the output failed: %s", err)
+	}
+	if expected := "Summary: 1 open, 8 closed, 1 filtered\n"; string(data) != expected {
+		t.Errorf("Expected the file to hold only %q, got %q", expected, data)
+	}
+	if strings.Contains(string(printed), "Port ") || !strings.Contains(string(printed), "Summary: 1 open, 8 closed, 1 filtered") {
+		t.Errorf("Expected only the summary on stdout, got %q", printed)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isTerminal reports whether r is an interactive terminal, where run prompts
+// for the domain instead of reading a list of targets.
+//
+// Parameters:
+// - r: The reader, usually os.Stdin.
+//
+// Returns:
+// - true for a character device such as a TTY; false for pipes, files and readers that are not files.
+func isTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ReadTargets reads newline-separated targets, as piped in with
+// "cat targets.txt | det". Surrounding whitespace is trimmed; blank lines
+// and lines starting with "#" are skipped.
+//
+// Parameters:
+// - r: Where to read the targets from.
+//
+// Returns:
+// - The targets, in order.
+// - An error if reading fails.
+//
+// Example:
+//
+//	targets, err := ReadTargets(strings.NewReader("example.com\n# staging\nexample.org\n"))
+//	// targets: ["example.com", "example.org"]
+func ReadTargets(r io.Reader) ([]string, error) {
+	var targets []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// perTargetFile names the file of one of several targets after the shared
+// name given on the command line, e.g. "output-example.com.txt" for
+// "output.txt", so concurrent scans do not write to the same file.
+//
+// Parameters:
+// - name: The file name given on the command line.
+// - target: The target the file is for.
+//
+// Returns:
+// - The file name for target, or "" if name is empty.
+func perTargetFile(name, target string) string {
+	if name == "" {
+		return ""
+	}
+	safe := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(target)
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-" + safe + ext
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadTargets(t *testing.T) {
+	input := "example.com\n\n  # staging hosts\nexample.org  \n\t\n#example.net\n10.0.0.1\n"
+	targets, err := ReadTargets(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadTargets failed: %s", err)
+	}
+	expected := []string{"example.com", "example.org", "10.0.0.1"}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("Expected %v, got %v", expected, targets)
+	}
+}
+
+func TestPerTargetFile(t *testing.T) {
+	tests := []struct {
+		name, target, expected string
+	}{
+		{"output.txt", "example.com", "output-example.com.txt"},
+		{"dir/scan", "10.0.0.1", "dir/scan-10.0.0.1"},
+		{"output.txt", "unix:///var/run/docker.sock", "output-unix____var_run_docker.sock.txt"},
+		{"", "example.com", ""},
+	}
+	for _, tt := range tests {
+		if got := perTargetFile(tt.name, tt.target); got != tt.expected {
+			t.Errorf("perTargetFile(%q, %q): Expected %q, got %q", tt.name, tt.target, tt.expected, got)
+		}
+	}
+}
+
+func TestRun_TargetsFromStdin(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	// Only 127.0.0.1 has the listener, 127.0.0.2 is another loopback address
+	dir := t.TempDir()
+	output := filepath.Join(dir, "output.txt")
+	stdin := strings.NewReader("# loopback addresses\n127.0.0.1\n\n127.0.0.2\n")
+	if code := run([]string{"-quiet", "-o", output, "-ports", port}, stdin); code != ExitOpen {
+		t.Errorf("Expected exit code %d, got %d", ExitOpen, code)
+	}
+
+	for target, open := range map[string]bool{"127.0.0.1": true, "127.0.0.2": false} {
+		data, err := os.ReadFile(filepath.Join(dir, "output-"+target+".txt"))
+		if err != nil {
+			t.Errorf("Expected an output file for %s: %s", target, err)
+			continue
+		}
+		if found := strings.Contains(string(data), "Port "+port+" (TCP) is Open"); found != open {
+			t.Errorf("%s: Expected port %s listed %v, got %q", target, port, open, data)
+		}
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("Expected no shared output file with several targets, got %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"det/service"
+)
+
+// exampleTemplates holds the report templates shipped with the scanner.
+//
+//go:embed templates/*.tmpl
+var exampleTemplates embed.FS
+
+// ExampleTemplates names the shipped report templates accepted by
+// LoadTemplate: "markdown" renders a Markdown table, "nagios" a one-line
+// Nagios plugin status with performance data.
+var ExampleTemplates = []string{"markdown", "nagios"}
+
+// TemplateFuncs are the functions available to report templates:
+// - join: strings.Join, e.g. {{join .Resolution.IPs ", "}}.
+// - open: the ports of a list in the "Open" state, e.g. {{len (open .TCP)}}.
+var TemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"open": func(services []service.ServiceVersion) []service.ServiceVersion {
+		var open []service.ServiceVersion
+		for _, svc := range services {
+			if svc.State == "Open" {
+				open = append(open, svc)
+			}
+		}
+		return open
+	},
+}
+
+// LoadTemplate parses a report template for WriteResultsTemplate, with
+// TemplateFuncs available.
+//
+// Parameters:
+// - name: One of ExampleTemplates, or the path of a text/template file.
+//
+// Returns:
+// - The parsed template.
+// - An error if the file cannot be read or the template is invalid.
+//
+// Example:
+//
+//	tmpl, err := LoadTemplate("markdown")
+func LoadTemplate(name string) (*template.Template, error) {
+	var data []byte
+	var err error
+	if isExampleTemplate(name) {
+		data, err = exampleTemplates.ReadFile("templates/" + name + ".tmpl")
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading template: %s", err)
+	}
+	tmpl, err := template.New(name).Funcs(TemplateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %s", err)
+	}
+	return tmpl, nil
+}
+
+// isExampleTemplate reports whether name is one of ExampleTemplates.
+func isExampleTemplate(name string) bool {
+	for _, example := range ExampleTemplates {
+		if name == example {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteResultsTemplate renders the scan results with a custom template, the
+// ScanReport being the template's data, so organizations can produce their
+// own report layout.
+//
+// Parameters:
+// - w: Where to write the report.
+// - t: The scanner whose results are rendered.
+// - tmpl: The template, e.g. from LoadTemplate.
+//
+// Returns:
+// - An error if the template fails to render.
+//
+// Example:
+//
+//	tmpl := template.Must(template.New("ports").Parse("{{range .TCP}}{{.Port}}\n{{end}}"))
+//	err := WriteResultsTemplate(os.Stdout, scanner, tmpl)
+func WriteResultsTemplate(w io.Writer, t *PortScanner, tmpl *template.Template) error {
+	if err := tmpl.Execute(w, t.Report()); err != nil {
+		return fmt.Errorf("error rendering template: %s", err)
+	}
+	return nil
+}
+
+// writeTemplateFile renders the scan results with a template into a file.
+//
+// Parameters:
+// - t: The scanner whose results are rendered.
+// - name: The template, as accepted by LoadTemplate.
+// - fileName: The file to write.
+//
+// Returns:
+// - An error if the template cannot be loaded or the file written.
+func writeTemplateFile(t *PortScanner, name, fileName string) error {
+	tmpl, err := LoadTemplate(name)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("error creating file: %s", err)
+	}
+	defer file.Close()
+	return WriteResultsTemplate(file, t, tmpl)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"text/template"
+
+	"det/service"
+)
+
+func TestWriteResultsTemplate(t *testing.T) {
+	scanner := &PortScanner{
+		Domain:       "example.com",
+		OpenPorts:    make(chan service.ServiceVersion, 2),
+		OpenPortsUDP: make(chan service.ServiceVersion),
+		ICMPResults:  make(chan ICMPResult),
+	}
+	scanner.OpenPorts <- service.ServiceVersion{IP: "192.0.2.1", Port: 22, Service: "ssh", State: "Open"}
+	scanner.OpenPorts <- service.ServiceVersion{IP: "192.0.2.1", Port: 23, Service: "telnet", State: "Closed"}
+
+	tmpl := template.Must(template.New("test").Funcs(TemplateFuncs).Parse(
+		"{{.Target}}:{{range open .TCP}} {{.Port}}/{{.Service}}{{end}}"))
+	var buf bytes.Buffer
+	if err := WriteResultsTemplate(&buf, scanner, tmpl); err != nil {
+		t.Fatalf("WriteResultsTemplate failed: %s", err)
+	}
+	if got, want := buf.String(), "example.com: 22/ssh"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestLoadTemplate_Examples(t *testing.T) {
+	scanner := &PortScanner{
+		Domain:       "example.com",
+		OpenPorts:    make(chan service.ServiceVersion, 1),
+		OpenPortsUDP: make(chan service.ServiceVersion),
+		ICMPResults:  make(chan ICMPResult),
+	}
+	scanner.OpenPorts <- service.ServiceVersion{IP: "192.0.2.1", Port: 443, Service: "https", State: "Open"}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"markdown", "| 192.0.2.1 | 443 | TCP | Open | https |"},
+		{"nagios", "OK - example.com: 1 open TCP ports 443/https | open_tcp=1 open_udp=0"},
+	}
+	for _, test := range tests {
+		tmpl, err := LoadTemplate(test.name)
+		if err != nil {
+			t.Fatalf("LoadTemplate(%q) failed: %s", test.name, err)
+		}
+		var buf bytes.Buffer
+		if err := WriteResultsTemplate(&buf, scanner, tmpl); err != nil {
+			t.Fatalf("Rendering %q failed: %s", test.name, err)
+		}
+		if !strings.Contains(buf.String(), test.want) {
+			t.Errorf("Expected %q output to contain %q, got:\n%s", test.name, test.want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import "context"
+
+// forcedProber answers every probe without any network I/O, reporting the
+// ports in open as open and every other port as closed. It backs TestMode.
+type forcedProber struct {
+	open map[int]bool
+}
+
+// Probe returns the forced state of port.
+func (p forcedProber) Probe(ctx context.Context, ip string, port int) (State, error) {
+	detail, err := p.ProbeDetail(ctx, ip, port)
+	return detail.State, err
+}
+
+// ProbeDetail returns the forced state of port with ReasonTestMode.
+func (p forcedProber) ProbeDetail(ctx context.Context, ip string, port int) (ProbeDetail, error) {
+	if p.open[port] {
+		return ProbeDetail{State: StateOpen, Reason: ReasonTestMode}, nil
+	}
+	return ProbeDetail{State: StateClosed, Reason: ReasonTestMode}, nil
+}
+
+// applyTestMode replaces the scan's probes with synthetic ones when TestMode
+// is set. This is for testing only: the TCP ports in ForceOpen are reported open,
+// every other port is reported closed and every IP answers pings. The probes that
+// would reach the network besides the port probes, i.e. HTTP probes,
+// fingerprinting, port knocking, reverse DNS and the ICMP listeners, are
+// turned off, so the scan does no network I/O at all. Resolution happens in
+// NewTarget, before this: give it an IP literal or "localhost".
+func (t *PortScanner) applyTestMode() {
+	if !t.TestMode {
+		return
+	}
+	open := make(map[int]bool, len(t.ForceOpen))
+	for _, port := range t.ForceOpen {
+		open[port] = true
+	}
+	t.TCPProber = forcedProber{open: open}
+	t.UDPProber = forcedProber{}
+	t.pinger = func(ip string) ICMPResult {
+		return ICMPResult{IP: ip, Reachable: true, State: ICMPReachable}
+	}
+	t.HTTPProbe = false
+	t.Fingerprint = 0
+	t.KnockSequence = nil
+	t.ReverseDNS = false
+	t.FastUDPClose = false
+}
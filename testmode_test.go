@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScan_TestModeForcesOpenPorts(t *testing.T) {
+	scanner, err := NewTarget("127.0.0.1", 4)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	scanner.Ports = []int{21, 22, 80, 8080}
+	scanner.TestMode = true
+	scanner.ForceOpen = []int{22, 8080}
+	scanner.ReverseDNS = true
+	scanner.Fingerprint = 3
+	scanner.Quiet = true
+	var dials int64
+	scanner.Dial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		atomic.AddInt64(&dials, 1)
+		return nil, errors.New("dialed in TestMode")
+	}
+	scanner.Scan()
+
+	if dials != 0 {
+		t.Errorf("Expected no network I/O, got %d dials", dials)
+	}
+	report := scanner.Report()
+	open := map[int]string{}
+	for _, svc := range report.TCP {
+		open[svc.Port] = svc.Service
+		if svc.Reason != ReasonTestMode {
+			t.Errorf("Port %d: Expected reason %q, got %q", svc.Port, ReasonTestMode, svc.Reason)
+		}
+	}
+	if len(open) != 2 || open[22] != "ssh" || open[8080] == "" {
+		t.Errorf("Expected ports 22 (ssh) and 8080 open with their services, got %v", open)
+	}
+	if len(report.UDP) != 0 {
+		t.Errorf("Expected no open UDP ports, got %d", len(report.UDP))
+	}
+	if len(report.ICMP) != 1 || !report.ICMP[0].Reachable {
+		t.Errorf("Expected a synthetic ICMP reply, got %+v", report.ICMP)
+	}
+	if summary := scanner.Summary(); summary.Open != 2 || summary.Closed != 6 {
+		t.Errorf("Expected 2 open and 6 closed probes, got %s", summary)
+	}
+}
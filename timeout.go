@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTCPTimeout is the TCP connect timeout used when none is configured.
+	DefaultTCPTimeout = 10 * time.Second
+	// DefaultMinTimeout is the lower clamp applied to adaptive timeouts.
+	DefaultMinTimeout = 100 * time.Millisecond
+	// DefaultMaxTimeout is the upper clamp applied to adaptive timeouts.
+	DefaultMaxTimeout = 10 * time.Second
+	// DefaultUDPWriteTimeout bounds dialing and sending a UDP probe.
+	DefaultUDPWriteTimeout = time.Second
+	// DefaultUDPReadTimeout is how long a UDP probe waits for a response.
+	// With DefaultUDPWriteTimeout it bounds a UDP probe to 5s.
+	DefaultUDPReadTimeout = 4 * time.Second
+	// DefaultTCPRetryBackoff is the wait before the first retry of a timed
+	// out TCP connect; it doubles with every further retry.
+	DefaultTCPRetryBackoff = 200 * time.Millisecond
+	// DefaultICMPTimeout is how long a ping waits for an echo reply, and
+	// the connect timeout of the TCP reachability fallback.
+	DefaultICMPTimeout = 2 * time.Second
+
+	// rttSamples is the number of successful connects observed before the
+	// adaptive timeout replaces the configured one.
+	rttSamples = 3
+	// rttMultiplier is the factor applied to the average RTT to obtain a timeout.
+	rttMultiplier = 4
+)
+
+// DialFunc opens a connection to an address within the given timeout.
+// It has the same signature as net.DialTimeout so tests can substitute a mock.
+type DialFunc func(network, address string, timeout time.Duration) (net.Conn, error)
+
+// RTTEstimator keeps a running average of observed round-trip times and
+// derives per-port timeouts from it, similar to nmap's RTT estimator.
+//
+// The zero value is ready to use and safe for concurrent use.
+//
+// Example:
+//
+//	var est RTTEstimator
+//	est.Observe(20 * time.Millisecond)
+//	timeout := est.Timeout(10*time.Second, 100*time.Millisecond, 10*time.Second)
+type RTTEstimator struct {
+	mu      sync.Mutex
+	samples int
+	avg     time.Duration
+}
+
+// Observe records a measured round-trip time.
+//
+// Parameters:
+// - rtt: The duration of a successful connect.
+func (e *RTTEstimator) Observe(rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.samples++
+	e.avg += (rtt - e.avg) / time.Duration(e.samples)
+}
+
+// Timeout returns the timeout to use for the next probe.
+//
+// Parameters:
+// - fallback: The timeout used until enough samples have been observed.
+// - min: The lower bound for the adaptive timeout.
+// - max: The upper bound for the adaptive timeout.
+//
+// Returns:
+// - fallback while fewer than rttSamples RTTs are known, otherwise the
+// average RTT times rttMultiplier clamped to [min, max].
+func (e *RTTEstimator) Timeout(fallback, min, max time.Duration) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.samples < rttSamples {
+		return fallback
+	}
+	timeout := e.avg * rttMultiplier
+	if min > 0 && timeout < min {
+		timeout = min
+	}
+	if max > 0 && timeout > max {
+		timeout = max
+	}
+	return timeout
+}
+
+// tcpRetryBackoff returns the wait before the given retry of a timed out TCP
+// connect, doubling from TCPRetryBackoff (or DefaultTCPRetryBackoff). A
+// random delay below TCPRetryJitter is added so workers whose probes timed
+// out together do not all retry at the same instant.
+func (t *PortScanner) tcpRetryBackoff(retry int) time.Duration {
+	backoff := t.TCPRetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultTCPRetryBackoff
+	}
+	backoff <<= retry
+	if t.TCPRetryJitter > 0 {
+		backoff += time.Duration(t.rand.int63n(t.RandSeed, int64(t.TCPRetryJitter)))
+	}
+	return backoff
+}
+
+// icmpTimeout returns the configured ping timeout, or DefaultICMPTimeout.
+func (t *PortScanner) icmpTimeout() time.Duration {
+	if t.ICMPTimeout <= 0 {
+		return DefaultICMPTimeout
+	}
+	return t.ICMPTimeout
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockDialer records every dial and either accepts or refuses it.
+type mockDialer struct {
+	mu       sync.Mutex
+	timeouts []time.Duration
+	addrs    []string
+	delay    time.Duration
+	accept   func(address string) bool
+	timeout  func(address string) bool
+}
+
+// timeoutError is a net.Error reporting a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func (m *mockDialer) Dial(network, address string, timeout time.Duration) (net.Conn, error) {
+	m.mu.Lock()
+	m.timeouts = append(m.timeouts, timeout)
+	m.addrs = append(m.addrs, network+"://"+address)
+	m.mu.Unlock()
+	time.Sleep(m.delay)
+	if m.timeout != nil && m.timeout(address) {
+		return nil, timeoutError{}
+	}
+	if m.accept != nil && !m.accept(address) {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func TestRTTEstimator(t *testing.T) {
+	var est RTTEstimator
+	if got := est.Timeout(time.Second, 0, 0); got != time.Second {
+		t.Errorf("Expected fallback timeout %v, got %v", time.Second, got)
+	}
+
+	for _, rtt := range []time.Duration{10, 20, 30} {
+		est.Observe(rtt * time.Millisecond)
+	}
+	if got := est.Timeout(time.Second, 0, 0); got != 80*time.Millisecond {
+		t.Errorf("Expected adaptive timeout 80ms, got %v", got)
+	}
+	if got := est.Timeout(time.Second, 100*time.Millisecond, 0); got != 100*time.Millisecond {
+		t.Errorf("Expected timeout clamped to min 100ms, got %v", got)
+	}
+	if got := est.Timeout(time.Second, 0, 50*time.Millisecond); got != 50*time.Millisecond {
+		t.Errorf("Expected timeout clamped to max 50ms, got %v", got)
+	}
+}
+
+func TestScanPortTCP_AdaptiveTimeout(t *testing.T) {
+	dialer := &mockDialer{delay: time.Millisecond}
+	scanner := &PortScanner{
+		Timeout:         10 * time.Second,
+		AdaptiveTimeout: true,
+		MinTimeout:      50 * time.Millisecond,
+		MaxTimeout:      time.Second,
+		Dial:            dialer.Dial,
+	}
+
+	for port := 1; port <= 6; port++ {
+		if state := scanner.scanPortTCP("127.0.0.1", port); state != "Open" {
+			t.Fatalf("Port %d: Expected Open, got %s", port, state)
+		}
+	}
+
+	for i, timeout := range dialer.timeouts {
+		if i < rttSamples {
+			if timeout != 10*time.Second {
+				t.Errorf("Dial %d: Expected initial timeout 10s, got %v", i, timeout)
+			}
+			continue
+		}
+		if timeout < 50*time.Millisecond || timeout > time.Second {
+			t.Errorf("Dial %d: Expected adaptive timeout within [50ms, 1s], got %v", i, timeout)
+		}
+	}
+}
+
+func TestScanUDP_WorstCaseDuration(t *testing.T) {
+	const (
+		writeTimeout = 50 * time.Millisecond
+		readTimeout  = 100 * time.Millisecond
+		slack        = 50 * time.Millisecond
+	)
+	// silent reads the probe but never answers
+	silent := func(server net.Conn) {
+		go io.Copy(io.Discard, server)
+	}
+	// stalled never reads the probe, so the write blocks
+	stalled := func(server net.Conn) {}
+
+	tests := []struct {
+		name      string
+		dialDelay time.Duration
+		serve     func(server net.Conn)
+	}{
+		{"no response", 0, silent},
+		{"write blocked", 0, stalled},
+		{"slow dial", 2 * writeTimeout, silent},
+	}
+	for _, test := range tests {
+		var servers []net.Conn
+		dial := func(network, address string, timeout time.Duration) (net.Conn, error) {
+			time.Sleep(test.dialDelay)
+			client, server := net.Pipe()
+			servers = append(servers, server)
+			test.serve(server)
+			return client, nil
+		}
+
+		start := time.Now()
+		err := scanUDPWith(context.Background(), dial, nil, 40000, "127.0.0.1", writeTimeout, readTimeout)
+		elapsed := time.Since(start)
+		for _, server := range servers {
+			server.Close()
+		}
+
+		if !isTimeout(err) {
+			t.Errorf("%s: Expected a timeout, got %v", test.name, err)
+		}
+		budget := writeTimeout + readTimeout
+		if elapsed > budget+slack {
+			t.Errorf("%s: Expected the probe to finish within %v, took %v", test.name, budget, elapsed)
+		}
+	}
+}
+
+func TestScanPortTCP_RetriesTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []error
+		retries  int
+		state    string
+		dials    int
+	}{
+		{"timeout then connect", []error{timeoutError{}}, 2, "Open", 2},
+		{"retries exhausted", []error{timeoutError{}, timeoutError{}, timeoutError{}}, 2, "Filtered", 3},
+		{"refused is not retried", []error{errors.New("connection refused")}, 2, "Closed", 1},
+		{"retries disabled", []error{timeoutError{}}, 0, "Filtered", 1},
+	}
+	for _, test := range tests {
+		dials := 0
+		scanner := &PortScanner{
+			Dial: func(network, address string, timeout time.Duration) (net.Conn, error) {
+				dials++
+				if dials <= len(test.failures) {
+					return nil, test.failures[dials-1]
+				}
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			},
+			TCPRetries:      test.retries,
+			TCPRetryBackoff: time.Millisecond,
+		}
+		if state := scanner.scanPortTCP("127.0.0.1", 80); state != test.state {
+			t.Errorf("%s: Expected %s, got %s", test.name, test.state, state)
+		}
+		if dials != test.dials {
+			t.Errorf("%s: Expected %d dials, got %d", test.name, test.dials, dials)
+		}
+	}
+}
+
+func TestTCPRetryBackoff_Doubles(t *testing.T) {
+	scanner := &PortScanner{TCPRetryBackoff: 10 * time.Millisecond}
+	for retry, expected := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		if backoff := scanner.tcpRetryBackoff(retry); backoff != expected {
+			t.Errorf("Expected backoff %s for retry %d, got %s", expected, retry, backoff)
+		}
+	}
+}
+
+func TestTCPRetryBackoff_Jitter(t *testing.T) {
+	scanner := &PortScanner{TCPRetryBackoff: 10 * time.Millisecond, TCPRetryJitter: 50 * time.Millisecond, RandSeed: 1}
+	for retry, base := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 20; i++ {
+			backoff := scanner.tcpRetryBackoff(retry)
+			if backoff < base || backoff >= base+scanner.TCPRetryJitter {
+				t.Errorf("Retry %d: Expected a backoff in [%s, %s), got %s", retry, base, base+scanner.TCPRetryJitter, backoff)
+			}
+			seen[backoff] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("Retry %d: Expected the backoff to vary, got %v", retry, seen)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultUDPPayload is sent to UDP ports without a protocol-specific payload.
+var defaultUDPPayload = []byte("ping")
+
+//go:embed udp_payloads.txt
+var embeddedUDPPayloads string
+
+var (
+	udpPayloadsMu sync.RWMutex
+	udpPayloads   = mustParseUDPPayloads(embeddedUDPPayloads)
+)
+
+// ParseUDPPayloads parses a payload table with one "<port> <hex payload>"
+// entry per line. Blank lines and lines starting with # are ignored.
+//
+// Parameters:
+// - data: The payload table.
+//
+// Returns:
+// - The payloads keyed by port.
+// - An error if a line is malformed.
+//
+// Example:
+//
+//	payloads, err := ParseUDPPayloads("53 12340100000100000000000000010001\n")
+func ParseUDPPayloads(data string) (map[int][]byte, error) {
+	payloads := make(map[int][]byte)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected <port> <hex payload>", i+1)
+		}
+		port, err := strconv.Atoi(fields[0])
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("line %d: invalid port %q", i+1, fields[0])
+		}
+		payload, err := hex.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid payload: %s", i+1, err)
+		}
+		payloads[port] = payload
+	}
+	return payloads, nil
+}
+
+// mustParseUDPPayloads parses the embedded payload table, panicking if it is
+// malformed since that is a build defect.
+func mustParseUDPPayloads(data string) map[int][]byte {
+	payloads, err := ParseUDPPayloads(data)
+	if err != nil {
+		panic("embedded udp_payloads.txt: " + err.Error())
+	}
+	return payloads
+}
+
+// SetUDPPayloads overrides the UDP probe payloads for the given ports. Ports
+// not in overrides keep their current payload; a nil payload restores the
+// generic default for that port.
+//
+// Parameters:
+// - overrides: The payloads keyed by port.
+//
+// Example:
+//
+//	SetUDPPayloads(map[int][]byte{9999: []byte("hello")})
+func SetUDPPayloads(overrides map[int][]byte) {
+	udpPayloadsMu.Lock()
+	defer udpPayloadsMu.Unlock()
+	for port, payload := range overrides {
+		if payload == nil {
+			delete(udpPayloads, port)
+			continue
+		}
+		udpPayloads[port] = payload
+	}
+}
+
+// UDPPayload returns the probe payload sent to a UDP port.
+//
+// Parameters:
+// - port: The UDP port to probe.
+//
+// Returns:
+// - The protocol-specific payload for well-known ports, otherwise a generic one.
+func UDPPayload(port int) []byte {
+	udpPayloadsMu.RLock()
+	defer udpPayloadsMu.RUnlock()
+	if payload, ok := udpPayloads[port]; ok {
+		return payload
+	}
+	return defaultUDPPayload
+}
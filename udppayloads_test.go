@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestEmbeddedUDPPayloads(t *testing.T) {
+	payloads, err := ParseUDPPayloads(embeddedUDPPayloads)
+	if err != nil {
+		t.Fatalf("Embedded payload table failed to parse: %s", err)
+	}
+	for _, port := range []int{53, 123, 137, 161, 500} {
+		if len(payloads[port]) == 0 {
+			t.Errorf("Expected an embedded payload for port %d", port)
+		}
+	}
+	if ntp := payloads[123]; len(ntp) != 48 || ntp[0] != 0x1b {
+		t.Errorf("Expected a 48 byte NTP client request, got %x", ntp)
+	}
+}
+
+func TestParseUDPPayloads_Invalid(t *testing.T) {
+	for _, data := range []string{"53", "0 00", "53 zz", "x 00"} {
+		if _, err := ParseUDPPayloads(data); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+	}
+}
+
+func TestUDPPayload_Selection(t *testing.T) {
+	if payload := UDPPayload(53); !bytes.Equal(payload, udpPayloads[53]) || bytes.Equal(payload, defaultUDPPayload) {
+		t.Errorf("Expected the DNS payload for port 53, got %x", payload)
+	}
+	if payload := UDPPayload(40000); !bytes.Equal(payload, defaultUDPPayload) {
+		t.Errorf("Expected the default payload for port 40000, got %x", payload)
+	}
+
+	SetUDPPayloads(map[int][]byte{40000: []byte("hello")})
+	defer SetUDPPayloads(map[int][]byte{40000: nil})
+	if payload := UDPPayload(40000); string(payload) != "hello" {
+		t.Errorf("Expected the overridden payload, got %q", payload)
+	}
+}
+
+func TestScanUDP_SendsPortPayload(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %s", err)
+	}
+	defer conn.Close()
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	SetUDPPayloads(map[int][]byte{port: []byte("probe")})
+	defer SetUDPPayloads(map[int][]byte{port: nil})
+
+	received := make(chan []byte, 1)
+	go func() {
+		buf := make([]byte, 64)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			received <- nil
+			return
+		}
+		conn.WriteTo([]byte("pong"), addr)
+		received <- buf[:n]
+	}()
+
+	if err := ScanUDP(port, "127.0.0.1"); err != nil {
+		t.Fatalf("Expected the port to answer, got %s", err)
+	}
+	if payload := <-received; string(payload) != "probe" {
+		t.Errorf("Expected the port payload to be sent, got %q", payload)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"det/service"
+)
+
+// UnixScheme prefixes targets naming a Unix domain socket instead of a host,
+// e.g. "unix:///var/run/docker.sock".
+const UnixScheme = "unix://"
+
+// DefaultUnixBannerWait is how long a Unix socket scan waits for a banner
+// when BannerWait is not set.
+const DefaultUnixBannerWait = 500 * time.Millisecond
+
+// ScanUnixSocket checks whether a service listens on a Unix domain socket.
+//
+// Parameters:
+// - path: The path of the socket.
+// - timeout: The connect timeout.
+//
+// Returns:
+// - "Open" if the connect succeeded, otherwise "Closed".
+// - The connect error when the socket is closed, otherwise nil.
+//
+// Example:
+//
+//	state, err := ScanUnixSocket("/var/run/docker.sock", time.Second)
+func ScanUnixSocket(path string, timeout time.Duration) (string, error) {
+	state, _, _, err := probeUnixSocket(path, timeout, 0)
+	return state, err
+}
+
+// probeUnixSocket connects to a Unix domain socket and, if bannerWait is
+// positive, reads the banner the service sends on connect.
+//
+// Parameters:
+// - path: The path of the socket.
+// - timeout: The connect timeout.
+// - bannerWait: How long to wait for a banner, 0 to close right away.
+//
+// Returns:
+// - "Open" or "Closed".
+// - The reason for the state, e.g. "connection refused".
+// - The banner, if any.
+// - The connect error when the socket is closed.
+func probeUnixSocket(path string, timeout, bannerWait time.Duration) (string, string, string, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return "Closed", unixReason(err), "", err
+	}
+	defer conn.Close()
+	var banner string
+	if bannerWait > 0 {
+		banner = readBanner(conn, bannerWait)
+	}
+	return "Open", ReasonConnected, banner, nil
+}
+
+// unixReason names the signal behind a failed Unix socket connect, which
+// mostly fails on the file rather than on the network.
+func unixReason(err error) string {
+	switch {
+	case errors.Is(err, syscall.ENOENT):
+		return "no such socket"
+	case errors.Is(err, syscall.EACCES):
+		return "permission denied"
+	}
+	return ReasonFor(err)
+}
+
+// unixSocketPath extracts the socket path from a "unix://" target.
+//
+// Parameters:
+// - target: The target as given to NewTarget.
+//
+// Returns:
+// - The socket path.
+// - false if target is not a Unix socket target.
+// - An error if the target names no path.
+func unixSocketPath(target string) (string, bool, error) {
+	if !strings.HasPrefix(target, UnixScheme) {
+		return "", false, nil
+	}
+	path := strings.TrimPrefix(target, UnixScheme)
+	if path == "" {
+		return "", true, fmt.Errorf("missing socket path in %q", target)
+	}
+	return path, true, nil
+}
+
+// newUnixTarget creates a PortScanner for a Unix domain socket target. There
+// is nothing to resolve and no port to enumerate.
+func newUnixTarget(target, path string, numWorkers int) *PortScanner {
+	return &PortScanner{
+		Domain:         target,
+		UnixSocket:     path,
+		NumWorkers:     numWorkers,
+		TCPPortChannel: make(chan Endpoint),
+		UDPPortChannel: make(chan Endpoint),
+		OpenPorts:      make(chan service.ServiceVersion, 1),
+		OpenPortsUDP:   make(chan service.ServiceVersion),
+		ICMPResults:    make(chan ICMPResult),
+		Done:           make(chan bool, 1),
+		Services:       service.Services,
+		Timeout:        DefaultTCPTimeout,
+		ReportStates:   []string{"Open"},
+
+		ServicesByProtocol: service.ServicesByProtocol,
+	}
+}
+
+// scanUnixSocket probes the scanner's UnixSocket and reports it with the
+// "UNIX" protocol alongside the TCP ports, the socket path taking the place
+// of the IP.
+func (t *PortScanner) scanUnixSocket() {
+	bannerWait := t.BannerWait
+	if bannerWait <= 0 {
+		bannerWait = DefaultUnixBannerWait
+	}
+	state, reason, banner, _ := probeUnixSocket(t.UnixSocket, t.tcpTimeout(), bannerWait)
+	svc := service.ServiceVersion{
+		IP:        t.UnixSocket,
+		Protocol:  "UNIX",
+		Service:   "Unknown",
+		State:     state,
+		Banner:    banner,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	}
+	if state == "Open" {
+		t.notifyOpen(svc)
+		if t.recordOpen() {
+			t.sendResult(t.OpenPorts, svc)
+		}
+	} else if t.reportsState(state) {
+		t.sendResult(t.OpenPorts, svc)
+	}
+	t.countState(state)
+	t.logPort("%s: %s, Reason: %s\n", t.UnixSocket, state, reason)
+}
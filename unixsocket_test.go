@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// listenUnix starts a Unix socket server that greets every client.
+func listenUnix(t *testing.T, greeting string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "s.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(greeting))
+			conn.Close()
+		}
+	}()
+	return path
+}
+
+func TestScanUnixSocket(t *testing.T) {
+	path := listenUnix(t, "+PONG\r\n")
+	if state, err := ScanUnixSocket(path, time.Second); state != "Open" || err != nil {
+		t.Errorf("Expected Open, got %s (%v)", state, err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.sock")
+	if state, err := ScanUnixSocket(missing, time.Second); state != "Closed" || err == nil {
+		t.Errorf("Expected Closed with an error for a missing socket, got %s (%v)", state, err)
+	}
+}
+
+func TestNewTarget_UnixSocket(t *testing.T) {
+	path := listenUnix(t, "+PONG\r\n")
+	scanner, err := NewTarget(UnixScheme+path, 10)
+	if err != nil {
+		t.Fatalf("NewTarget failed: %s", err)
+	}
+	if scanner.UnixSocket != path || len(scanner.IPs) != 0 {
+		t.Fatalf("Expected a Unix socket target without IPs, got %q and %v", scanner.UnixSocket, scanner.IPs)
+	}
+	scanner.Quiet = true
+	scanner.Scan()
+
+	report := scanner.Report()
+	if len(report.TCP) != 1 {
+		t.Fatalf("Expected one result, got %+v", report.TCP)
+	}
+	svc := report.TCP[0]
+	if svc.IP != path || svc.Protocol != "UNIX" || svc.State != "Open" || svc.Banner != "+PONG" {
+		t.Errorf("Expected the open socket with its banner, got %+v", svc)
+	}
+
+	if _, err := NewTarget(UnixScheme, 10); err == nil {
+		t.Errorf("Expected an error for a target without a socket path")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// ErrPortUnreachable is returned by a UDP probe cut short by an ICMP
+// port-unreachable message.
+var ErrPortUnreachable = errors.New("udp: port unreachable")
+
+// UnreachableWatcher listens for ICMP port-unreachable messages and notifies
+// the UDP probes they are about, so a closed port is reported as soon as the
+// message arrives rather than when the read deadline expires.
+//
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	watcher, err := ListenUnreachable("0.0.0.0")
+//	if err == nil {
+//	    defer watcher.Close()
+//	}
+type UnreachableWatcher struct {
+	mu      sync.Mutex
+	waiters map[string]func()
+	conn    net.PacketConn
+}
+
+// NewUnreachableWatcher creates a watcher that is only fed through Deliver.
+//
+// Returns:
+// - A pointer to the new UnreachableWatcher.
+func NewUnreachableWatcher() *UnreachableWatcher {
+	return &UnreachableWatcher{waiters: make(map[string]func())}
+}
+
+// ListenUnreachable opens an ICMP socket and feeds every message received on
+// it to a new watcher until Close is called. Opening the socket usually
+// requires root.
+//
+// Parameters:
+// - src: The local address to listen on, "0.0.0.0" for any.
+//
+// Returns:
+// - A pointer to the new UnreachableWatcher.
+// - An error if the ICMP socket cannot be opened.
+func ListenUnreachable(src string) (*UnreachableWatcher, error) {
+	conn, err := listenRaw("ip4:icmp", src)
+	if err != nil {
+		return nil, err
+	}
+	w := NewUnreachableWatcher()
+	w.conn = conn
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if msg, err := ParseICMP(buf[:n]); err == nil {
+				w.Deliver(msg)
+			}
+		}
+	}()
+	return w, nil
+}
+
+// Close stops listening for ICMP messages.
+//
+// Returns:
+// - An error if the ICMP socket cannot be closed.
+func (w *UnreachableWatcher) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	return w.conn.Close()
+}
+
+// Watch registers a callback for a port-unreachable message about datagrams
+// sent from local to remote.
+//
+// Parameters:
+// - local: The local address of the UDP probe.
+// - remote: The address the UDP probe is sent to.
+// - onUnreachable: Called once, from the watcher's goroutine, when the message arrives.
+//
+// Returns:
+// - A function that removes the callback.
+func (w *UnreachableWatcher) Watch(local, remote *net.UDPAddr, onUnreachable func()) (stop func()) {
+	key := unreachableKey(local.Port, remote.IP, remote.Port)
+	w.mu.Lock()
+	w.waiters[key] = onUnreachable
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		delete(w.waiters, key)
+		w.mu.Unlock()
+	}
+}
+
+// Deliver notifies the probe a port-unreachable message is about, if any.
+// Other ICMP messages are ignored.
+//
+// Parameters:
+// - msg: The received ICMP message.
+func (w *UnreachableWatcher) Deliver(msg ICMPMessage) {
+	if msg.Type != icmpDestUnreachable || msg.Code != icmpPortUnreachable || msg.OriginalProto != protoUDP {
+		return
+	}
+	key := unreachableKey(msg.OriginalSrcPort, msg.OriginalDst, msg.OriginalDstPort)
+	w.mu.Lock()
+	onUnreachable, ok := w.waiters[key]
+	delete(w.waiters, key)
+	w.mu.Unlock()
+	if ok {
+		onUnreachable()
+	}
+}
+
+// unreachableKey identifies a UDP probe by its source port and destination.
+func unreachableKey(srcPort int, dst net.IP, dstPort int) string {
+	return strconv.Itoa(srcPort) + ">" + net.JoinHostPort(dst.String(), strconv.Itoa(dstPort))
+}
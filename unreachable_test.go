@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// Destination unreachable, port unreachable, quoting a UDP datagram from
+// 192.0.2.10:40000 to 192.0.2.1:53.
+const recordedPortUnreachable = "0303 607f 00000000" +
+	"4500 001c 0000 4000 4011 b6c5 c000020a c0000201" +
+	"9c40 0035 0008 0000"
+
+func TestParseICMP_PortUnreachable(t *testing.T) {
+	msg, err := ParseICMP(decodeHex(t, recordedPortUnreachable))
+	if err != nil {
+		t.Fatalf("ParseICMP failed: %s", err)
+	}
+	if msg.OriginalProto != protoUDP || msg.OriginalSrcPort != 40000 || msg.OriginalDstPort != 53 {
+		t.Errorf("Expected UDP 40000 -> 53, got protocol %d %d -> %d", msg.OriginalProto, msg.OriginalSrcPort, msg.OriginalDstPort)
+	}
+	if !msg.OriginalDst.Equal(net.ParseIP("192.0.2.1")) {
+		t.Errorf("Expected original destination 192.0.2.1, got %s", msg.OriginalDst)
+	}
+}
+
+func TestScanUDP_UnreachableShortCircuits(t *testing.T) {
+	// The server never answers, so only the watcher can end the read early
+	server, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %s", err)
+	}
+	defer server.Close()
+	port := server.LocalAddr().(*net.UDPAddr).Port
+
+	watcher := NewUnreachableWatcher()
+	locals := make(chan int, 1)
+	dial := func(network, address string, timeout time.Duration) (net.Conn, error) {
+		conn, err := net.DialTimeout(network, address, timeout)
+		if err == nil {
+			locals <- conn.LocalAddr().(*net.UDPAddr).Port
+		}
+		return conn, err
+	}
+	go func() {
+		local := <-locals
+		time.Sleep(100 * time.Millisecond)
+		watcher.Deliver(ICMPMessage{
+			Type:            icmpDestUnreachable,
+			Code:            icmpPortUnreachable,
+			OriginalDst:     net.ParseIP("127.0.0.1"),
+			OriginalProto:   protoUDP,
+			OriginalSrcPort: local,
+			OriginalDstPort: port,
+		})
+	}()
+
+	start := time.Now()
+	err = scanUDPWith(context.Background(), dial, watcher, port, "127.0.0.1", time.Second, 4*time.Second)
+	elapsed := time.Since(start)
+
+	if err != ErrPortUnreachable {
+		t.Errorf("Expected ErrPortUnreachable, got %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("Expected the probe to return soon after the unreachable at 100ms, took %v", elapsed)
+	}
+}
+
+// instantUnreachableConn is a UDP probe connection whose port-unreachable
+// is delivered by watcher while the datagram is still being written, or
+// whose write fails with err.
+type instantUnreachableConn struct {
+	net.Conn
+	watcher *UnreachableWatcher
+	err     error
+}
+
+func (c *instantUnreachableConn) Write(b []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	local := c.LocalAddr().(*net.UDPAddr)
+	remote := c.RemoteAddr().(*net.UDPAddr)
+	c.watcher.Deliver(ICMPMessage{
+		Type:            icmpDestUnreachable,
+		Code:            icmpPortUnreachable,
+		OriginalDst:     remote.IP,
+		OriginalProto:   protoUDP,
+		OriginalSrcPort: local.Port,
+		OriginalDstPort: remote.Port,
+	})
+	return c.Conn.Write(b)
+}
+
+func TestScanUDP_UnreachableBeforeRead(t *testing.T) {
+	server, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %s", err)
+	}
+	defer server.Close()
+	port := server.LocalAddr().(*net.UDPAddr).Port
+
+	for _, writeErr := range []error{nil, errors.New("write failed")} {
+		watcher := NewUnreachableWatcher()
+		dial := func(network, address string, timeout time.Duration) (net.Conn, error) {
+			conn, err := net.DialTimeout(network, address, timeout)
+			if err != nil {
+				return nil, err
+			}
+			return &instantUnreachableConn{Conn: conn, watcher: watcher, err: writeErr}, nil
+		}
+
+		start := time.Now()
+		err := scanUDPWith(context.Background(), dial, watcher, port, "127.0.0.1", time.Second, 4*time.Second)
+		elapsed := time.Since(start)
+
+		if writeErr == nil && err != ErrPortUnreachable {
+			t.Errorf("Expected ErrPortUnreachable for an unreachable during the write, got %v", err)
+		}
+		if writeErr != nil && err != writeErr {
+			t.Errorf("Expected the write error, got %v", err)
+		}
+		if elapsed > time.Second {
+			t.Errorf("Expected the probe to return at once, took %v", elapsed)
+		}
+		watcher.mu.Lock()
+		if len(watcher.waiters) != 0 {
+			t.Errorf("Expected the probe to stop watching, %d waiters left", len(watcher.waiters))
+		}
+		watcher.mu.Unlock()
+	}
+}
+
+func TestUnreachableWatcher_IgnoresOtherMessages(t *testing.T) {
+	watcher := NewUnreachableWatcher()
+	local := &net.UDPAddr{IP: net.ParseIP("192.0.2.10"), Port: 40000}
+	remote := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 53}
+	called := 0
+	stop := watcher.Watch(local, remote, func() { called++ })
+	defer stop()
+
+	watcher.Deliver(ICMPMessage{Type: icmpEchoReply})
+	watcher.Deliver(ICMPMessage{Type: icmpDestUnreachable, Code: 1, OriginalDst: remote.IP, OriginalProto: protoUDP, OriginalSrcPort: 40000, OriginalDstPort: 53})
+	watcher.Deliver(ICMPMessage{Type: icmpDestUnreachable, Code: icmpPortUnreachable, OriginalDst: remote.IP, OriginalProto: protoUDP, OriginalSrcPort: 40001, OriginalDstPort: 53})
+	if called != 0 {
+		t.Fatalf("Expected unrelated messages to be ignored, got %d calls", called)
+	}
+
+	msg, err := ParseICMP(decodeHex(t, recordedPortUnreachable))
+	if err != nil {
+		t.Fatalf("ParseICMP failed: %s", err)
+	}
+	watcher.Deliver(msg)
+	watcher.Deliver(msg)
+	if called != 1 {
+		t.Errorf("Expected exactly one notification, got %d", called)
+	}
+}
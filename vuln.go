@@ -0,0 +1,29 @@
+package main
+
+import "det/service"
+
+// knownVuln returns the note of a known vulnerability of an open port's
+// version, from its banner or else the Server header of its HTTP probe. The
+// table is KnownVulns, or service.KnownVulns when none is configured.
+//
+// Parameters:
+// - svc: The open port with its banner and HTTP details.
+//
+// Returns:
+// - The vulnerability note, or "" if FlagKnownVulns is off or nothing matches.
+func (t *PortScanner) knownVuln(svc service.ServiceVersion) string {
+	if !t.FlagKnownVulns {
+		return ""
+	}
+	table := t.KnownVulns
+	if table == nil {
+		table = service.KnownVulns
+	}
+	if note := service.VulnFor(svc.Banner, table); note != "" {
+		return note
+	}
+	if svc.HTTP != nil {
+		return service.VulnFor(svc.HTTP.Server, table)
+	}
+	return ""
+}
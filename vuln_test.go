@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestScan_FlagsKnownVulnerableBanner(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %s", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// Recorded from an Ubuntu 16.04 server
+			conn.Write([]byte("SSH-2.0-OpenSSH_7.2p2 Ubuntu-4ubuntu2.8\r\n"))
+			conn.Close()
+		}
+	}()
+
+	for _, flag := range []bool{false, true} {
+		scanner, err := NewTarget("127.0.0.1", 1)
+		if err != nil {
+			t.Fatalf("NewTarget failed: %s", err)
+		}
+		scanner.Ports = []int{listener.Addr().(*net.TCPAddr).Port}
+		scanner.EnableTCP = true
+		scanner.EnableUDP = false
+		scanner.EnableICMP = false
+		scanner.BannerWait = time.Second
+		scanner.FlagKnownVulns = flag
+		scanner.Quiet = true
+		scanner.Scan()
+
+		report := scanner.Report()
+		if len(report.TCP) != 1 {
+			t.Fatalf("Expected the open port, got %d TCP results", len(report.TCP))
+		}
+		svc := report.TCP[0]
+		line := serviceLine("TCP", svc)
+		if !flag {
+			if svc.Vuln != "" || strings.Contains(line, "Vulnerable") {
+				t.Errorf("Expected no flag without FlagKnownVulns, got %q", line)
+			}
+			continue
+		}
+		if !strings.Contains(svc.Vuln, "CVE-2016-6210") {
+			t.Errorf("Expected the OpenSSH 7.2p2 banner flagged, got %q from banner %q", svc.Vuln, svc.Banner)
+		}
+		if !strings.Contains(line, "Vulnerable: "+svc.Vuln) {
+			t.Errorf("Expected the note in the report line, got %q", line)
+		}
+	}
+}
+
+func TestKnownVuln_Overridable(t *testing.T) {
+	scanner := &PortScanner{FlagKnownVulns: true, KnownVulns: map[string]string{"nginx/1.0": "ancient nginx"}}
+	svc := service.ServiceVersion{Port: 80, HTTP: &service.HTTPInfo{Server: "nginx/1.0.15"}}
+	if got := scanner.knownVuln(svc); got != "ancient nginx" {
+		t.Errorf("Expected the custom table to match the HTTP Server header, got %q", got)
+	}
+	svc.Banner = "SSH-2.0-OpenSSH_7.7"
+	svc.HTTP = nil
+	if got := scanner.knownVuln(svc); got != "" {
+		t.Errorf("Expected the custom table to replace the default one, got %q", got)
+	}
+}
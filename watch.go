@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// watch reruns a scan every interval until ctx is done, writing only the
+// changes since the previous run to out, for -watch. The changes are the
+// output of the watch, so unlike progress they are written even when quiet.
+// A scan that is already running when ctx is done is finished first, so the
+// last report is complete. The first scan must succeed; a later one that
+// fails, e.g. because the network is briefly down, is logged and skipped,
+// and the next one is compared to the last successful report.
+//
+// Parameters:
+// - ctx: Stops watching, e.g. on SIGINT.
+// - interval: The time between the end of a scan and the start of the next.
+// - scan: Runs one scan, without progress output, and returns its report.
+// - out: Where the changes are written, e.g. os.Stdout.
+// - logf: Prints progress unless quiet.
+//
+// Returns:
+// - ExitError if the first scan failed, otherwise ExitOpen if the last
+// report has an open port and ExitClosed if it has none.
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	code := watch(ctx, time.Minute, scanOnce, os.Stdout, logf)
+func watch(ctx context.Context, interval time.Duration, scan func() (ScanReport, error), out io.Writer, logf func(string, ...interface{})) int {
+	previous, err := scan()
+	if err != nil {
+		logf("Error scanning: %s\n", err)
+		return ExitError
+	}
+	logf("Watching %s every %s, %d open ports\n", previous.Target, interval, len(openPortsByKey(previous)))
+
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if len(openPortsByKey(previous)) == 0 {
+				return ExitClosed
+			}
+			return ExitOpen
+		case <-timer.C:
+		}
+
+		report, err := scan()
+		if err != nil {
+			logf("Error scanning: %s\n", err)
+			continue
+		}
+		if diff := DiffReports(previous, report); !diff.Empty() {
+			fmt.Fprintf(out, "Changes at %s:\n%s\n", time.Now().Format(time.RFC3339), diff)
+		}
+		previous = report
+	}
+}
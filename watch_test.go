@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"det/service"
+)
+
+func TestWatch_PrintsChanges(t *testing.T) {
+	reports := []ScanReport{
+		{Target: "example.com", TCP: []service.ServiceVersion{
+			{IP: "192.0.2.1", Port: 22, Protocol: "TCP", State: "Open", Service: "ssh"},
+		}},
+		{Target: "example.com", TCP: []service.ServiceVersion{
+			{IP: "192.0.2.1", Port: 22, Protocol: "TCP", State: "Open", Service: "ssh"},
+			{IP: "192.0.2.1", Port: 80, Protocol: "TCP", State: "Open", Service: "http"},
+		}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scans := 0
+	scan := func() (ScanReport, error) {
+		report := reports[scans]
+		scans++
+		if scans == len(reports) {
+			// Interrupted during the last scan, which still completes
+			cancel()
+		}
+		return report, nil
+	}
+	// Quiet: the changes are still written
+	var printed strings.Builder
+	logf := func(string, ...interface{}) {}
+
+	code := watch(ctx, time.Millisecond, scan, &printed, logf)
+	if code != ExitOpen {
+		t.Errorf("Expected exit code %d, got %d", ExitOpen, code)
+	}
+	if scans != 2 {
+		t.Errorf("Expected 2 scans, got %d", scans)
+	}
+	if !strings.Contains(printed.String(), "Opened: 192.0.2.1 port 80 (TCP), http") {
+		t.Errorf("Expected the newly opened port to be printed, got %q", printed.String())
+	}
+	if strings.Contains(printed.String(), "port 22") {
+		t.Errorf("Expected the unchanged port not to be printed, got %q", printed.String())
+	}
+}
+
+func TestWatch_SkipsFailedScans(t *testing.T) {
+	open := ScanReport{TCP: []service.ServiceVersion{
+		{IP: "192.0.2.1", Port: 22, Protocol: "TCP", State: "Open", Service: "ssh"},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scans := 0
+	scan := func() (ScanReport, error) {
+		scans++
+		switch scans {
+		case 1:
+			return open, nil
+		case 2:
+			return ScanReport{}, errors.New("network is unreachable")
+		}
+		cancel()
+		return ScanReport{}, nil
+	}
+	var printed strings.Builder
+	logf := func(format string, args ...interface{}) {
+		fmt.Fprintf(&printed, format, args...)
+	}
+
+	if code := watch(ctx, time.Millisecond, scan, &printed, logf); code != ExitClosed {
+		t.Errorf("Expected exit code %d once the port closed, got %d", ExitClosed, code)
+	}
+	if !strings.Contains(printed.String(), "network is unreachable") {
+		t.Errorf("Expected the failed scan to be logged, got %q", printed.String())
+	}
+	// The third scan is compared to the first, not to the failed one
+	if !strings.Contains(printed.String(), "Closed: 192.0.2.1 port 22 (TCP), ssh") {
+		t.Errorf("Expected the closed port to be printed, got %q", printed.String())
+	}
+}
+
+func TestWatch_FirstScanFails(t *testing.T) {
+	scan := func() (ScanReport, error) {
+		return ScanReport{}, errors.New("no such host")
+	}
+	logf := func(string, ...interface{}) {}
+	if code := watch(context.Background(), time.Millisecond, scan, io.Discard, logf); code != ExitError {
+		t.Errorf("Expected exit code %d, got %d", ExitError, code)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNewTarget_ZeroWorkers(t *testing.T) {
+	if _, err := NewTarget("127.0.0.1", 0); err == nil {
+		t.Errorf("Expected NewTarget to reject 0 workers")
+	}
+	if _, err := NewCIDRTarget("127.0.0.0/30", 0, 0); err == nil {
+		t.Errorf("Expected NewCIDRTarget to reject 0 workers")
+	}
+	if _, err := NewStreamingTarget("127.0.0.1", -1, func(ScanEvent) {}); err == nil {
+		t.Errorf("Expected NewStreamingTarget to reject -1 workers")
+	}
+	multi := NewMultiScan(context.Background(), []string{"127.0.0.1"}, 0, net.DefaultResolver)
+	if multi.Scanners[0] != nil || multi.Errors[0] == nil {
+		t.Errorf("Expected NewMultiScan to reject 0 workers, got %v", multi.Errors[0])
+	}
+}
+
+func TestScan_ZeroWorkersErrorsCleanly(t *testing.T) {
+	scanner := stubbedScanner(t)
+	scanner.Ports = allPorts()
+	scanner.NumWorkers = 0
+
+	finished := make(chan struct{})
+	go func() {
+		scanner.Scan()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected a scan without workers to return, still running")
+	}
+
+	if len(scanner.Errors) != 1 {
+		t.Fatalf("Expected the missing workers in Errors, got %v", scanner.Errors)
+	}
+	if scanErr, ok := scanner.Errors[0].(*ScanError); !ok || scanErr.Stage != "workers" {
+		t.Errorf("Expected a workers error, got %v", scanner.Errors[0])
+	}
+	if completed := scanner.Completed(); completed != 0 {
+		t.Errorf("Expected nothing probed, got %d probes", completed)
+	}
+}